@@ -0,0 +1,429 @@
+// Package archive fetches release archives pinned by checksum and extracts
+// them into the store, where skills are discovered and linked like any other
+// origin.
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/jmmarotta/agent_skills_manager/internal/debug"
+	"github.com/jmmarotta/agent_skills_manager/internal/gitstore"
+	"github.com/jmmarotta/agent_skills_manager/internal/source"
+)
+
+// ErrChecksumMismatch is returned when a download does not hash to the
+// pinned sha256.
+var ErrChecksumMismatch = errors.New("archive checksum mismatch")
+
+type Options struct {
+	Origin string
+	Sha256 string
+	// StoreDir holds extracted archives under archives/<sha256>, so one
+	// download serves every origin that publishes the same bytes.
+	StoreDir string
+	// CacheDir keeps the verified downloads under archives/.
+	CacheDir string
+	// Offline only uses archives that are already extracted or cached.
+	Offline bool
+}
+
+// StorePath is where the archive with sum is extracted.
+func StorePath(storeDir string, sum string) string {
+	return filepath.Join(storeDir, "archives", sum)
+}
+
+const completeSuffix = ".complete"
+
+// Extracted reports whether the archive with sum is fully extracted under
+// storeDir. Extraction leaves a .complete marker beside the directory once
+// it is in place; a directory without one was left by an interrupted or
+// outside write and is extracted again.
+func Extracted(storeDir string, sum string) (bool, error) {
+	dest := StorePath(storeDir, sum)
+	for _, path := range []string{dest, dest + completeSuffix} {
+		if _, err := os.Stat(path); err != nil {
+			if os.IsNotExist(err) {
+				return false, nil
+			}
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// Ensure makes the archive at opts.Origin available extracted under the
+// store and returns its directory. The download is verified against
+// opts.Sha256 before anything is extracted; an existing extraction is
+// trusted once it is marked complete.
+func Ensure(ctx context.Context, opts Options) (string, error) {
+	suffix, ok := source.ArchiveSuffix(opts.Origin)
+	if !ok {
+		return "", fmt.Errorf("not an archive url: %s", debug.SanitizeOrigin(opts.Origin))
+	}
+	dest := StorePath(opts.StoreDir, opts.Sha256)
+	extracted, err := Extracted(opts.StoreDir, opts.Sha256)
+	if err != nil {
+		return "", err
+	}
+	if extracted {
+		return dest, nil
+	}
+	if _, err := os.Lstat(dest); err == nil {
+		debug.Logf("archive store incomplete path=%s", dest)
+		if err := os.RemoveAll(dest); err != nil {
+			return "", err
+		}
+	}
+
+	cached := filepath.Join(opts.CacheDir, "archives", opts.Sha256+suffix)
+	if err := verifyFile(cached, opts.Sha256); err != nil {
+		if !os.IsNotExist(err) {
+			debug.Logf("archive cache invalid path=%s err=%v", cached, err)
+			if err := os.Remove(cached); err != nil && !os.IsNotExist(err) {
+				return "", err
+			}
+		}
+		if opts.Offline {
+			return "", fmt.Errorf("%s is not cached; cannot download it offline", debug.SanitizeOrigin(opts.Origin))
+		}
+		if err := download(ctx, opts.Origin, opts.Sha256, cached); err != nil {
+			return "", err
+		}
+	}
+
+	if err := extract(cached, suffix, dest); err != nil {
+		return "", fmt.Errorf("extract %s: %w", debug.SanitizeOrigin(opts.Origin), err)
+	}
+	return dest, nil
+}
+
+// download fetches origin into dest, keeping it only if it hashes to sum.
+func download(ctx context.Context, origin string, sum string, dest string) error {
+	debug.Logf("archive download origin=%s", debug.SanitizeOrigin(origin))
+	client, err := gitstore.NewHTTPClient(0)
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, origin, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(request)
+	if err != nil {
+		return fmt.Errorf("download %s: %w", debug.SanitizeOrigin(origin), err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("download %s: unexpected status %s", debug.SanitizeOrigin(origin), resp.Status)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+		return err
+	}
+	staging, err := os.CreateTemp(filepath.Dir(dest), ".download-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(staging.Name())
+
+	hash := sha256.New()
+	_, err = io.Copy(io.MultiWriter(staging, hash), resp.Body)
+	if closeErr := staging.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("download %s: %w", debug.SanitizeOrigin(origin), err)
+	}
+	if got := hex.EncodeToString(hash.Sum(nil)); got != sum {
+		return fmt.Errorf("%s: %w: expected %s, got %s", debug.SanitizeOrigin(origin), ErrChecksumMismatch, sum, got)
+	}
+	return os.Rename(staging.Name(), dest)
+}
+
+func verifyFile(path string, sum string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return err
+	}
+	if got := hex.EncodeToString(hash.Sum(nil)); got != sum {
+		return fmt.Errorf("%w: expected %s, got %s", ErrChecksumMismatch, sum, got)
+	}
+	return nil
+}
+
+// extract unpacks the archive at path into dest. When every entry sits under
+// one top-level directory, as in most release archives, that directory is
+// dropped so subdirs are relative to the project inside it.
+func extract(path string, suffix string, dest string) error {
+	if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+		return err
+	}
+	staging, err := os.MkdirTemp(filepath.Dir(dest), ".extract-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(staging)
+
+	unpacked := filepath.Join(staging, "root")
+	if suffix == ".zip" {
+		err = extractZip(path, unpacked)
+	} else {
+		err = extractTar(path, suffix != ".tar", unpacked)
+	}
+	if err != nil {
+		return err
+	}
+
+	root, err := singleTopDir(unpacked)
+	if err != nil {
+		return err
+	}
+	if err := verifyLinks(root); err != nil {
+		return err
+	}
+	if err := os.Rename(root, dest); err != nil {
+		if _, statErr := os.Stat(dest); statErr != nil {
+			return err
+		}
+	}
+	// The marker goes down only once dest holds the whole tree, so a crash
+	// before this point leaves an extraction Ensure will redo.
+	return os.WriteFile(dest+completeSuffix, nil, 0o644)
+}
+
+func extractTar(path string, gzipped bool, dest string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var stream io.Reader = file
+	if gzipped {
+		reader, err := gzip.NewReader(file)
+		if err != nil {
+			return err
+		}
+		defer reader.Close()
+		stream = reader
+	}
+
+	reader := tar.NewReader(stream)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := makeDir(dest, header.Name); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := writeEntry(dest, header.Name, header.FileInfo().Mode(), reader); err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			if err := writeSymlink(dest, header.Name, header.Linkname); err != nil {
+				return err
+			}
+		default:
+			debug.Logf("archive skip entry=%s type=%c", header.Name, header.Typeflag)
+		}
+	}
+}
+
+func extractZip(path string, dest string) error {
+	reader, err := zip.OpenReader(path)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	for _, file := range reader.File {
+		mode := file.Mode()
+		switch {
+		case mode.IsDir():
+			if err := makeDir(dest, file.Name); err != nil {
+				return err
+			}
+		case mode&os.ModeSymlink != 0:
+			target, err := readZipEntry(file)
+			if err != nil {
+				return err
+			}
+			if err := writeSymlink(dest, file.Name, target); err != nil {
+				return err
+			}
+		case mode.IsRegular():
+			content, err := file.Open()
+			if err != nil {
+				return err
+			}
+			err = writeEntry(dest, file.Name, mode, content)
+			content.Close()
+			if err != nil {
+				return err
+			}
+		default:
+			debug.Logf("archive skip entry=%s mode=%s", file.Name, mode)
+		}
+	}
+	return nil
+}
+
+func readZipEntry(file *zip.File) (string, error) {
+	content, err := file.Open()
+	if err != nil {
+		return "", err
+	}
+	defer content.Close()
+	data, err := io.ReadAll(content)
+	return string(data), err
+}
+
+// entryPath maps an archive entry name into dest, refusing names that
+// would land outside it, either directly or through a symlink an earlier
+// entry created.
+func entryPath(dest string, name string) (string, error) {
+	name = strings.ReplaceAll(name, "\\", "/")
+	cleaned := path.Clean(name)
+	if path.IsAbs(name) || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", fmt.Errorf("archive entry %q escapes the archive", name)
+	}
+	if cleaned == "." {
+		return dest, nil
+	}
+	current := dest
+	for _, part := range strings.Split(cleaned, "/") {
+		current = filepath.Join(current, part)
+		info, err := os.Lstat(current)
+		if os.IsNotExist(err) {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			return "", fmt.Errorf("archive entry %q is written through a symlink", name)
+		}
+	}
+	return filepath.Join(dest, filepath.FromSlash(cleaned)), nil
+}
+
+func makeDir(dest string, name string) error {
+	target, err := entryPath(dest, name)
+	if err != nil {
+		return err
+	}
+	return os.MkdirAll(target, 0o755)
+}
+
+func writeEntry(dest string, name string, mode os.FileMode, content io.Reader) error {
+	target, err := entryPath(dest, name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode.Perm()|0o600)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(file, content)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// writeSymlink keeps links whose target stays inside the archive.
+func writeSymlink(dest string, name string, linkname string) error {
+	target, err := entryPath(dest, name)
+	if err != nil {
+		return err
+	}
+	resolved := path.Clean(path.Join(path.Dir(path.Clean(name)), linkname))
+	if path.IsAbs(linkname) || resolved == ".." || strings.HasPrefix(resolved, "../") {
+		return fmt.Errorf("archive entry %q links outside the archive", name)
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return err
+	}
+	return os.Symlink(filepath.FromSlash(linkname), target)
+}
+
+// verifyLinks refuses a tree holding a symlink that, followed on disk,
+// leads outside root or to nothing. writeSymlink only checks each target as
+// text, which misses links that climb out through one another, and root may
+// be the wrapper directory singleTopDir kept.
+func verifyLinks(root string) error {
+	realRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return err
+	}
+	return filepath.WalkDir(root, func(current string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.Type()&fs.ModeSymlink == 0 {
+			return nil
+		}
+		name, err := filepath.Rel(root, current)
+		if err != nil {
+			return err
+		}
+		resolved, err := filepath.EvalSymlinks(current)
+		if err != nil {
+			return fmt.Errorf("archive entry %q links to nothing inside the archive", filepath.ToSlash(name))
+		}
+		relative, err := filepath.Rel(realRoot, resolved)
+		if err != nil || relative == ".." || strings.HasPrefix(relative, ".."+string(filepath.Separator)) {
+			return fmt.Errorf("archive entry %q links outside the archive", filepath.ToSlash(name))
+		}
+		return nil
+	})
+}
+
+// singleTopDir returns the only directory in root when it holds nothing
+// else and is not itself a skill, and root otherwise.
+func singleTopDir(root string) (string, error) {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return root, os.MkdirAll(root, 0o755)
+		}
+		return "", err
+	}
+	if len(entries) != 1 || !entries[0].IsDir() {
+		return root, nil
+	}
+	top := filepath.Join(root, entries[0].Name())
+	if _, err := os.Stat(filepath.Join(top, "SKILL.md")); err == nil {
+		return root, nil
+	}
+	return top, nil
+}
@@ -0,0 +1,312 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func buildTarGz(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buffer bytes.Buffer
+	compressed := gzip.NewWriter(&buffer)
+	writer := tar.NewWriter(compressed)
+	for _, name := range sortedNames(files) {
+		header := &tar.Header{Name: name, Mode: 0o644, Size: int64(len(files[name])), Typeflag: tar.TypeReg}
+		if err := writer.WriteHeader(header); err != nil {
+			t.Fatalf("write header: %v", err)
+		}
+		if _, err := writer.Write([]byte(files[name])); err != nil {
+			t.Fatalf("write entry: %v", err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("close tar: %v", err)
+	}
+	if err := compressed.Close(); err != nil {
+		t.Fatalf("close gzip: %v", err)
+	}
+	return buffer.Bytes()
+}
+
+func buildZip(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buffer bytes.Buffer
+	writer := zip.NewWriter(&buffer)
+	for _, name := range sortedNames(files) {
+		entry, err := writer.Create(name)
+		if err != nil {
+			t.Fatalf("create entry: %v", err)
+		}
+		if _, err := entry.Write([]byte(files[name])); err != nil {
+			t.Fatalf("write entry: %v", err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("close zip: %v", err)
+	}
+	return buffer.Bytes()
+}
+
+func sortedNames(files map[string]string) []string {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func sum(data []byte) string {
+	digest := sha256.Sum256(data)
+	return hex.EncodeToString(digest[:])
+}
+
+// serveArchives serves payloads by path and counts the requests.
+func serveArchives(t *testing.T, payloads map[string][]byte) (*httptest.Server, *int32) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		payload, ok := payloads[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write(payload)
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func isolateHTTP(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("HOME", root)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(root, "xdg"))
+	t.Setenv("GIT_CONFIG_GLOBAL", filepath.Join(root, "gitconfig"))
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	for _, key := range []string{"HTTP_PROXY", "http_proxy", "HTTPS_PROXY", "https_proxy", "ASM_CA_BUNDLE", "ASM_CLIENT_CERT"} {
+		t.Setenv(key, "")
+	}
+}
+
+func TestEnsureVerifiesAndExtractsArchives(t *testing.T) {
+	isolateHTTP(t)
+	tarball := buildTarGz(t, map[string]string{
+		"skills-1.2.0/skills/foo/SKILL.md": "# foo\n",
+		"skills-1.2.0/skills/bar/SKILL.md": "# bar\n",
+	})
+	zipped := buildZip(t, map[string]string{
+		"skills/baz/SKILL.md": "# baz\n",
+		"README.md":           "readme\n",
+	})
+	server, requests := serveArchives(t, map[string][]byte{
+		"/skills-1.2.0.tar.gz": tarball,
+		"/skills.zip":          zipped,
+	})
+
+	root := t.TempDir()
+	opts := Options{
+		Origin:   server.URL + "/skills-1.2.0.tar.gz",
+		Sha256:   sum(tarball),
+		StoreDir: filepath.Join(root, "store"),
+		CacheDir: filepath.Join(root, "cache"),
+	}
+	dir, err := Ensure(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("Ensure: %v", err)
+	}
+	if dir != StorePath(opts.StoreDir, opts.Sha256) {
+		t.Fatalf("expected the content-addressed store path, got %q", dir)
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "skills", "foo", "SKILL.md")); err != nil || string(data) != "# foo\n" {
+		t.Fatalf("expected the wrapper directory to be dropped, got %q, %v", data, err)
+	}
+
+	// An extraction without its completion marker is not trusted.
+	if err := os.WriteFile(filepath.Join(dir, "skills", "foo", "SKILL.md"), []byte("tampered\n"), 0o644); err != nil {
+		t.Fatalf("tamper: %v", err)
+	}
+	if err := os.Remove(dir + ".complete"); err != nil {
+		t.Fatalf("remove marker: %v", err)
+	}
+	if _, err := Ensure(context.Background(), opts); err != nil {
+		t.Fatalf("Ensure incomplete: %v", err)
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "skills", "foo", "SKILL.md")); err != nil || string(data) != "# foo\n" {
+		t.Fatalf("expected the unmarked extraction to be redone, got %q, %v", data, err)
+	}
+
+	// Offline reuses the cached download once the extraction is gone.
+	if err := os.RemoveAll(dir); err != nil {
+		t.Fatalf("remove store: %v", err)
+	}
+	opts.Offline = true
+	if _, err := Ensure(context.Background(), opts); err != nil {
+		t.Fatalf("Ensure offline: %v", err)
+	}
+	if got := atomic.LoadInt32(requests); got != 1 {
+		t.Fatalf("expected one download, got %d", got)
+	}
+
+	zipOpts := Options{
+		Origin:   server.URL + "/skills.zip",
+		Sha256:   sum(zipped),
+		StoreDir: opts.StoreDir,
+		CacheDir: opts.CacheDir,
+	}
+	dir, err = Ensure(context.Background(), zipOpts)
+	if err != nil {
+		t.Fatalf("Ensure zip: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "skills", "baz", "SKILL.md")); err != nil {
+		t.Fatalf("expected zip contents: %v", err)
+	}
+}
+
+func TestEnsureRejectsChecksumMismatch(t *testing.T) {
+	isolateHTTP(t)
+	tarball := buildTarGz(t, map[string]string{"foo/SKILL.md": "# foo\n"})
+	server, _ := serveArchives(t, map[string][]byte{"/skills.tgz": tarball})
+
+	root := t.TempDir()
+	wrong := strings.Repeat("0", 64)
+	_, err := Ensure(context.Background(), Options{
+		Origin:   server.URL + "/skills.tgz",
+		Sha256:   wrong,
+		StoreDir: filepath.Join(root, "store"),
+		CacheDir: filepath.Join(root, "cache"),
+	})
+	if !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatalf("expected a checksum mismatch, got %v", err)
+	}
+	if _, err := os.Stat(StorePath(filepath.Join(root, "store"), wrong)); !os.IsNotExist(err) {
+		t.Fatalf("expected nothing extracted, got %v", err)
+	}
+	if entries, _ := os.ReadDir(filepath.Join(root, "cache", "archives")); len(entries) != 0 {
+		t.Fatalf("expected nothing cached, got %v", entries)
+	}
+}
+
+func TestEnsureRejectsEntriesOutsideTheArchive(t *testing.T) {
+	isolateHTTP(t)
+	tarball := buildTarGz(t, map[string]string{"../escape/SKILL.md": "# escape\n"})
+	server, _ := serveArchives(t, map[string][]byte{"/evil.tar.gz": tarball})
+
+	root := t.TempDir()
+	_, err := Ensure(context.Background(), Options{
+		Origin:   server.URL + "/evil.tar.gz",
+		Sha256:   sum(tarball),
+		StoreDir: filepath.Join(root, "store"),
+		CacheDir: filepath.Join(root, "cache"),
+	})
+	if err == nil || !strings.Contains(err.Error(), "escapes the archive") {
+		t.Fatalf("expected the entry to be refused, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "escape")); !os.IsNotExist(err) {
+		t.Fatalf("expected nothing written outside the store, got %v", err)
+	}
+}
+
+func TestEnsureRejectsEntriesThroughChainedSymlinks(t *testing.T) {
+	isolateHTTP(t)
+	// Each link stays inside the archive on its own, but the second is
+	// created through the first and so climbs past the extraction root.
+	var buffer bytes.Buffer
+	compressed := gzip.NewWriter(&buffer)
+	writer := tar.NewWriter(compressed)
+	headers := []*tar.Header{
+		{Name: "a/b/c/L", Linkname: "../../..", Typeflag: tar.TypeSymlink},
+		{Name: "a/b/c/L/a/b/c/L2", Linkname: "../../../../../../..", Typeflag: tar.TypeSymlink},
+		{Name: "a/b/c/L/a/b/c/L2/ESCAPED", Mode: 0o644, Size: int64(len("escaped\n")), Typeflag: tar.TypeReg},
+	}
+	for _, header := range headers {
+		if err := writer.WriteHeader(header); err != nil {
+			t.Fatalf("write header: %v", err)
+		}
+		if header.Typeflag == tar.TypeReg {
+			if _, err := writer.Write([]byte("escaped\n")); err != nil {
+				t.Fatalf("write entry: %v", err)
+			}
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("close tar: %v", err)
+	}
+	if err := compressed.Close(); err != nil {
+		t.Fatalf("close gzip: %v", err)
+	}
+	tarball := buffer.Bytes()
+	server, _ := serveArchives(t, map[string][]byte{"/evil.tar.gz": tarball})
+
+	root := t.TempDir()
+	_, err := Ensure(context.Background(), Options{
+		Origin:   server.URL + "/evil.tar.gz",
+		Sha256:   sum(tarball),
+		StoreDir: filepath.Join(root, "store"),
+		CacheDir: filepath.Join(root, "cache"),
+	})
+	if err == nil || !strings.Contains(err.Error(), "through a symlink") {
+		t.Fatalf("expected the chained link to be refused, got %v", err)
+	}
+	if err := filepath.Walk(filepath.Dir(root), func(path string, info os.FileInfo, err error) error {
+		if err == nil && info.Name() == "ESCAPED" {
+			t.Fatalf("expected nothing written outside the store, found %s", path)
+		}
+		return nil
+	}); err != nil {
+		t.Fatalf("walk: %v", err)
+	}
+}
+
+func TestEnsureRejectsLinksThatClimbThroughOtherLinks(t *testing.T) {
+	isolateHTTP(t)
+	// t only stays inside the archive as text; followed through s it lands
+	// above the extraction root.
+	var buffer bytes.Buffer
+	compressed := gzip.NewWriter(&buffer)
+	writer := tar.NewWriter(compressed)
+	for _, header := range []*tar.Header{
+		{Name: "pkg/x/deep/s", Linkname: "../..", Typeflag: tar.TypeSymlink},
+		{Name: "pkg/x/deep/t", Linkname: "s/../..", Typeflag: tar.TypeSymlink},
+	} {
+		if err := writer.WriteHeader(header); err != nil {
+			t.Fatalf("write header: %v", err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("close tar: %v", err)
+	}
+	if err := compressed.Close(); err != nil {
+		t.Fatalf("close gzip: %v", err)
+	}
+	tarball := buffer.Bytes()
+	server, _ := serveArchives(t, map[string][]byte{"/evil.tar.gz": tarball})
+
+	root := t.TempDir()
+	storeDir := filepath.Join(root, "store")
+	_, err := Ensure(context.Background(), Options{
+		Origin:   server.URL + "/evil.tar.gz",
+		Sha256:   sum(tarball),
+		StoreDir: storeDir,
+		CacheDir: filepath.Join(root, "cache"),
+	})
+	if err == nil || !strings.Contains(err.Error(), "links outside the archive") {
+		t.Fatalf("expected the climbing link to be refused, got %v", err)
+	}
+	if _, err := os.Stat(StorePath(storeDir, sum(tarball))); !os.IsNotExist(err) {
+		t.Fatalf("expected nothing extracted, got %v", err)
+	}
+}
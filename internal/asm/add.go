@@ -1,9 +1,11 @@
 package asm
 
 import (
+	"context"
 	"fmt"
 	"strings"
 
+	"github.com/jmmarotta/agent_skills_manager/internal/archive"
 	"github.com/jmmarotta/agent_skills_manager/internal/debug"
 	"github.com/jmmarotta/agent_skills_manager/internal/gitstore"
 	"github.com/jmmarotta/agent_skills_manager/internal/manifest"
@@ -18,31 +20,90 @@ type addResolution struct {
 	ReplacePath string
 }
 
-func Add(input string, pathFlag string) (InstallReport, error) {
-	state, _, err := manifest.LoadOrInitState()
+type AddOptions struct {
+	Input string
+	Path  string
+	Track string
+	// Sha256 pins an archive url to the checksum of its download.
+	Sha256 string
+	Global bool
+	// Wait waits for another asm process to release the state lock.
+	Wait bool
+}
+
+func Add(ctx context.Context, opts AddOptions) (InstallReport, error) {
+	unlock, err := lockState(ctx, stateLock{Global: opts.Global, Wait: opts.Wait, Init: true})
+	if err != nil {
+		return InstallReport{}, err
+	}
+	defer unlock()
+
+	state, _, err := loadOrInitState(ctx, opts.Global)
 	if err != nil {
 		return InstallReport{}, fmt.Errorf("load manifest: %w", err)
 	}
 
-	pathFlag = strings.TrimSpace(pathFlag)
-	debug.Logf("add start input=%q path=%q", input, pathFlag)
+	input := opts.Input
+	pathFlag := strings.TrimSpace(opts.Path)
+	track := strings.TrimSpace(opts.Track)
+	sha256 := strings.ToLower(strings.TrimSpace(opts.Sha256))
+	debug.Logf("add start input=%q path=%q track=%q sha256=%q", input, pathFlag, track, sha256)
 
-	inputSpec, err := parseAddInput(input, pathFlag)
+	inputSpec, err := parseAddInput(ctx, input, pathFlag)
 	if err != nil {
 		return InstallReport{}, fmt.Errorf("parse add input: %w", err)
 	}
+	if track != "" {
+		if inputSpec.Ref != "" && inputSpec.Ref != track {
+			return InstallReport{}, fmt.Errorf("ref %q conflicts with --track %q", inputSpec.Ref, track)
+		}
+		inputSpec.Ref = track
+	}
 
-	resolution, err := resolveAddInput(state, inputSpec)
-	if err != nil {
-		return InstallReport{}, fmt.Errorf("resolve add input: %w", err)
+	if inputSpec.IsArchive && sha256 == "" {
+		return InstallReport{}, fmt.Errorf("archive urls require --sha256")
+	}
+	if !inputSpec.IsArchive && sha256 != "" {
+		return InstallReport{}, fmt.Errorf("--sha256 requires an archive url")
+	}
+	if inputSpec.IsArchive && track != "" {
+		return InstallReport{}, fmt.Errorf("--track requires a git origin")
+	}
+	if sha256 != "" && !manifest.IsSha256(sha256) {
+		return InstallReport{}, fmt.Errorf("invalid --sha256 %q: expected 64 hex digits", sha256)
+	}
+
+	if state.Signers == nil {
+		state.Signers = map[manifest.LockKey]string{}
 	}
-	if !inputSpec.IsLocal && resolution.Rev != "" {
-		if err := gitstore.CheckoutRevision(resolution.RepoPath, resolution.Rev); err != nil {
-			return InstallReport{}, fmt.Errorf("checkout repo: %w", err)
+	var resolution addResolution
+	var discoverRoot string
+	if inputSpec.IsArchive {
+		discoverRoot, err = archive.Ensure(ctx, archiveOptions(state, inputSpec.Origin, sha256, false))
+		if err != nil {
+			return InstallReport{}, fmt.Errorf("fetch archive: %w", err)
+		}
+		resolution = addResolution{Origin: inputSpec.Origin, RepoPath: discoverRoot}
+	} else {
+		resolution, err = resolveAddInput(ctx, state, inputSpec, track)
+		if err != nil {
+			return InstallReport{}, fmt.Errorf("resolve add input: %w", err)
+		}
+		if !inputSpec.IsLocal && resolution.Version != "" {
+			if err := verifyLockedRevision(state, resolution.Origin, resolution.Version, resolution.RepoPath, resolution.Rev); err != nil {
+				return InstallReport{}, err
+			}
+		}
+		discoverRoot = resolution.RepoPath
+		if !inputSpec.IsLocal && resolution.Rev != "" {
+			discoverRoot = gitstore.CheckoutPath(state.Paths.CheckoutsDir, resolution.Origin, resolution.Rev)
+			if err := gitstore.ExportRevision(resolution.RepoPath, resolution.Rev, discoverRoot, []string{inputSpec.Subdir}); err != nil {
+				return InstallReport{}, fmt.Errorf("checkout repo: %w", err)
+			}
 		}
 	}
 
-	skills, err := source.DiscoverSkills(resolution.RepoPath, inputSpec.Subdir)
+	skills, err := source.DiscoverSkills(discoverRoot, inputSpec.Subdir)
 	if err != nil {
 		return InstallReport{}, fmt.Errorf("discover skills: %w", err)
 	}
@@ -66,6 +127,9 @@ func Add(input string, pathFlag string) (InstallReport, error) {
 		Origin:  resolution.Origin,
 		Version: resolution.Version,
 		Author:  author,
+		Track:   track,
+		Pinned:  track == "" && inputSpec.Ref != "",
+		Sha256:  sha256,
 	}); err != nil {
 		return InstallReport{}, err
 	}
@@ -75,13 +139,20 @@ func Add(input string, pathFlag string) (InstallReport, error) {
 			state.Lock = map[manifest.LockKey]string{}
 		}
 		state.Lock[manifest.LockKey{Origin: resolution.Origin, Version: resolution.Version}] = resolution.Rev
+		if state.Hashes == nil {
+			state.Hashes = map[manifest.HashKey]string{}
+		}
+		if err := recordSkillHashes(state, resolution.Origin, resolution.Version, resolution.RepoPath, resolution.Rev); err != nil {
+			return InstallReport{}, err
+		}
+		pruneUnusedLock(state)
 	}
 
 	if err := manifest.SaveState(state); err != nil {
 		return InstallReport{}, fmt.Errorf("save manifest: %w", err)
 	}
 
-	report, err := installSkills(state)
+	report, err := installSkills(ctx, state, InstallOptions{})
 	if err != nil {
 		return InstallReport{}, fmt.Errorf("install skills: %w", err)
 	}
@@ -89,7 +160,7 @@ func Add(input string, pathFlag string) (InstallReport, error) {
 	return report, nil
 }
 
-func parseAddInput(input string, pathFlag string) (source.Input, error) {
+func parseAddInput(ctx context.Context, input string, pathFlag string) (source.Input, error) {
 	debug.Logf("parse add input raw=%q path=%q", input, pathFlag)
 	if source.IsGitHubTreeURL(input) {
 		if pathFlag != "" {
@@ -101,7 +172,7 @@ func parseAddInput(input string, pathFlag string) (source.Input, error) {
 			return source.Input{}, fmt.Errorf("parse github tree origin: %w", err)
 		}
 		if ok {
-			tree, err := resolveGitHubTreeInput(input, origin)
+			tree, err := resolveGitHubTreeInput(ctx, input, origin)
 			if err != nil {
 				return source.Input{}, fmt.Errorf("unable to parse github tree url; use origin@ref --path instead: %w", err)
 			}
@@ -119,8 +190,8 @@ func parseAddInput(input string, pathFlag string) (source.Input, error) {
 	return source.ParseInput(input, pathFlag)
 }
 
-func resolveGitHubTreeInput(raw string, origin string) (source.GitHubTreeSpec, error) {
-	refs, refErr := gitstore.ListRemoteRefs(origin)
+func resolveGitHubTreeInput(ctx context.Context, raw string, origin string) (source.GitHubTreeSpec, error) {
+	refs, refErr := gitstore.ListRemoteRefs(ctx, origin)
 	if refErr == nil {
 		tree, ok, parseErr := source.ParseGitHubTreeURL(raw, refs.All)
 		if parseErr == nil && ok {
@@ -142,7 +213,7 @@ func resolveGitHubTreeInput(raw string, origin string) (source.GitHubTreeSpec, e
 	return source.GitHubTreeSpec{}, fmt.Errorf("unable to resolve ref from github tree url")
 }
 
-func resolveAddInput(state manifest.State, inputSpec source.Input) (addResolution, error) {
+func resolveAddInput(ctx context.Context, state manifest.State, inputSpec source.Input, track string) (addResolution, error) {
 	debug.Logf(
 		"resolve add input origin=%s local=%t ref=%q subdir=%q",
 		debug.SanitizeOrigin(inputSpec.Origin),
@@ -158,7 +229,7 @@ func resolveAddInput(state manifest.State, inputSpec source.Input) (addResolutio
 			}
 			if ok {
 				origin := source.NormalizeOrigin(originURL)
-				resolved, err := gitstore.ResolveForRefAt(inputSpec.RepoRoot, inputSpec.Ref)
+				resolved, err := resolveAddRef(ctx, inputSpec.RepoRoot, "", inputSpec.Ref, track)
 				if err != nil {
 					return addResolution{}, fmt.Errorf("resolve ref %q: %w", inputSpec.Ref, err)
 				}
@@ -172,6 +243,9 @@ func resolveAddInput(state manifest.State, inputSpec source.Input) (addResolutio
 			}
 		}
 
+		if track != "" {
+			return addResolution{}, fmt.Errorf("--track requires a git origin")
+		}
 		return addResolution{
 			Origin:   inputSpec.Origin,
 			RepoPath: inputSpec.Origin,
@@ -183,10 +257,10 @@ func resolveAddInput(state manifest.State, inputSpec source.Input) (addResolutio
 	if inputSpec.RawOrigin != "" {
 		origin = inputSpec.RawOrigin
 	}
-	if err := gitstore.EnsureRepo(repoPath, origin); err != nil {
+	if err := gitstore.EnsureRepo(ctx, repoPath, origin); err != nil {
 		return addResolution{}, err
 	}
-	resolved, err := resolveRemoteRef(repoPath, origin, inputSpec.Ref)
+	resolved, err := resolveAddRef(ctx, repoPath, origin, inputSpec.Ref, track)
 	if err != nil {
 		if inputSpec.Ref == "" {
 			return addResolution{}, fmt.Errorf("resolve default ref: %w", err)
@@ -212,9 +286,19 @@ func resolveAuthor(inputSpec source.Input, resolution addResolution) string {
 	return source.AuthorForRemoteOrigin(resolution.Origin)
 }
 
-func resolveRemoteRef(repoPath string, origin string, ref string) (gitstore.Resolved, error) {
+func resolveAddRef(ctx context.Context, repoPath string, origin string, ref string, track string) (gitstore.Resolved, error) {
+	if track != "" {
+		return gitstore.ResolveForBranchAt(repoPath, track)
+	}
+	if origin == "" {
+		return gitstore.ResolveForRefAt(repoPath, ref)
+	}
+	return resolveRemoteRef(ctx, repoPath, origin, ref)
+}
+
+func resolveRemoteRef(ctx context.Context, repoPath string, origin string, ref string) (gitstore.Resolved, error) {
 	if ref == "" {
-		resolved, err := gitstore.ResolveForRemoteHeadAt(repoPath, origin)
+		resolved, err := gitstore.ResolveForRemoteHeadAt(ctx, repoPath, origin)
 		if err == nil {
 			return resolved, nil
 		}
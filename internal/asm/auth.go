@@ -0,0 +1,78 @@
+package asm
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/jmmarotta/agent_skills_manager/internal/debug"
+	"github.com/jmmarotta/agent_skills_manager/internal/gitstore"
+	"github.com/jmmarotta/agent_skills_manager/internal/source"
+	"github.com/jmmarotta/agent_skills_manager/internal/workpool"
+)
+
+type AuthStatusOptions struct {
+	Global bool
+	// Origin limits the report to one origin, which need not be in the
+	// manifest.
+	Origin string
+}
+
+// AuthStatus reports, for each git origin in the manifest, the URL asm would
+// fetch after insteadOf and ~/.ssh/config rewrites, which credentials it
+// would send, and whether the remote answers without credentials.
+func AuthStatus(ctx context.Context, opts AuthStatusOptions) (AuthStatusReport, error) {
+	origins := []string{}
+	if opts.Origin != "" {
+		if !source.IsRemoteOrigin(opts.Origin) {
+			return AuthStatusReport{}, fmt.Errorf("%s is not a git origin", opts.Origin)
+		}
+		origins = append(origins, source.NormalizeOrigin(opts.Origin))
+	} else {
+		state, err := loadState(ctx, opts.Global)
+		if err != nil {
+			return AuthStatusReport{}, err
+		}
+		seen := map[string]bool{}
+		for _, skill := range state.Config.Skills {
+			if source.IsRemoteOrigin(skill.Origin) && !seen[skill.Origin] {
+				seen[skill.Origin] = true
+				origins = append(origins, skill.Origin)
+			}
+		}
+		sort.Strings(origins)
+	}
+	if len(origins) == 0 {
+		return AuthStatusReport{NoOrigins: true}, nil
+	}
+
+	statuses := make([]OriginAuthStatus, len(origins))
+	workpool.Run(workpool.DefaultJobs, len(origins), func(index int) {
+		statuses[index] = originAuthStatus(ctx, origins[index])
+	})
+	return AuthStatusReport{Origins: statuses}, nil
+}
+
+func originAuthStatus(ctx context.Context, origin string) OriginAuthStatus {
+	status := OriginAuthStatus{Origin: debug.SanitizeOrigin(origin)}
+	access, err := gitstore.ResolveRemoteAccess(origin)
+	if err != nil {
+		status.Err = err
+		return status
+	}
+	status.URL = debug.SanitizeOrigin(access.URL)
+	status.Rewritten = access.Rewritten
+	status.Source = access.Source
+	status.Credential = access.RedactedAuth()
+
+	if access.IsSSH() {
+		status.Anonymous = "skipped"
+		return status
+	}
+	if err := gitstore.ListRemoteAnonymous(ctx, origin); err != nil {
+		status.Anonymous = err.Error()
+		return status
+	}
+	status.Anonymous = "ok"
+	return status
+}
@@ -0,0 +1,117 @@
+package asm
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/jmmarotta/agent_skills_manager/internal/debug"
+	"github.com/jmmarotta/agent_skills_manager/internal/gitstore"
+	"github.com/jmmarotta/agent_skills_manager/internal/manifest"
+)
+
+// extendsOptions controls what resolving a git extends base may touch.
+type extendsOptions struct {
+	// Fetch allows cloning or fetching the base origin. Without it the locked
+	// base revision must already be in the store.
+	Fetch bool
+	// Record writes a newly resolved base revision to the lock. Only callers
+	// holding lockState set it.
+	Record bool
+}
+
+// resolveExtends merges in a base manifest that lives in a git origin. Local
+// bases are already merged by manifest.Load. The base revision is pinned in
+// the local lock like any other origin.
+func resolveExtends(ctx context.Context, state *manifest.State, opts extendsOptions) error {
+	extends := state.Config.Extends
+	if extends == nil || !extends.IsRemote() {
+		return nil
+	}
+
+	if state.Lock == nil {
+		state.Lock = map[manifest.LockKey]string{}
+	}
+	lockChanged := false
+	base, err := loadRemoteBase(ctx, state, *extends, map[manifest.LockKey]bool{}, opts.Fetch, &lockChanged)
+	if err != nil {
+		return err
+	}
+	state.Config.Inherit(base)
+	if err := state.Config.Validate(); err != nil {
+		return fmt.Errorf("extends %s@%s: %w", extends.Origin, extends.Version, err)
+	}
+	state.AdoptInheritedLock()
+
+	// A conflicted lock is rewritten by the lock fix instead.
+	if opts.Record && lockChanged && state.LockConflict == nil {
+		if err := manifest.SaveStateLock(*state); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func loadRemoteBase(ctx context.Context, state *manifest.State, extends manifest.Extends, seen map[manifest.LockKey]bool, fetch bool, lockChanged *bool) (manifest.Config, error) {
+	key := extends.LockKey()
+	if seen[key] {
+		return manifest.Config{}, fmt.Errorf("extends cycle at %s@%s", extends.Origin, extends.Version)
+	}
+	seen[key] = true
+	debug.Logf("extends origin=%s version=%s path=%s", debug.SanitizeOrigin(extends.Origin), extends.Version, extends.ManifestPath())
+
+	root, err := remoteBaseRoot(ctx, state, extends, fetch, lockChanged)
+	if err != nil {
+		return manifest.Config{}, fmt.Errorf("extends %s@%s: %w", extends.Origin, extends.Version, err)
+	}
+
+	base, err := manifest.LoadBase(filepath.Join(root, filepath.FromSlash(extends.ManifestPath())))
+	if err != nil {
+		return manifest.Config{}, fmt.Errorf("extends %s@%s: %w", extends.Origin, extends.Version, err)
+	}
+	if base.Extends != nil && base.Extends.IsRemote() {
+		parent, err := loadRemoteBase(ctx, state, *base.Extends, seen, fetch, lockChanged)
+		if err != nil {
+			return manifest.Config{}, err
+		}
+		base.Inherit(parent)
+	}
+	return base, nil
+}
+
+func remoteBaseRoot(ctx context.Context, state *manifest.State, extends manifest.Extends, fetch bool, lockChanged *bool) (string, error) {
+	if replacePath := state.Config.Replace[extends.Origin]; replacePath != "" {
+		return replacePath, nil
+	}
+
+	repoPath := gitstore.RepoPath(state.Paths.StoreDir, extends.Origin)
+	key := extends.LockKey()
+	if !fetch {
+		if err := gitstore.RequireStoredRevision(repoPath, extends.Origin, extends.Version, state.Lock); err != nil {
+			return "", err
+		}
+	}
+	rev := state.Lock[key]
+	if rev != "" {
+		if exists, err := gitstore.CommitExists(repoPath, rev); err != nil || !exists {
+			rev = ""
+		}
+	}
+	if rev == "" {
+		if err := gitstore.EnsureRepo(ctx, repoPath, extends.Origin); err != nil {
+			return "", err
+		}
+		resolved, changed, err := gitstore.ResolveRevision(repoPath, extends.Origin, extends.Version, state.Lock, true)
+		if err != nil {
+			return "", err
+		}
+		rev = resolved
+		*lockChanged = *lockChanged || changed
+	}
+
+	checkout := gitstore.CheckoutPath(state.Paths.CheckoutsDir, extends.Origin, rev)
+	if err := gitstore.ExportRevision(repoPath, rev, checkout, nil); err != nil {
+		return "", err
+	}
+	return checkout, nil
+}
@@ -0,0 +1,121 @@
+package asm
+
+import (
+	"context"
+	"sort"
+
+	"github.com/jmmarotta/agent_skills_manager/internal/archive"
+	"github.com/jmmarotta/agent_skills_manager/internal/debug"
+	"github.com/jmmarotta/agent_skills_manager/internal/gitstore"
+	"github.com/jmmarotta/agent_skills_manager/internal/manifest"
+	"github.com/jmmarotta/agent_skills_manager/internal/workpool"
+)
+
+type FetchOptions struct {
+	Global bool
+	// Jobs bounds how many origins are fetched at once.
+	Jobs int
+	// Wait waits for another asm process to release the state lock.
+	Wait bool
+}
+
+// Fetch downloads every origin/rev pair and archive in the lockfile into the
+// store. It links nothing and leaves the manifest alone, so it can warm a
+// store for a later offline install.
+func Fetch(ctx context.Context, opts FetchOptions) (FetchReport, error) {
+	unlock, err := lockState(ctx, stateLock{Global: opts.Global, Wait: opts.Wait})
+	if err != nil {
+		return FetchReport{}, err
+	}
+	defer unlock()
+
+	state, err := loadLockedState(ctx, opts.Global, extendsOptions{Fetch: true})
+	if err != nil {
+		return FetchReport{}, err
+	}
+
+	revsByOrigin := map[string]map[string]string{}
+	origins := []string{}
+	for key, rev := range state.Lock {
+		if _, ok := revsByOrigin[key.Origin]; !ok {
+			revsByOrigin[key.Origin] = map[string]string{}
+			origins = append(origins, key.Origin)
+		}
+		revsByOrigin[key.Origin][key.Version] = rev
+	}
+	if len(origins) == 0 && len(state.Archives) == 0 {
+		return FetchReport{NoEntries: true}, nil
+	}
+	sort.Strings(origins)
+
+	type fetchOutcome struct {
+		present bool
+		err     error
+	}
+	outcomes := make([]fetchOutcome, len(origins))
+	workpool.Run(opts.Jobs, len(origins), func(index int) {
+		origin := origins[index]
+		path := gitstore.RepoPath(state.Paths.StoreDir, origin)
+		if gitstore.RevisionsPresent(path, revsByOrigin[origin]) {
+			outcomes[index].present = true
+			return
+		}
+		debug.Logf("fetch origin=%s revs=%d", debug.SanitizeOrigin(origin), len(revsByOrigin[origin]))
+		outcomes[index].err = gitstore.EnsureRevisions(ctx, path, origin, revsByOrigin[origin])
+	})
+
+	report := FetchReport{}
+	for index, origin := range origins {
+		outcome := outcomes[index]
+		path := gitstore.RepoPath(state.Paths.StoreDir, origin)
+		versions := make([]string, 0, len(revsByOrigin[origin]))
+		for version := range revsByOrigin[origin] {
+			versions = append(versions, version)
+		}
+		sort.Strings(versions)
+
+		missing := false
+		for _, version := range versions {
+			rev := revsByOrigin[origin][version]
+			if exists, err := gitstore.CommitExists(path, rev); err == nil && exists {
+				continue
+			}
+			reason := "commit not found"
+			if outcome.err != nil {
+				reason = outcome.err.Error()
+			}
+			report.Missing = append(report.Missing, FetchMissing{Origin: origin, Version: version, Rev: rev, Reason: reason})
+			missing = true
+		}
+		switch {
+		case missing:
+		case outcome.present:
+			report.Present = append(report.Present, origin)
+		default:
+			report.Fetched = append(report.Fetched, origin)
+		}
+	}
+	fetchArchives(ctx, state, &report)
+	return report, nil
+}
+
+// fetchArchives downloads and extracts every archive in the lockfile.
+func fetchArchives(ctx context.Context, state manifest.State, report *FetchReport) {
+	origins := make([]string, 0, len(state.Archives))
+	for origin := range state.Archives {
+		origins = append(origins, origin)
+	}
+	sort.Strings(origins)
+	for _, origin := range origins {
+		sum := state.Archives[origin]
+		if extracted, err := archive.Extracted(state.Paths.StoreDir, sum); err == nil && extracted {
+			report.Present = append(report.Present, origin)
+			continue
+		}
+		if _, err := archive.Ensure(ctx, archiveOptions(state, origin, sum, false)); err != nil {
+			report.Missing = append(report.Missing, FetchMissing{Origin: origin, Sha256: sum, Reason: err.Error()})
+			continue
+		}
+		report.Fetched = append(report.Fetched, origin)
+	}
+}
@@ -3,7 +3,6 @@ package asm
 import (
 	"encoding/json"
 	"fmt"
-	"net/http"
 	"net/url"
 	"os"
 	"strconv"
@@ -11,6 +10,7 @@ import (
 	"time"
 
 	"github.com/jmmarotta/agent_skills_manager/internal/debug"
+	"github.com/jmmarotta/agent_skills_manager/internal/gitstore"
 )
 
 const (
@@ -62,7 +62,10 @@ func searchSkillsAPI(query string, limit int) ([]FindSkill, error) {
 
 	debug.Logf("find query=%q url=%s", query, parsed.String())
 
-	client := &http.Client{Timeout: 10 * time.Second}
+	client, err := gitstore.NewHTTPClient(10 * time.Second)
+	if err != nil {
+		return nil, fmt.Errorf("search skills: %w", err)
+	}
 	resp, err := client.Get(parsed.String())
 	if err != nil {
 		return nil, fmt.Errorf("search skills: %w", err)
@@ -1,12 +1,14 @@
 package asm
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
 	"sort"
 	"strings"
 
+	"github.com/jmmarotta/agent_skills_manager/internal/archive"
 	"github.com/jmmarotta/agent_skills_manager/internal/gitstore"
 	"github.com/jmmarotta/agent_skills_manager/internal/linker"
 	"github.com/jmmarotta/agent_skills_manager/internal/manifest"
@@ -26,8 +28,8 @@ type skillDoc struct {
 	Description string
 }
 
-func Index(outputPath string) (IndexReport, error) {
-	state, err := manifest.LoadState()
+func Index(ctx context.Context, outputPath string) (IndexReport, error) {
+	state, err := loadState(ctx, false)
 	if err != nil {
 		return IndexReport{}, err
 	}
@@ -135,6 +137,10 @@ func skillDocCandidates(state manifest.State, skill manifest.Skill, safeName str
 		return filepath.Join(base, "SKILL.md")
 	}
 
+	if skill.Sha256 != "" {
+		candidates = append(candidates, add(pathForBase(archive.StorePath(state.Paths.StoreDir, skill.Sha256)))...)
+		return candidates
+	}
 	if skill.Version == "" {
 		candidates = append(candidates, add(pathForBase(skill.Origin))...)
 		return candidates
@@ -147,8 +153,10 @@ func skillDocCandidates(state manifest.State, skill manifest.Skill, safeName str
 	if replacePath != "" {
 		candidates = append(candidates, add(pathForBase(replacePath))...)
 	}
-	storePath := gitstore.RepoPath(state.Paths.StoreDir, skill.Origin)
-	candidates = append(candidates, add(pathForBase(storePath))...)
+	if rev := state.Lock[skill.LockKey()]; rev != "" {
+		checkoutPath := gitstore.CheckoutPath(state.Paths.CheckoutsDir, skill.Origin, rev)
+		candidates = append(candidates, add(pathForBase(checkoutPath))...)
+	}
 	return candidates
 }
 
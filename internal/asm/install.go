@@ -1,25 +1,115 @@
 package asm
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
 
+	"github.com/jmmarotta/agent_skills_manager/internal/archive"
 	"github.com/jmmarotta/agent_skills_manager/internal/debug"
+	"github.com/jmmarotta/agent_skills_manager/internal/envflag"
 	"github.com/jmmarotta/agent_skills_manager/internal/gitstore"
 	"github.com/jmmarotta/agent_skills_manager/internal/linker"
 	"github.com/jmmarotta/agent_skills_manager/internal/manifest"
 )
 
-func Install() (InstallReport, error) {
-	state, err := manifest.LoadState()
+type InstallOptions struct {
+	Global   bool
+	Profiles []string
+	Without  []string
+	// All clears a remembered selection so every skill is linked.
+	All bool
+	// Jobs bounds how many origins resolve at once.
+	Jobs int
+	// Offline never touches the network; $ASM_OFFLINE turns it on too.
+	Offline bool
+	// Wait waits for another asm process to release the state lock.
+	Wait bool
+}
+
+const offlineEnv = "ASM_OFFLINE"
+
+func Install(ctx context.Context, opts InstallOptions) (InstallReport, error) {
+	if envflag.Enabled(offlineEnv) {
+		opts.Offline = true
+	}
+	// The command line is checked before anything, the lockfile included,
+	// is touched.
+	if err := opts.validateSelection(); err != nil {
+		return InstallReport{}, err
+	}
+	unlock, err := lockState(ctx, stateLock{Global: opts.Global, Wait: opts.Wait})
 	if err != nil {
 		return InstallReport{}, err
 	}
+	defer unlock()
 
-	return installSkills(state)
+	state, err := loadConflictedState(ctx, opts.Global, extendsOptions{Fetch: !opts.Offline, Record: true})
+	if err != nil {
+		return InstallReport{}, err
+	}
+	warnings := []linker.Warning{}
+	if state.LockConflict != nil {
+		fix, err := fixLockConflict(ctx, &state, opts.Offline)
+		if err != nil {
+			return InstallReport{}, fmt.Errorf("fix lock conflict: %w", err)
+		}
+		warnings = append(warnings, linker.Warning{
+			Message: fmt.Sprintf("resolved merge conflicts in %s (kept %d, dropped %d)", filepath.Base(state.LockPath), fix.Kept, fix.Dropped),
+		})
+		for _, warning := range fix.Warnings {
+			warnings = append(warnings, linker.Warning{Message: warning})
+		}
+	}
+
+	report, err := installSkills(ctx, state, opts)
+	if err != nil {
+		return InstallReport{}, err
+	}
+	// The selection is remembered only once it installed cleanly.
+	if opts.selects() {
+		selection := manifest.Selection{Profiles: opts.Profiles, Without: opts.Without}
+		if err := manifest.SaveSelection(manifest.SelectionPath(state.Paths), selection); err != nil {
+			return InstallReport{}, fmt.Errorf("save selection: %w", err)
+		}
+	}
+	report.Warnings = append(warnings, report.Warnings...)
+	return report, nil
 }
 
-func installSkills(state manifest.State) (InstallReport, error) {
+// validateSelection rejects selection flags that contradict each other or
+// name invalid groups.
+func (opts InstallOptions) validateSelection() error {
+	if opts.All && (len(opts.Profiles) > 0 || len(opts.Without) > 0) {
+		return fmt.Errorf("--all cannot be combined with --profile or --without")
+	}
+	for _, group := range append(append([]string{}, opts.Profiles...), opts.Without...) {
+		if err := manifest.ValidateGroupName(group); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// selects reports whether opts replace the remembered selection.
+func (opts InstallOptions) selects() bool {
+	return opts.All || len(opts.Profiles) > 0 || len(opts.Without) > 0
+}
+
+// installSkills resolves and links the selected skills. Only the Jobs,
+// Offline and selection options apply here; without a selection the one
+// remembered from an earlier install is used.
+func installSkills(ctx context.Context, state manifest.State, opts InstallOptions) (InstallReport, error) {
 	debug.Logf("install skills count=%d", len(state.Config.Skills))
+	if state.Hashes == nil {
+		state.Hashes = map[manifest.HashKey]string{}
+	}
+	if state.Signers == nil {
+		state.Signers = map[manifest.LockKey]string{}
+	}
 	if len(state.Config.Skills) == 0 {
 		prune, err := linker.Prune(linker.Target{Name: "skills", Path: state.Paths.SkillsDir}, nil)
 		if err != nil {
@@ -28,7 +118,20 @@ func installSkills(state manifest.State) (InstallReport, error) {
 		return InstallReport{Pruned: prune.Removed, Warnings: prune.Warnings, NoSkills: true}, nil
 	}
 
-	sources, warnings, lockChanged, err := resolveInstallSources(state)
+	selection := manifest.Selection{Profiles: opts.Profiles, Without: opts.Without}
+	if !opts.selects() {
+		var err error
+		selection, err = manifest.LoadSelection(manifest.SelectionPath(state.Paths))
+		if err != nil {
+			return InstallReport{}, err
+		}
+	}
+	selected := state
+	var skipped []string
+	selected.Config.Skills, skipped = selection.Filter(state.Config.Skills)
+	debug.Logf("install selection profiles=%v without=%v skipped=%d", selection.Profiles, selection.Without, len(skipped))
+
+	sources, warnings, lockChanged, err := resolveInstallSources(ctx, selected, opts)
 	if err != nil {
 		return InstallReport{}, fmt.Errorf("resolve sources: %w", err)
 	}
@@ -39,23 +142,42 @@ func installSkills(state manifest.State) (InstallReport, error) {
 	}
 
 	warnings = append(warnings, result.Warnings...)
+	for _, group := range selection.UnknownGroups(state.Config.Skills) {
+		warnings = append(warnings, linker.Warning{Message: fmt.Sprintf("no skills in group %q", group)})
+	}
 
-	if lockChanged {
-		if err := manifest.SaveLockWithSkills(state.LockPath, state.Lock, state.Config.Skills); err != nil {
+	if lockChanged || !maps.Equal(state.Archives, state.Config.ArchiveSums()) {
+		if err := manifest.SaveStateLock(state); err != nil {
 			return InstallReport{}, err
 		}
 	}
 
-	return InstallReport{Linked: result.Linked, Pruned: result.Removed, Warnings: warnings}, nil
+	return InstallReport{
+		Linked:    result.Linked,
+		Pruned:    result.Removed,
+		Warnings:  warnings,
+		Selection: selection,
+		Skipped:   skipped,
+	}, nil
 }
 
-func resolveInstallSources(state manifest.State) ([]linker.Source, []linker.Warning, bool, error) {
-	originVersions := state.Config.GitOriginVersions()
-	originPaths := make(map[string]string)
+func resolveInstallSources(ctx context.Context, state manifest.State, opts InstallOptions) ([]linker.Source, []linker.Warning, bool, error) {
+	lockKeys := state.Config.GitLockKeys()
+	originPaths := make(map[manifest.LockKey]string)
 	warnings := []linker.Warning{}
 	lockChanged := false
-	if len(originVersions) > 0 {
-		result, err := gitstore.ResolveOrigins(state.Paths.StoreDir, originVersions, state.Config.Replace, state.Lock, true)
+	if len(lockKeys) > 0 {
+		result, err := gitstore.ResolveOrigins(ctx, lockKeys, gitstore.ResolveOptions{
+			StoreDir:     state.Paths.StoreDir,
+			CheckoutsDir: state.Paths.CheckoutsDir,
+			Replace:      state.Config.Replace,
+			Lock:         state.Lock,
+			Strict:       true,
+			Subdirs:      state.Config.GitSubdirs(),
+			Jobs:         opts.Jobs,
+			Offline:      opts.Offline,
+			Verify:       state.Config.Verify,
+		})
 		if err != nil {
 			return nil, nil, false, err
 		}
@@ -64,6 +186,27 @@ func resolveInstallSources(state manifest.State) ([]linker.Source, []linker.Warn
 		for _, warning := range result.Warnings {
 			warnings = append(warnings, linker.Warning{Message: warning})
 		}
+
+		hashesChanged, err := verifySkillHashes(state, result.Resolutions)
+		if err != nil {
+			return nil, nil, false, err
+		}
+		lockChanged = lockChanged || hashesChanged || recordSigners(state, result.Resolutions)
+
+		if err := pruneStoreCheckouts(state.Paths.CheckoutsDir, result.Resolutions); err != nil {
+			return nil, nil, false, err
+		}
+	}
+
+	archives, err := ensureArchives(ctx, state, opts.Offline)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	if len(archives) > 0 && originPaths == nil {
+		originPaths = make(map[manifest.LockKey]string)
+	}
+	for key, path := range archives {
+		originPaths[key] = path
 	}
 
 	sources, err := linker.SourcesFromConfig(state.Config, originPaths)
@@ -72,3 +215,147 @@ func resolveInstallSources(state manifest.State) ([]linker.Source, []linker.Warn
 	}
 	return sources, warnings, lockChanged, nil
 }
+
+// ensureArchives fetches and extracts each archive origin, keyed like git
+// origins but without a version.
+func ensureArchives(ctx context.Context, state manifest.State, offline bool) (map[manifest.LockKey]string, error) {
+	paths := map[manifest.LockKey]string{}
+	for origin, sum := range state.Config.ArchiveSums() {
+		path, err := archive.Ensure(ctx, archiveOptions(state, origin, sum, offline))
+		if err != nil {
+			return nil, err
+		}
+		paths[manifest.LockKey{Origin: origin}] = path
+	}
+	return paths, nil
+}
+
+func archiveOptions(state manifest.State, origin string, sum string, offline bool) archive.Options {
+	return archive.Options{
+		Origin:   origin,
+		Sha256:   sum,
+		StoreDir: state.Paths.StoreDir,
+		CacheDir: state.Paths.CacheDir,
+		Offline:  offline,
+	}
+}
+
+// verifySkillHashes checks each store checkout against the hash recorded in
+// the lock, filling in hashes that older lockfiles do not carry yet. Replace
+// paths are working copies, so they are hashed from git objects but never
+// compared against the files on disk; a store checkout missing a locked skill
+// is an error.
+func verifySkillHashes(state manifest.State, resolutions map[manifest.LockKey]gitstore.OriginResolution) (bool, error) {
+	changed := false
+	for _, skill := range state.Config.Skills {
+		if skill.Version == "" {
+			continue
+		}
+		resolution, ok := resolutions[skill.LockKey()]
+		rev := state.Lock[skill.LockKey()]
+		if !ok || rev == "" {
+			continue
+		}
+		path := filepath.Join(resolution.SourcePath(), filepath.FromSlash(skill.Subdir))
+		if _, err := os.Stat(path); err != nil {
+			if !os.IsNotExist(err) {
+				return false, err
+			}
+			if resolution.UsingReplace {
+				continue
+			}
+			return false, fmt.Errorf("refusing to link skill %s: %s missing from checkout %s: %w", skill.Name, skill.Subdir, resolution.SourcePath(), gitstore.ErrPathNotFound)
+		}
+
+		key := skill.HashKey()
+		expected := state.Hashes[key]
+		if expected == "" {
+			hash, err := gitstore.TreeHash(resolution.Path, rev, skill.Subdir)
+			if err != nil {
+				return false, fmt.Errorf("hash skill %s: %w", skill.Name, err)
+			}
+			state.Hashes[key] = hash
+			expected = hash
+			changed = true
+		}
+		if resolution.UsingReplace {
+			continue
+		}
+		if err := gitstore.VerifyDirHash(path, expected); err != nil {
+			return false, fmt.Errorf("refusing to link skill %s: %w", skill.Name, err)
+		}
+	}
+	return changed, nil
+}
+
+// pruneStoreCheckouts drops exported revisions that no skill links to anymore.
+func pruneStoreCheckouts(checkoutsDir string, resolutions map[manifest.LockKey]gitstore.OriginResolution) error {
+	keep := map[string][]string{}
+	for key, resolution := range resolutions {
+		if resolution.Checkout == "" {
+			continue
+		}
+		keep[key.Origin] = append(keep[key.Origin], resolution.Rev)
+	}
+	for origin, revs := range keep {
+		if err := gitstore.PruneCheckouts(checkoutsDir, origin, revs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recordSigners stores the verified signer of each resolved revision,
+// dropping signers of origins that no longer have a verify policy.
+func recordSigners(state manifest.State, resolutions map[manifest.LockKey]gitstore.OriginResolution) bool {
+	changed := false
+	for key, resolution := range resolutions {
+		if resolution.UsingReplace {
+			continue
+		}
+		if _, ok := state.Config.VerifyFor(key.Origin); !ok {
+			if _, recorded := state.Signers[key]; recorded {
+				delete(state.Signers, key)
+				changed = true
+			}
+			continue
+		}
+		if state.Signers[key] != resolution.Signer {
+			state.Signers[key] = resolution.Signer
+			changed = true
+		}
+	}
+	return changed
+}
+
+// verifyLockedRevision checks origin's verify policy, if it has one, before
+// version is locked to rev, and records the signer.
+func verifyLockedRevision(state manifest.State, origin string, version string, repoPath string, rev string) error {
+	policy, ok := state.Config.VerifyFor(origin)
+	if !ok {
+		return nil
+	}
+	signer, err := gitstore.VerifyRevision(repoPath, version, rev, policy)
+	if err != nil {
+		return fmt.Errorf("verify %s %s: %w", origin, version, err)
+	}
+	state.Signers[manifest.LockKey{Origin: origin, Version: version}] = signer
+	return nil
+}
+
+func recordSkillHashes(state manifest.State, origin string, version string, repoPath string, rev string) error {
+	for _, skill := range state.Config.Skills {
+		if skill.Origin != origin || skill.Version != version {
+			continue
+		}
+		hash, err := gitstore.TreeHash(repoPath, rev, skill.Subdir)
+		if err != nil {
+			if errors.Is(err, gitstore.ErrPathNotFound) {
+				continue
+			}
+			return fmt.Errorf("hash skill %s: %w", skill.Name, err)
+		}
+		state.Hashes[skill.HashKey()] = hash
+	}
+	return nil
+}
@@ -1,27 +1,51 @@
 package asm
 
-import "github.com/jmmarotta/agent_skills_manager/internal/manifest"
+import (
+	"context"
+	"errors"
 
-func List() (ListReport, error) {
-	state, err := manifest.LoadState()
-	if err != nil {
-		return ListReport{}, err
+	"github.com/jmmarotta/agent_skills_manager/internal/manifest"
+)
+
+// List reports repo and global skills together, each tagged with its scope.
+func List(ctx context.Context) (ListReport, error) {
+	repoState, repoErr := loadState(ctx, false)
+	if repoErr != nil && !errors.Is(repoErr, manifest.ErrManifestNotFound) {
+		return ListReport{}, repoErr
+	}
+	globalState, globalErr := loadState(ctx, true)
+	if globalErr != nil && !errors.Is(globalErr, manifest.ErrManifestNotFound) {
+		return ListReport{}, globalErr
+	}
+	if repoErr != nil && globalErr != nil {
+		return ListReport{}, repoErr
 	}
 
-	if len(state.Config.Skills) == 0 {
+	report := ListReport{}
+	if repoErr == nil {
+		report.Skills = append(report.Skills, summarizeSkills(repoState.Config.Skills, scopeRepo)...)
+	}
+	if globalErr == nil {
+		report.Skills = append(report.Skills, summarizeSkills(globalState.Config.Skills, scopeGlobal)...)
+	}
+	if len(report.Skills) == 0 {
 		return ListReport{NoSkills: true}, nil
 	}
 
-	orderedSkills := manifest.SortedSkills(state.Config.Skills)
-	report := ListReport{Skills: make([]SkillSummary, 0, len(orderedSkills))}
+	return report, nil
+}
+
+func summarizeSkills(skills []manifest.Skill, scope string) []SkillSummary {
+	orderedSkills := manifest.SortedSkills(skills)
+	summaries := make([]SkillSummary, 0, len(orderedSkills))
 	for _, skill := range orderedSkills {
-		report.Skills = append(report.Skills, SkillSummary{
+		summaries = append(summaries, SkillSummary{
 			Name:    skill.Name,
+			Scope:   scope,
 			Origin:  skill.Origin,
 			Version: skill.Version,
 			Subdir:  skill.Subdir,
 		})
 	}
-
-	return report, nil
+	return summaries
 }
@@ -0,0 +1,96 @@
+package asm
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+
+	"github.com/jmmarotta/agent_skills_manager/internal/debug"
+	"github.com/jmmarotta/agent_skills_manager/internal/gitstore"
+	"github.com/jmmarotta/agent_skills_manager/internal/manifest"
+)
+
+type FixLockOptions struct {
+	Global bool
+	// Wait waits for another asm process to release the state lock.
+	Wait bool
+}
+
+// FixLock rewrites a lockfile left conflicted by a merge.
+func FixLock(ctx context.Context, opts FixLockOptions) (LockFixReport, error) {
+	unlock, err := lockState(ctx, stateLock{Global: opts.Global, Wait: opts.Wait})
+	if err != nil {
+		return LockFixReport{}, err
+	}
+	defer unlock()
+
+	state, err := loadConflictedState(ctx, opts.Global, extendsOptions{Fetch: true, Record: true})
+	if err != nil {
+		return LockFixReport{}, err
+	}
+	if state.LockConflict == nil {
+		return LockFixReport{Clean: true}, nil
+	}
+	return fixLockConflict(ctx, &state, false)
+}
+
+// fixLockConflict keeps the entries both sides agree on, re-resolves the
+// ones they disagree on against the store, drops entries the manifest no
+// longer references and saves a clean lock. Offline, a disagreement on a
+// used key cannot be re-resolved and is an error.
+func fixLockConflict(ctx context.Context, state *manifest.State, offline bool) (LockFixReport, error) {
+	recovery := state.LockConflict
+	report := LockFixReport{}
+	before := len(state.Lock) + len(recovery.Candidates)
+	used := usedLockKeys(state.Config)
+
+	keys := make([]manifest.LockKey, 0, len(recovery.Candidates))
+	for key := range recovery.Candidates {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].Origin != keys[j].Origin {
+			return keys[i].Origin < keys[j].Origin
+		}
+		return keys[i].Version < keys[j].Version
+	})
+
+	for _, key := range keys {
+		if !used[key] {
+			continue
+		}
+		if offline {
+			return LockFixReport{}, fmt.Errorf("%s %s is conflicted in %s; run asm lock fix online", key.Origin, key.Version, filepath.Base(state.LockPath))
+		}
+		candidates := recovery.Candidates[key]
+		repoPath, err := updateRepoPath(ctx, *state, key.Origin)
+		if err != nil {
+			return LockFixReport{}, err
+		}
+		rev, _, err := gitstore.ResolveRevision(repoPath, key.Origin, key.Version, map[manifest.LockKey]string{}, false)
+		if err != nil {
+			return LockFixReport{}, fmt.Errorf("resolve %s %s: %w", key.Origin, key.Version, err)
+		}
+		matched := false
+		for _, candidate := range candidates {
+			matched = matched || candidate == rev
+		}
+		debug.Logf("lock fix origin=%s version=%s rev=%s matched=%t", debug.SanitizeOrigin(key.Origin), key.Version, rev, matched)
+		if !matched {
+			report.Warnings = append(report.Warnings, fmt.Sprintf("%s %s matches neither side of the conflict; locked %s", key.Origin, key.Version, rev))
+		}
+		recovery.Choose(key, rev)
+		report.Resolved = append(report.Resolved, LockResolution{Origin: key.Origin, Version: key.Version, Rev: rev, Matched: matched})
+	}
+
+	pruneUnusedLock(*state)
+	report.Kept = len(state.Lock)
+	report.Dropped = before - report.Kept
+	state.LockConflict = nil
+
+	if err := manifest.SaveStateLock(*state); err != nil {
+		return LockFixReport{}, err
+	}
+	return report, nil
+}
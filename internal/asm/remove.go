@@ -1,6 +1,7 @@
 package asm
 
 import (
+	"context"
 	"fmt"
 	"os"
 
@@ -8,19 +9,36 @@ import (
 	"github.com/jmmarotta/agent_skills_manager/internal/manifest"
 )
 
-func Remove(names []string) (RemoveReport, error) {
-	state, err := manifest.LoadState()
+type RemoveOptions struct {
+	Names  []string
+	Global bool
+	// Wait waits for another asm process to release the state lock.
+	Wait bool
+}
+
+func Remove(ctx context.Context, opts RemoveOptions) (RemoveReport, error) {
+	unlock, err := lockState(ctx, stateLock{Global: opts.Global, Wait: opts.Wait})
+	if err != nil {
+		return RemoveReport{}, err
+	}
+	defer unlock()
+
+	state, err := loadLockedState(ctx, opts.Global, extendsOptions{Fetch: true, Record: true})
 	if err != nil {
 		return RemoveReport{}, err
 	}
 
-	uniqueNames := uniqueRemoveNames(names)
+	uniqueNames := uniqueRemoveNames(opts.Names)
 	removed := make([]SkillSummary, 0, len(uniqueNames))
 	warnings := []string{}
 	originOrder := []string{}
 	originSeen := map[string]bool{}
 
 	for _, name := range uniqueNames {
+		if skill, ok := manifest.FindSkill(state.Config.Skills, name); ok && skill.Inherited {
+			warnings = append(warnings, fmt.Sprintf("skill %q is inherited from extends; remove it from the base manifest", name))
+			continue
+		}
 		skill, ok := state.Config.RemoveSkill(name)
 		if !ok {
 			warnings = append(warnings, fmt.Sprintf("skill %q not found", name))
@@ -48,19 +66,26 @@ func Remove(names []string) (RemoveReport, error) {
 	for _, origin := range originOrder {
 		if !originInUse(state.Config, origin) {
 			delete(state.Config.Replace, origin)
-			deleteLockForOrigin(state.Lock, origin)
-			if err := os.RemoveAll(gitstore.RepoPath(state.Paths.StoreDir, origin)); err != nil {
+			// Other repos may still fetch from a shared store.
+			if !state.Paths.SharedStore {
+				if err := os.RemoveAll(gitstore.RepoPath(state.Paths.StoreDir, origin)); err != nil {
+					return RemoveReport{}, err
+				}
+			}
+			if err := os.RemoveAll(gitstore.CheckoutsPath(state.Paths.CheckoutsDir, origin)); err != nil {
 				return RemoveReport{}, err
 			}
 			prunedStores = append(prunedStores, origin)
 		}
 	}
 
+	pruneUnusedLock(state)
+
 	if err := manifest.SaveState(state); err != nil {
 		return RemoveReport{}, err
 	}
 
-	report, err := installSkills(state)
+	report, err := installSkills(ctx, state, InstallOptions{})
 	if err != nil {
 		return RemoveReport{}, fmt.Errorf("install skills: %w", err)
 	}
@@ -95,10 +120,42 @@ func originInUse(configValue manifest.Config, origin string) bool {
 	return false
 }
 
-func deleteLockForOrigin(lock map[manifest.LockKey]string, origin string) {
-	for key := range lock {
-		if key.Origin == origin {
-			delete(lock, key)
+// pruneUnusedLock drops lock entries, hashes and signers that no skill refers
+// to.
+func pruneUnusedLock(state manifest.State) {
+	usedKeys := usedLockKeys(state.Config)
+	usedHashes := map[manifest.HashKey]bool{}
+	for _, skill := range state.Config.Skills {
+		if skill.Version != "" {
+			usedHashes[skill.HashKey()] = true
+		}
+	}
+	for key := range state.Lock {
+		if !usedKeys[key] {
+			delete(state.Lock, key)
+		}
+	}
+	for key := range state.Signers {
+		if !usedKeys[key] {
+			delete(state.Signers, key)
+		}
+	}
+	for key := range state.Hashes {
+		if !usedHashes[key] {
+			delete(state.Hashes, key)
+		}
+	}
+}
+
+func usedLockKeys(configValue manifest.Config) map[manifest.LockKey]bool {
+	used := map[manifest.LockKey]bool{}
+	for _, skill := range configValue.Skills {
+		if skill.Version != "" {
+			used[skill.LockKey()] = true
 		}
 	}
+	if extends := configValue.Extends; extends != nil && extends.IsRemote() {
+		used[extends.LockKey()] = true
+	}
+	return used
 }
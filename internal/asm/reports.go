@@ -1,16 +1,22 @@
 package asm
 
-import "github.com/jmmarotta/agent_skills_manager/internal/linker"
+import (
+	"github.com/jmmarotta/agent_skills_manager/internal/linker"
+	"github.com/jmmarotta/agent_skills_manager/internal/manifest"
+)
 
 type InstallReport struct {
-	Linked   int
-	Pruned   int
-	Warnings []linker.Warning
-	NoSkills bool
+	Linked    int
+	Pruned    int
+	Warnings  []linker.Warning
+	NoSkills  bool
+	Selection manifest.Selection
+	Skipped   []string
 }
 
 type SkillSummary struct {
 	Name    string
+	Scope   string
 	Origin  string
 	Version string
 	Subdir  string
@@ -40,11 +46,14 @@ type FindReport struct {
 }
 
 type ShowReport struct {
-	Name    string `json:"name"`
-	Origin  string `json:"origin"`
-	Subdir  string `json:"subdir,omitempty"`
-	Version string `json:"version,omitempty"`
-	Replace string `json:"replace,omitempty"`
+	Name       string   `json:"name"`
+	Origin     string   `json:"origin"`
+	Subdir     string   `json:"subdir,omitempty"`
+	Version    string   `json:"version,omitempty"`
+	Constraint string   `json:"constraint,omitempty"`
+	Track      string   `json:"track,omitempty"`
+	Groups     []string `json:"groups,omitempty"`
+	Replace    string   `json:"replace,omitempty"`
 }
 
 type InitReport struct {
@@ -64,3 +73,61 @@ type RemoveReport struct {
 	Warnings     []string
 	NoChanges    bool
 }
+
+type ValidateReport struct {
+	ManifestPath string
+	Diagnostics  []manifest.Diagnostic
+}
+
+type LockFixReport struct {
+	Clean    bool
+	Kept     int
+	Dropped  int
+	Resolved []LockResolution
+	Warnings []string
+}
+
+type LockResolution struct {
+	Origin  string
+	Version string
+	Rev     string
+	// Matched is false when neither side of the conflict had the revision the
+	// store resolves to now.
+	Matched bool
+}
+
+type FetchReport struct {
+	Fetched   []string
+	Present   []string
+	Missing   []FetchMissing
+	NoEntries bool
+}
+
+// FetchMissing is a locked revision the store still lacks after a fetch.
+type FetchMissing struct {
+	Origin  string
+	Version string
+	Rev     string
+	// Sha256 is set instead of Version and Rev for archives.
+	Sha256 string
+	Reason string
+}
+
+type AuthStatusReport struct {
+	Origins   []OriginAuthStatus
+	NoOrigins bool
+}
+
+// OriginAuthStatus explains how asm would reach one origin. Credential never
+// holds a secret.
+type OriginAuthStatus struct {
+	Origin     string
+	URL        string
+	Rewritten  bool
+	Source     string
+	Credential string
+	// Anonymous is "ok", "skipped" for SSH, or why listing refs without
+	// credentials failed.
+	Anonymous string
+	Err       error
+}
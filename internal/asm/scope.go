@@ -0,0 +1,117 @@
+package asm
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+
+	"github.com/jmmarotta/agent_skills_manager/internal/filelock"
+	"github.com/jmmarotta/agent_skills_manager/internal/manifest"
+)
+
+const (
+	scopeRepo   = "repo"
+	scopeGlobal = "global"
+)
+
+// loadState loads the manifest and lock for commands that only read them. A
+// git extends base is never fetched or re-locked here; its locked revision
+// must already be in the store.
+func loadState(ctx context.Context, global bool) (manifest.State, error) {
+	state, err := readState(global)
+	if err != nil {
+		return manifest.State{}, err
+	}
+	if err := resolveExtends(ctx, &state, extendsOptions{}); err != nil {
+		return manifest.State{}, fmt.Errorf("%w; run asm install to fetch the extends base", err)
+	}
+	if err := requireCleanLock(state); err != nil {
+		return manifest.State{}, err
+	}
+	return state, nil
+}
+
+// loadLockedState is loadState for callers holding lockState, which may
+// fetch the extends base and record its revision as asked.
+func loadLockedState(ctx context.Context, global bool, extends extendsOptions) (manifest.State, error) {
+	state, err := loadConflictedState(ctx, global, extends)
+	if err != nil {
+		return manifest.State{}, err
+	}
+	if err := requireCleanLock(state); err != nil {
+		return manifest.State{}, err
+	}
+	return state, nil
+}
+
+// loadConflictedState loads like loadLockedState but leaves a conflicted
+// lockfile for the caller to fix.
+func loadConflictedState(ctx context.Context, global bool, extends extendsOptions) (manifest.State, error) {
+	state, err := readState(global)
+	if err != nil {
+		return manifest.State{}, err
+	}
+	if err := resolveExtends(ctx, &state, extends); err != nil {
+		return manifest.State{}, err
+	}
+	return state, nil
+}
+
+func readState(global bool) (manifest.State, error) {
+	var state manifest.State
+	var err error
+	if global {
+		state, err = manifest.LoadGlobalState()
+	} else {
+		state, err = manifest.LoadState()
+	}
+	if err != nil {
+		return manifest.State{}, err
+	}
+	if err := requireStoreLocking(state.Paths); err != nil {
+		return manifest.State{}, err
+	}
+	return state, nil
+}
+
+// requireStoreLocking refuses a shared store where file locks are not
+// enforced: other repos' asm processes would fetch into it unguarded.
+func requireStoreLocking(paths manifest.Paths) error {
+	if paths.SharedStore && !filelock.Supported {
+		return fmt.Errorf("shared store %s needs file locking, which asm does not support on %s; unset ASM_SHARED_STORE and ASM_STORE_DIR", paths.StoreDir, runtime.GOOS)
+	}
+	return nil
+}
+
+// loadOrInitState is for callers holding lockState; it may fetch and lock the
+// extends base.
+func loadOrInitState(ctx context.Context, global bool) (manifest.State, bool, error) {
+	var state manifest.State
+	var created bool
+	var err error
+	if global {
+		state, created, err = manifest.LoadOrInitGlobalState()
+	} else {
+		state, created, err = manifest.LoadOrInitState()
+	}
+	if err != nil {
+		return manifest.State{}, false, err
+	}
+	if err := requireStoreLocking(state.Paths); err != nil {
+		return manifest.State{}, false, err
+	}
+	if err := resolveExtends(ctx, &state, extendsOptions{Fetch: true, Record: true}); err != nil {
+		return manifest.State{}, false, err
+	}
+	if err := requireCleanLock(state); err != nil {
+		return manifest.State{}, false, err
+	}
+	return state, created, nil
+}
+
+func requireCleanLock(state manifest.State) error {
+	if state.LockConflict != nil {
+		return fmt.Errorf("%s has merge conflicts; run asm lock fix or asm install", state.LockPath)
+	}
+	return nil
+}
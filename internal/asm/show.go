@@ -1,13 +1,14 @@
 package asm
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/jmmarotta/agent_skills_manager/internal/manifest"
 )
 
-func Show(name string) (ShowReport, error) {
-	state, err := manifest.LoadState()
+func Show(ctx context.Context, name string) (ShowReport, error) {
+	state, err := loadState(ctx, false)
 	if err != nil {
 		return ShowReport{}, err
 	}
@@ -18,10 +19,13 @@ func Show(name string) (ShowReport, error) {
 	}
 
 	return ShowReport{
-		Name:    skill.Name,
-		Origin:  skill.Origin,
-		Subdir:  skill.Subdir,
-		Version: skill.Version,
-		Replace: state.Config.Replace[skill.Origin],
+		Name:       skill.Name,
+		Origin:     skill.Origin,
+		Subdir:     skill.Subdir,
+		Version:    skill.Version,
+		Constraint: state.Config.ConstraintFor(skill),
+		Track:      skill.Track,
+		Groups:     skill.Groups,
+		Replace:    state.Config.Replace[skill.Origin],
 	}, nil
 }
@@ -0,0 +1,122 @@
+package asm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmmarotta/agent_skills_manager/internal/debug"
+	"github.com/jmmarotta/agent_skills_manager/internal/filelock"
+	"github.com/jmmarotta/agent_skills_manager/internal/manifest"
+)
+
+const (
+	lockTimeoutEnv     = "ASM_LOCK_TIMEOUT"
+	defaultLockTimeout = 5 * time.Second
+	lockPollInterval   = 100 * time.Millisecond
+)
+
+// stateLock says which state lockState takes and how.
+type stateLock struct {
+	Global bool
+	// Wait waits for as long as another asm process holds the lock instead of
+	// giving up after the timeout.
+	Wait bool
+	// Init locks where a new manifest would be created when none exists yet,
+	// for commands that create one.
+	Init bool
+}
+
+// lockState takes the advisory lock every mutating command holds on the
+// state directory, so two asm processes never write the lockfile, store or
+// skills directory at once. Without Init, a manifest that does not exist yet
+// has nothing to protect, and loading it reports the error.
+//
+// The OS drops the lock when its holder exits, so a crashed run never blocks
+// the next one; the PID it left behind is only logged as stale. Waiting stops
+// when ctx is canceled, so Ctrl-C still ends a --wait.
+func lockState(ctx context.Context, opts stateLock) (func(), error) {
+	var paths manifest.Paths
+	var err error
+	if opts.Init {
+		paths, err = manifest.InitStatePaths(opts.Global)
+	} else {
+		paths, err = manifest.FindStatePaths(opts.Global)
+		if errors.Is(err, manifest.ErrManifestNotFound) {
+			return func() {}, nil
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	timeout, err := stateLockTimeout()
+	if err != nil {
+		return nil, err
+	}
+
+	path := manifest.StateLockPath(paths)
+	start := time.Now()
+	for {
+		lock, err := filelock.TryAcquire(path)
+		if err == nil {
+			if pid := lockHolder(path); pid != 0 {
+				debug.Logf("state lock path=%s stale pid=%d", path, pid)
+			}
+			if err := lock.Write([]byte(strconv.Itoa(os.Getpid()) + "\n")); err != nil {
+				lock.Release()
+				return nil, fmt.Errorf("record lock holder: %w", err)
+			}
+			debug.Logf("state lock acquired path=%s", path)
+			return func() {
+				lock.Write(nil)
+				lock.Release()
+			}, nil
+		}
+		if !errors.Is(err, filelock.ErrLocked) {
+			return nil, err
+		}
+		if !opts.Wait && time.Since(start) >= timeout {
+			holder := "another asm process"
+			if pid := lockHolder(path); pid != 0 {
+				holder = fmt.Sprintf("another asm process (pid %d)", pid)
+			}
+			return nil, fmt.Errorf("%s holds %s; gave up after %s (use --wait to wait for it)", holder, path, timeout)
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(lockPollInterval):
+		}
+	}
+}
+
+// stateLockTimeout is how long to wait for the state lock: $ASM_LOCK_TIMEOUT
+// as a duration such as 30s, or five seconds.
+func stateLockTimeout() (time.Duration, error) {
+	value := os.Getenv(lockTimeoutEnv)
+	if value == "" {
+		return defaultLockTimeout, nil
+	}
+	timeout, err := time.ParseDuration(value)
+	if err != nil || timeout < 0 {
+		return 0, fmt.Errorf("invalid %s %q: expected a duration such as 30s", lockTimeoutEnv, value)
+	}
+	return timeout, nil
+}
+
+// lockHolder returns the PID recorded in the lock file, or zero.
+func lockHolder(path string) int {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || pid <= 0 {
+		return 0
+	}
+	return pid
+}
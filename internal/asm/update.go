@@ -1,6 +1,7 @@
 package asm
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -14,95 +15,221 @@ import (
 	"github.com/jmmarotta/agent_skills_manager/internal/gitstore"
 	"github.com/jmmarotta/agent_skills_manager/internal/manifest"
 	"github.com/jmmarotta/agent_skills_manager/internal/source"
+	"github.com/jmmarotta/agent_skills_manager/internal/workpool"
 )
 
-func Update(selector string, pathFlag string) (UpdateReport, error) {
-	state, err := manifest.LoadState()
+type UpdateOptions struct {
+	Selector   string
+	Path       string
+	Prerelease bool
+	Global     bool
+	// Jobs bounds how many origins resolve at once.
+	Jobs int
+	// Wait waits for another asm process to release the state lock.
+	Wait bool
+}
+
+// updateGroup collects skills that resolve to the same version: one origin
+// under one constraint or tracked branch.
+type updateGroup struct {
+	origin     string
+	constraint string
+	track      string
+}
+
+func Update(ctx context.Context, opts UpdateOptions) (UpdateReport, error) {
+	unlock, err := lockState(ctx, stateLock{Global: opts.Global, Wait: opts.Wait})
+	if err != nil {
+		return UpdateReport{}, err
+	}
+	defer unlock()
+
+	state, err := loadLockedState(ctx, opts.Global, extendsOptions{Fetch: true, Record: true})
 	if err != nil {
 		return UpdateReport{}, fmt.Errorf("load manifest: %w", err)
 	}
-	selector = strings.TrimSpace(selector)
-	pathFlag = strings.TrimSpace(pathFlag)
-	debug.Logf("update start selector=%q path=%q", selector, pathFlag)
+	selector := strings.TrimSpace(opts.Selector)
+	pathFlag := strings.TrimSpace(opts.Path)
+	debug.Logf("update start selector=%q path=%q prerelease=%t", selector, pathFlag, opts.Prerelease)
 
 	if len(state.Config.Skills) == 0 {
 		return UpdateReport{Install: InstallReport{NoSkills: true}}, nil
 	}
 
-	origins, explicit, err := resolveUpdateOrigins(state.Config, selector, pathFlag)
+	targets, explicit, err := resolveUpdateTargets(state.Config, selector, pathFlag)
 	if err != nil {
 		return UpdateReport{}, err
 	}
-	updatedOrigins := make([]string, 0, len(origins))
-	for origin := range origins {
-		updatedOrigins = append(updatedOrigins, origin)
-	}
-	sort.Strings(updatedOrigins)
 
 	if state.Lock == nil {
 		state.Lock = map[manifest.LockKey]string{}
 	}
+	if state.Hashes == nil {
+		state.Hashes = map[manifest.HashKey]string{}
+	}
 
-	for origin, versionValue := range origins {
-		if !explicit && semver.IsValid(versionValue) && !module.IsPseudoVersion(versionValue) {
+	groups := []updateGroup{}
+	byGroup := map[updateGroup][]int{}
+	for _, index := range targets {
+		skill := state.Config.Skills[index]
+		constraint := state.Config.ConstraintFor(skill)
+		if !explicit && constraint == "" && skill.Track == "" && semver.IsValid(skill.Version) && !module.IsPseudoVersion(skill.Version) {
 			continue
 		}
+		group := updateGroup{origin: skill.Origin, constraint: constraint, track: skill.Track}
+		if _, ok := byGroup[group]; !ok {
+			groups = append(groups, group)
+		}
+		byGroup[group] = append(byGroup[group], index)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].origin != groups[j].origin {
+			return groups[i].origin < groups[j].origin
+		}
+		if groups[i].constraint != groups[j].constraint {
+			return groups[i].constraint < groups[j].constraint
+		}
+		return groups[i].track < groups[j].track
+	})
 
-		resolved, err := resolveLatestOrigin(state, origin)
-		if err != nil {
+	outcomes, err := resolveUpdateGroups(ctx, state, groups, opts)
+	if err != nil {
+		return UpdateReport{}, err
+	}
+
+	origins := []string{}
+	for position, group := range groups {
+		resolved := outcomes[position].resolved
+		repoPath := outcomes[position].repoPath
+		for _, index := range byGroup[group] {
+			skill := state.Config.Skills[index]
+			debug.Logf(
+				"update skill=%s origin=%s from=%s to=%s rev=%s",
+				skill.Name,
+				debug.SanitizeOrigin(group.origin),
+				skill.Version,
+				resolved.Version,
+				resolved.Rev,
+			)
+			skill.Version = resolved.Version
+			// Updating an inherited skill pins it locally as an override.
+			skill.Inherited = false
+			state.Config.Skills[index] = skill
+		}
+		// Replace paths are working copies, so like install, update only
+		// verifies revisions resolved from the store.
+		if repoPath == gitstore.RepoPath(state.Paths.StoreDir, group.origin) {
+			if err := verifyLockedRevision(state, group.origin, resolved.Version, repoPath, resolved.Rev); err != nil {
+				return UpdateReport{}, err
+			}
+		}
+		state.Lock[manifest.LockKey{Origin: group.origin, Version: resolved.Version}] = resolved.Rev
+		if err := recordSkillHashes(state, group.origin, resolved.Version, repoPath, resolved.Rev); err != nil {
 			return UpdateReport{}, err
 		}
-		debug.Logf(
-			"update origin=%s from=%s to=%s rev=%s",
-			debug.SanitizeOrigin(origin),
-			versionValue,
-			resolved.Version,
-			resolved.Rev,
-		)
-
-		updateOriginVersion(state.Config.Skills, origin, resolved.Version)
-		deleteLockForOrigin(state.Lock, origin)
-		state.Lock[manifest.LockKey{Origin: origin, Version: resolved.Version}] = resolved.Rev
+		if len(origins) == 0 || origins[len(origins)-1] != group.origin {
+			origins = append(origins, group.origin)
+		}
 	}
+	pruneUnusedLock(state)
 
 	if err := manifest.SaveState(state); err != nil {
 		return UpdateReport{}, fmt.Errorf("save manifest: %w", err)
 	}
 
-	report, err := installSkills(state)
+	report, err := installSkills(ctx, state, InstallOptions{Jobs: opts.Jobs})
 	if err != nil {
 		return UpdateReport{}, fmt.Errorf("install skills: %w", err)
 	}
 
-	return UpdateReport{Install: report, UpdatedOrigins: updatedOrigins}, nil
+	return UpdateReport{Install: report, UpdatedOrigins: origins}, nil
+}
+
+type updateOutcome struct {
+	resolved gitstore.Resolved
+	repoPath string
+}
+
+// resolveUpdateGroups resolves groups concurrently, one worker per origin.
+// Each worker fetches its origin once and resolves every group of it from
+// that repo, and every origin that failed is reported. Outcomes line up with
+// groups.
+func resolveUpdateGroups(ctx context.Context, state manifest.State, groups []updateGroup, opts UpdateOptions) ([]updateOutcome, error) {
+	origins := []string{}
+	byOrigin := map[string][]int{}
+	for position, group := range groups {
+		if _, ok := byOrigin[group.origin]; !ok {
+			origins = append(origins, group.origin)
+		}
+		byOrigin[group.origin] = append(byOrigin[group.origin], position)
+	}
+
+	outcomes := make([]updateOutcome, len(groups))
+	failed := make([]error, len(origins))
+	workpool.Run(opts.Jobs, len(origins), func(index int) {
+		repoPath, err := updateRepoPath(ctx, state, origins[index])
+		if err != nil {
+			failed[index] = err
+			return
+		}
+		for _, position := range byOrigin[origins[index]] {
+			group := groups[position]
+			outcome := updateOutcome{repoPath: repoPath}
+			switch {
+			case group.track != "":
+				outcome.resolved, err = resolveTrackedOrigin(repoPath, group.origin, group.track)
+			case group.constraint != "":
+				outcome.resolved, err = resolveConstrainedOrigin(repoPath, group.origin, group.constraint, opts.Prerelease)
+			default:
+				outcome.resolved, outcome.repoPath, err = resolveLatestOrigin(ctx, state, group.origin, repoPath)
+			}
+			if err != nil {
+				failed[index] = err
+				return
+			}
+			outcomes[position] = outcome
+		}
+	})
+
+	var failures gitstore.OriginErrors
+	for index, err := range failed {
+		if err != nil {
+			failures = append(failures, gitstore.OriginError{Origin: origins[index], Err: err})
+		}
+	}
+	if len(failures) > 0 {
+		return nil, failures
+	}
+	return outcomes, nil
 }
 
-func resolveUpdateOrigins(configValue manifest.Config, selector string, pathFlag string) (map[string]string, bool, error) {
-	origins := make(map[string]string)
+// resolveUpdateTargets returns the indexes of the skills an update applies to.
+// Skills of one origin may pin different versions, so a skill selector only
+// moves that skill while an origin selector moves every skill of the origin.
+func resolveUpdateTargets(configValue manifest.Config, selector string, pathFlag string) ([]int, bool, error) {
+	targets := []int{}
 	if selector == "" {
 		if pathFlag != "" {
 			return nil, false, fmt.Errorf("--path requires an origin selector")
 		}
-		for _, skill := range configValue.Skills {
-			if skill.Version == "" {
+		for index, skill := range configValue.Skills {
+			if skill.Version == "" || skill.Inherited {
 				continue
 			}
-			if !module.IsPseudoVersion(skill.Version) {
+			if !module.IsPseudoVersion(skill.Version) && configValue.ConstraintFor(skill) == "" && skill.Track == "" {
 				continue
 			}
-			origins[skill.Origin] = skill.Version
+			targets = append(targets, index)
 		}
-		return origins, false, nil
+		return targets, false, nil
 	}
 
 	if pathFlag == "" {
-		skill, found := manifest.FindSkill(configValue.Skills, selector)
-		if found {
-			if skill.Version == "" {
+		if index, found := findSkillIndex(configValue.Skills, selector); found {
+			if configValue.Skills[index].Version == "" {
 				return nil, true, fmt.Errorf("skill %q does not have a version", selector)
 			}
-			origins[skill.Origin] = skill.Version
-			return origins, true, nil
+			return append(targets, index), true, nil
 		}
 	}
 
@@ -117,23 +244,21 @@ func resolveUpdateOrigins(configValue manifest.Config, selector string, pathFlag
 			return nil, true, err
 		}
 
-		skill, found := findSkillByIdentity(configValue.Skills, origin, normalizedPath)
+		index, found := findSkillByIdentity(configValue.Skills, origin, normalizedPath)
 		if !found {
 			return nil, true, fmt.Errorf("skill not found for origin %q and path %q", selector, pathFlag)
 		}
-		if skill.Version == "" {
-			return nil, true, fmt.Errorf("skill %q does not have a version", skill.Name)
+		if configValue.Skills[index].Version == "" {
+			return nil, true, fmt.Errorf("skill %q does not have a version", configValue.Skills[index].Name)
 		}
-		origins[skill.Origin] = skill.Version
-		return origins, true, nil
+		return append(targets, index), true, nil
 	}
 
-	skill, found := findSkillByOrigin(configValue.Skills, origin)
-	if !found {
+	targets = findSkillsByOrigin(configValue.Skills, origin)
+	if len(targets) == 0 {
 		return nil, true, fmt.Errorf("origin %q not found", selector)
 	}
-	origins[skill.Origin] = skill.Version
-	return origins, true, nil
+	return targets, true, nil
 }
 
 func normalizeUpdateOrigin(value string) (string, error) {
@@ -155,6 +280,9 @@ func normalizeUpdateOrigin(value string) (string, error) {
 		return "", err
 	}
 
+	if source.IsArchiveOrigin(origin) {
+		return "", fmt.Errorf("archive origins are pinned by sha256; run asm add %s --sha256 <hex> to change it", origin)
+	}
 	if source.IsRemoteOrigin(origin) {
 		origin, _ = source.ParseOriginRef(origin)
 		return source.NormalizeOrigin(origin), nil
@@ -183,62 +311,103 @@ func normalizeUpdateSubdir(value string) (string, error) {
 	return filepath.ToSlash(cleaned), nil
 }
 
-func findSkillByIdentity(skills []manifest.Skill, origin string, subdir string) (manifest.Skill, bool) {
-	for _, skill := range skills {
-		if skill.Origin == origin && skill.Subdir == subdir {
-			return skill, true
+func findSkillIndex(skills []manifest.Skill, name string) (int, bool) {
+	for index, skill := range skills {
+		if skill.Name == name {
+			return index, true
 		}
 	}
-	return manifest.Skill{}, false
+	return -1, false
 }
 
-func findSkillByOrigin(skills []manifest.Skill, origin string) (manifest.Skill, bool) {
-	for _, skill := range skills {
-		if skill.Origin != origin {
-			continue
-		}
-		if skill.Version == "" {
-			return manifest.Skill{}, false
+func findSkillByIdentity(skills []manifest.Skill, origin string, subdir string) (int, bool) {
+	for index, skill := range skills {
+		if skill.Origin == origin && skill.Subdir == subdir {
+			return index, true
 		}
-		return skill, true
 	}
-	return manifest.Skill{}, false
+	return -1, false
 }
 
-func updateOriginVersion(skills []manifest.Skill, origin string, version string) {
+func findSkillsByOrigin(skills []manifest.Skill, origin string) []int {
+	indexes := []int{}
 	for index, skill := range skills {
-		if skill.Origin != origin || skill.Version == "" {
-			continue
+		if skill.Origin == origin && skill.Version != "" {
+			indexes = append(indexes, index)
 		}
-		skill.Version = version
-		skills[index] = skill
 	}
+	return indexes
 }
 
-func resolveLatestOrigin(state manifest.State, origin string) (gitstore.Resolved, error) {
-	replacePath := ""
-	if state.Config.Replace != nil {
-		replacePath = state.Config.Replace[origin]
-	}
-	if replacePath != "" {
-		if info, err := os.Stat(replacePath); err == nil && info.IsDir() {
-			resolved, err := gitstore.ResolveForRefAt(replacePath, "")
-			if err == nil {
-				return resolved, nil
-			}
-			debug.Logf("update replace fallback origin=%s err=%v", debug.SanitizeOrigin(origin), err)
+// resolveLatestOrigin resolves the default branch of origin from repoPath.
+// A replace path resolves its own HEAD, falling back to the store clone when
+// that fails.
+func resolveLatestOrigin(ctx context.Context, state manifest.State, origin string, repoPath string) (gitstore.Resolved, string, error) {
+	path := gitstore.RepoPath(state.Paths.StoreDir, origin)
+	if repoPath != path {
+		resolved, err := gitstore.ResolveForRefAt(repoPath, "")
+		if err == nil {
+			return resolved, repoPath, nil
+		}
+		debug.Logf("update replace fallback origin=%s err=%v", debug.SanitizeOrigin(origin), err)
+		if err := gitstore.EnsureRepo(ctx, path, origin); err != nil {
+			return gitstore.Resolved{}, "", err
 		}
 	}
 
-	path := gitstore.RepoPath(state.Paths.StoreDir, origin)
-	if err := gitstore.EnsureRepo(path, origin); err != nil {
+	resolved, err := resolveRemoteRef(ctx, path, origin, "")
+	if err != nil {
+		return gitstore.Resolved{}, "", fmt.Errorf("resolve latest for %s: %w", debug.SanitizeOrigin(origin), err)
+	}
+
+	return resolved, path, nil
+}
+
+// resolveConstrainedOrigin picks the highest tag of origin in repoPath that
+// satisfies constraint.
+func resolveConstrainedOrigin(repoPath string, origin string, constraint string, prerelease bool) (gitstore.Resolved, error) {
+	parsed, err := manifest.ParseConstraint(constraint)
+	if err != nil {
 		return gitstore.Resolved{}, err
 	}
 
-	resolved, err := resolveRemoteRef(path, origin, "")
+	tags, err := gitstore.ListSemverTags(repoPath)
 	if err != nil {
-		return gitstore.Resolved{}, fmt.Errorf("resolve latest for %s: %w", debug.SanitizeOrigin(origin), err)
+		return gitstore.Resolved{}, err
+	}
+	version, ok := parsed.Latest(tags, prerelease)
+	if !ok {
+		return gitstore.Resolved{}, fmt.Errorf("no tag of %s satisfies %q", debug.SanitizeOrigin(origin), constraint)
 	}
 
+	resolved, err := gitstore.ResolveForRefAt(repoPath, version)
+	if err != nil {
+		return gitstore.Resolved{}, fmt.Errorf("resolve %s for %s: %w", version, debug.SanitizeOrigin(origin), err)
+	}
 	return resolved, nil
 }
+
+// resolveTrackedOrigin resolves the tip of the branch a skill tracks.
+func resolveTrackedOrigin(repoPath string, origin string, track string) (gitstore.Resolved, error) {
+	resolved, err := gitstore.ResolveForBranchAt(repoPath, track)
+	if err != nil {
+		return gitstore.Resolved{}, fmt.Errorf("resolve branch %q for %s: %w", track, debug.SanitizeOrigin(origin), err)
+	}
+	return resolved, nil
+}
+
+// updateRepoPath returns the repo to resolve origin from: its replace path
+// when usable, else the store clone after fetching.
+func updateRepoPath(ctx context.Context, state manifest.State, origin string) (string, error) {
+	if replacePath := state.Config.Replace[origin]; replacePath != "" {
+		if info, err := os.Stat(replacePath); err == nil && info.IsDir() {
+			return replacePath, nil
+		}
+	}
+
+	repoPath := gitstore.RepoPath(state.Paths.StoreDir, origin)
+	if err := gitstore.EnsureRepo(ctx, repoPath, origin); err != nil {
+		return "", err
+	}
+	return repoPath, nil
+}
@@ -0,0 +1,30 @@
+package asm
+
+import (
+	"fmt"
+
+	"github.com/jmmarotta/agent_skills_manager/internal/manifest"
+)
+
+func Validate(global bool) (ValidateReport, error) {
+	var path string
+	var err error
+	if global {
+		path, err = manifest.FindGlobalManifestPath()
+	} else {
+		path, err = manifest.FindManifestPath("")
+	}
+	if err != nil {
+		return ValidateReport{}, err
+	}
+
+	diagnostics, err := manifest.Check(path)
+	if err != nil {
+		return ValidateReport{}, fmt.Errorf("check manifest: %w", err)
+	}
+	return ValidateReport{ManifestPath: path, Diagnostics: diagnostics}, nil
+}
+
+func Schema() map[string]any {
+	return manifest.Schema()
+}
@@ -6,7 +6,11 @@ import (
 	"github.com/jmmarotta/agent_skills_manager/internal/asm"
 )
 
-const addPathFlag = "path"
+const (
+	addPathFlag   = "path"
+	addTrackFlag  = "track"
+	addSha256Flag = "sha256"
+)
 
 func newAddCommand() *cobra.Command {
 	cmd := &cobra.Command{
@@ -18,6 +22,9 @@ func newAddCommand() *cobra.Command {
 	}
 
 	cmd.Flags().String(addPathFlag, "", "Subdirectory path to install")
+	cmd.Flags().String(addTrackFlag, "", "Branch that asm update follows")
+	cmd.Flags().String(addSha256Flag, "", "SHA-256 that an archive url must match")
+	cmd.Flags().Bool(globalFlag, false, "Add to the global manifest")
 
 	return cmd
 }
@@ -28,7 +35,34 @@ func runAdd(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	report, err := asm.Add(args[0], pathFlag)
+	track, err := cmd.Flags().GetString(addTrackFlag)
+	if err != nil {
+		return err
+	}
+
+	sha256, err := cmd.Flags().GetString(addSha256Flag)
+	if err != nil {
+		return err
+	}
+
+	global, err := cmd.Flags().GetBool(globalFlag)
+	if err != nil {
+		return err
+	}
+
+	wait, err := readBoolFlag(cmd, waitFlag)
+	if err != nil {
+		return err
+	}
+
+	report, err := asm.Add(cmd.Context(), asm.AddOptions{
+		Input:  args[0],
+		Path:   pathFlag,
+		Track:  track,
+		Sha256: sha256,
+		Global: global,
+		Wait:   wait,
+	})
 	if err != nil {
 		return err
 	}
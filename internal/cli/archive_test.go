@@ -0,0 +1,128 @@
+package cli
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jmmarotta/agent_skills_manager/internal/manifest"
+)
+
+func serveSkillsTarball(t *testing.T) (string, string) {
+	var buffer bytes.Buffer
+	compressed := gzip.NewWriter(&buffer)
+	writer := tar.NewWriter(compressed)
+	for _, name := range []string{"skills-1.2.0/skills/bar/SKILL.md", "skills-1.2.0/skills/foo/SKILL.md"} {
+		content := "# skill\n"
+		if err := writer.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatalf("write header: %v", err)
+		}
+		if _, err := writer.Write([]byte(content)); err != nil {
+			t.Fatalf("write entry: %v", err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("close tar: %v", err)
+	}
+	if err := compressed.Close(); err != nil {
+		t.Fatalf("close gzip: %v", err)
+	}
+	payload := buffer.Bytes()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(payload)
+	}))
+	t.Cleanup(server.Close)
+	digest := sha256.Sum256(payload)
+	return server.URL + "/skills-1.2.0.tar.gz", hex.EncodeToString(digest[:])
+}
+
+func TestAddArchiveRecordsChecksum(t *testing.T) {
+	origin, sum := serveSkillsTarball(t)
+	repoRoot := t.TempDir()
+	setWorkingDir(t, repoRoot)
+
+	cmd, _, _ := newTestCommand()
+	cmd.SetArgs([]string{"add", origin})
+	if err := cmd.Execute(); err == nil || !strings.Contains(err.Error(), "--sha256") {
+		t.Fatalf("expected --sha256 to be required, got %v", err)
+	}
+
+	cmd, _, _ = newTestCommand()
+	cmd.SetArgs([]string{"add", origin, "--sha256", strings.Repeat("0", 64)})
+	if err := cmd.Execute(); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Fatalf("expected a checksum mismatch, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(repoRoot, "skills.jsonc")); !os.IsNotExist(err) {
+		t.Fatalf("expected no manifest after a mismatch, got %v", err)
+	}
+
+	cmd, _, _ = newTestCommand()
+	cmd.SetArgs([]string{"add", origin, "--sha256", sum})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("add: %v", err)
+	}
+
+	loaded, err := manifest.Load(filepath.Join(repoRoot, "skills.jsonc"))
+	if err != nil {
+		t.Fatalf("load manifest: %v", err)
+	}
+	if len(loaded.Skills) != 2 {
+		t.Fatalf("expected 2 skills, got %+v", loaded.Skills)
+	}
+	for _, skill := range loaded.Skills {
+		if skill.Origin != origin || skill.Sha256 != sum || skill.Version != "" {
+			t.Fatalf("expected an archive skill pinned by sha256, got %+v", skill)
+		}
+	}
+	lock, err := os.ReadFile(filepath.Join(repoRoot, "skills-lock.json"))
+	if err != nil {
+		t.Fatalf("read lock: %v", err)
+	}
+	if !strings.Contains(string(lock), `"sha256": "`+sum+`"`) || strings.Contains(string(lock), `"rev"`) {
+		t.Fatalf("expected the lock to record the checksum instead of a rev, got %s", lock)
+	}
+
+	cmd, stdout, _ := newTestCommand()
+	cmd.SetArgs([]string{"validate"})
+	if err := cmd.Execute(); err != nil || !strings.Contains(stdout.String(), "No problems found.") {
+		t.Fatalf("expected a clean validate, got %v: %s", err, stdout.String())
+	}
+
+	extracted := filepath.Join(repoRoot, ".asm", "store", "archives", sum)
+	assertSymlink(t, filepath.Join(repoRoot, "skills", "foo"), filepath.Join(extracted, "skills", "foo"))
+
+	// The verified download stays cached, so offline installs can extract it
+	// again.
+	if err := os.RemoveAll(filepath.Join(repoRoot, ".asm", "store")); err != nil {
+		t.Fatalf("remove store: %v", err)
+	}
+	cmd, stdout, _ = newTestCommand()
+	cmd.SetArgs([]string{"fetch"})
+	if err := cmd.Execute(); err != nil || !strings.Contains(stdout.String(), "Fetched: "+origin) {
+		t.Fatalf("expected fetch to extract the archive, got %v: %s", err, stdout.String())
+	}
+	if err := os.RemoveAll(filepath.Join(repoRoot, ".asm", "store")); err != nil {
+		t.Fatalf("remove store: %v", err)
+	}
+	cmd, _, _ = newTestCommand()
+	cmd.SetArgs([]string{"install", "--offline"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("install --offline: %v", err)
+	}
+	assertSymlink(t, filepath.Join(repoRoot, "skills", "bar"), filepath.Join(extracted, "skills", "bar"))
+
+	cmd, _, _ = newTestCommand()
+	cmd.SetArgs([]string{"update", origin})
+	if err := cmd.Execute(); err == nil || !strings.Contains(err.Error(), "pinned by sha256") {
+		t.Fatalf("expected update to refuse archive origins, got %v", err)
+	}
+}
@@ -0,0 +1,48 @@
+package cli
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/jmmarotta/agent_skills_manager/internal/asm"
+)
+
+func newAuthCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "auth",
+		Short: "Inspect credentials for git origins",
+	}
+
+	cmd.AddCommand(newAuthStatusCommand())
+
+	return cmd
+}
+
+func newAuthStatusCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "status [origin]",
+		Short: "Show the URL and credentials asm would use for each origin",
+		Args:  cobra.MaximumNArgs(1),
+		RunE:  runAuthStatus,
+	}
+
+	cmd.Flags().Bool(globalFlag, false, "Check origins in the global manifest")
+
+	return cmd
+}
+
+func runAuthStatus(cmd *cobra.Command, args []string) error {
+	global, err := cmd.Flags().GetBool(globalFlag)
+	if err != nil {
+		return err
+	}
+	opts := asm.AuthStatusOptions{Global: global}
+	if len(args) == 1 {
+		opts.Origin = args[0]
+	}
+
+	report, err := asm.AuthStatus(cmd.Context(), opts)
+	if err != nil {
+		return err
+	}
+	return printAuthStatusReport(report, cmd.OutOrStdout())
+}
@@ -0,0 +1,80 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+
+	"github.com/jmmarotta/agent_skills_manager/internal/manifest"
+)
+
+func TestAuthStatusExplainsEachOrigin(t *testing.T) {
+	originDir := t.TempDir()
+	repo, err := git.PlainInit(originDir, false)
+	if err != nil {
+		t.Fatalf("init repo: %v", err)
+	}
+	touchSkill(t, originDir+"/plugins/foo")
+	commitPaths(t, repo, "init", time.Now().Add(-time.Minute), "plugins/foo/SKILL.md")
+
+	public := "https://github.com/acme/marketplace"
+	private := "https://127.0.0.1:1/acme/private"
+	useGitRewrite(t, originDir, public)
+
+	repoRoot := t.TempDir()
+	setWorkingDir(t, repoRoot)
+	t.Setenv("ASM_TOKEN_127_0_0_1", "secret-token")
+	t.Setenv("ASM_GIT_RETRIES", "0")
+	saveConfig(t, repoRoot, manifest.Config{
+		Skills: []manifest.Skill{
+			{Name: "foo", Origin: public, Subdir: "plugins/foo", Version: "v1.0.0"},
+			{Name: "bar", Origin: private, Version: "v1.0.0"},
+		},
+	})
+
+	cmd, stdout, _ := newTestCommand()
+	cmd.SetArgs([]string{"auth", "status"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("auth status: %v", err)
+	}
+	output := stdout.String()
+	if strings.Contains(output, "secret-token") {
+		t.Fatalf("expected the token to be redacted, got %q", output)
+	}
+
+	// Origins are sorted, so the private one comes first.
+	sections := strings.Split(output, "\n\n")
+	if len(sections) != 2 {
+		t.Fatalf("expected two origins, got %q", output)
+	}
+	for _, want := range []string{
+		public,
+		"url:         " + originDir + " (rewritten by insteadOf)",
+		"credentials: none",
+		"anonymous:   ok",
+	} {
+		if !strings.Contains(sections[1], want) {
+			t.Fatalf("expected %q for the public origin, got %q", want, sections[1])
+		}
+	}
+	for _, want := range []string{
+		private,
+		"credentials: ASM_TOKEN_127_0_0_1 (user x-access-token, password redacted)",
+		"anonymous:   list remote refs",
+	} {
+		if !strings.Contains(sections[0], want) {
+			t.Fatalf("expected %q for the private origin, got %q", want, sections[0])
+		}
+	}
+
+	cmd, stdout, _ = newTestCommand()
+	cmd.SetArgs([]string{"auth", "status", private})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("auth status origin: %v", err)
+	}
+	if strings.Contains(stdout.String(), public) || !strings.Contains(stdout.String(), private) {
+		t.Fatalf("expected only the named origin, got %q", stdout.String())
+	}
+}
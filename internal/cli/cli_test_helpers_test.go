@@ -21,6 +21,13 @@ func newTestCommand() (*cobra.Command, *bytes.Buffer, *bytes.Buffer) {
 }
 
 func setWorkingDir(t *testing.T, dir string) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("ASM_STORE_DIR", "")
+	t.Setenv("ASM_SHARED_STORE", "")
+	t.Setenv("ASM_OFFLINE", "")
+	t.Setenv("ASM_LOCK_TIMEOUT", "")
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+
 	current, err := os.Getwd()
 	if err != nil {
 		t.Fatalf("getwd: %v", err)
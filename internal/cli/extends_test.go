@@ -0,0 +1,102 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+
+	"github.com/jmmarotta/agent_skills_manager/internal/manifest"
+)
+
+func TestInstallMergesGitExtendsBaseline(t *testing.T) {
+	origin := "https://github.com/acme/baseline"
+	baseDir := t.TempDir()
+	repo, err := git.PlainInit(baseDir, false)
+	if err != nil {
+		t.Fatalf("init repo: %v", err)
+	}
+	touchSkill(t, filepath.Join(baseDir, "skills", "review"))
+	touchSkill(t, filepath.Join(baseDir, "skills", "notes"))
+	baseline := `{
+  "skills": [
+    {"name": "review", "origin": "` + origin + `", "subdir": "skills/review", "version": "v1.0.0"},
+    {"name": "notes", "origin": "` + origin + `", "subdir": "skills/notes", "version": "v1.0.0"}
+  ]
+}
+`
+	if err := os.WriteFile(filepath.Join(baseDir, "team.jsonc"), []byte(baseline), 0o644); err != nil {
+		t.Fatalf("write baseline: %v", err)
+	}
+	commitPaths(t, repo, "baseline", time.Now().Add(-time.Minute),
+		"team.jsonc",
+		filepath.Join("skills", "review", "SKILL.md"),
+		filepath.Join("skills", "notes", "SKILL.md"),
+	)
+	tagHead(t, repo, "v1.0.0")
+	useGitRewrite(t, baseDir, origin)
+
+	localNotes := filepath.Join(t.TempDir(), "notes")
+	touchSkill(t, localNotes)
+
+	repoRoot := t.TempDir()
+	setWorkingDir(t, repoRoot)
+	saveConfig(t, repoRoot, manifest.Config{
+		Extends: &manifest.Extends{Origin: origin, Version: "v1.0.0", Path: "team.jsonc"},
+		Skills:  []manifest.Skill{{Name: "notes", Origin: localNotes}},
+	})
+
+	// Read-only commands use the stored base only; they neither fetch it nor
+	// write the lock.
+	cmd, _, _ := newTestCommand()
+	cmd.SetArgs([]string{"ls"})
+	if err := cmd.Execute(); err == nil || !strings.Contains(err.Error(), "run asm install") {
+		t.Fatalf("expected ls to ask for an install first, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(repoRoot, "skills-lock.json")); !os.IsNotExist(err) {
+		t.Fatalf("expected ls not to write the lock, got %v", err)
+	}
+
+	cmd, _, _ = newTestCommand()
+	cmd.SetArgs([]string{"install"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("install: %v", err)
+	}
+
+	cmd, stdout, _ := newTestCommand()
+	cmd.SetArgs([]string{"ls"})
+	if err := cmd.Execute(); err != nil || !strings.Contains(stdout.String(), "review") {
+		t.Fatalf("expected ls to show the stored base, got %v: %s", err, stdout.String())
+	}
+
+	assertSkillContent(t, filepath.Join(repoRoot, "skills", "review", "SKILL.md"), "# skill")
+	assertSymlink(t, filepath.Join(repoRoot, "skills", "notes"), localNotes)
+
+	lock, err := manifest.LoadLock(filepath.Join(repoRoot, "skills-lock.json"))
+	if err != nil {
+		t.Fatalf("load lock: %v", err)
+	}
+	if lock[manifest.LockKey{Origin: origin, Version: "v1.0.0"}] == "" {
+		t.Fatalf("expected baseline pinned in the local lock, got %v", lock)
+	}
+
+	cmd, _, stderr := newTestCommand()
+	cmd.SetArgs([]string{"remove", "review"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+	if !strings.Contains(stderr.String(), "inherited") {
+		t.Fatalf("expected inherited warning, got %q", stderr.String())
+	}
+
+	state, err := manifest.LoadStateAt(filepath.Join(repoRoot, "skills.jsonc"))
+	if err != nil {
+		t.Fatalf("load state: %v", err)
+	}
+	if err := assertNames(state.Config.Skills, "notes"); err != nil {
+		t.Fatalf("expected only the local override on disk: %v", err)
+	}
+}
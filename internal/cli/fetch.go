@@ -0,0 +1,45 @@
+package cli
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/jmmarotta/agent_skills_manager/internal/asm"
+	"github.com/jmmarotta/agent_skills_manager/internal/workpool"
+)
+
+func newFetchCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "fetch",
+		Short: "Download every locked revision into the store without linking",
+		Args:  cobra.NoArgs,
+		RunE:  runFetch,
+	}
+
+	cmd.Flags().Bool(globalFlag, false, "Fetch revisions locked by the global manifest")
+	cmd.Flags().Int(jobsFlag, workpool.DefaultJobs, "Number of origins to fetch at once")
+
+	return cmd
+}
+
+func runFetch(cmd *cobra.Command, _ []string) error {
+	global, err := cmd.Flags().GetBool(globalFlag)
+	if err != nil {
+		return err
+	}
+
+	jobs, err := readJobsFlag(cmd)
+	if err != nil {
+		return err
+	}
+
+	wait, err := readBoolFlag(cmd, waitFlag)
+	if err != nil {
+		return err
+	}
+
+	report, err := asm.Fetch(cmd.Context(), asm.FetchOptions{Global: global, Jobs: jobs, Wait: wait})
+	if err != nil {
+		return err
+	}
+	return printFetchReport(report, cmd.OutOrStdout())
+}
@@ -0,0 +1,100 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+
+	"github.com/jmmarotta/agent_skills_manager/internal/gitstore"
+	"github.com/jmmarotta/agent_skills_manager/internal/manifest"
+)
+
+func TestFetchPopulatesStoreFromLock(t *testing.T) {
+	originDir := t.TempDir()
+	repo, err := git.PlainInit(originDir, false)
+	if err != nil {
+		t.Fatalf("init repo: %v", err)
+	}
+	touchSkill(t, filepath.Join(originDir, "plugins", "foo"))
+	commitPaths(t, repo, "init", time.Now().Add(-time.Minute), filepath.Join("plugins", "foo", "SKILL.md"))
+	tagHead(t, repo, "v1.0.0")
+	head, err := repo.Head()
+	if err != nil {
+		t.Fatalf("head: %v", err)
+	}
+
+	origin := "https://github.com/acme/marketplace"
+	useGitRewrite(t, originDir, origin)
+
+	repoRoot := t.TempDir()
+	setWorkingDir(t, repoRoot)
+	saveConfig(t, repoRoot, manifest.Config{
+		Skills: []manifest.Skill{
+			{Name: "foo", Origin: origin, Subdir: "plugins/foo", Version: "v1.0.0"},
+		},
+	})
+	lockPath := filepath.Join(repoRoot, "skills-lock.json")
+	key := manifest.LockKey{Origin: origin, Version: "v1.0.0"}
+	config, err := manifest.Load(filepath.Join(repoRoot, "skills.jsonc"))
+	if err != nil {
+		t.Fatalf("load manifest: %v", err)
+	}
+	if err := manifest.SaveLockWithSkills(lockPath, map[manifest.LockKey]string{key: head.Hash().String()}, config.Skills); err != nil {
+		t.Fatalf("save lock: %v", err)
+	}
+	manifestBefore, err := os.ReadFile(filepath.Join(repoRoot, "skills.jsonc"))
+	if err != nil {
+		t.Fatalf("read manifest: %v", err)
+	}
+
+	cmd, stdout, _ := newTestCommand()
+	cmd.SetArgs([]string{"fetch"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("fetch: %v", err)
+	}
+	if !strings.Contains(stdout.String(), "Fetched: "+origin) {
+		t.Fatalf("expected fetched origin in output, got %q", stdout.String())
+	}
+
+	storeDir := manifest.RepoPaths(repoRoot).StoreDir
+	if exists, _ := gitstore.CommitExists(gitstore.RepoPath(storeDir, origin), head.Hash().String()); !exists {
+		t.Fatalf("expected locked commit in store")
+	}
+	if _, err := os.Stat(filepath.Join(repoRoot, "skills")); !os.IsNotExist(err) {
+		t.Fatalf("expected fetch not to create skills/")
+	}
+	manifestAfter, err := os.ReadFile(filepath.Join(repoRoot, "skills.jsonc"))
+	if err != nil {
+		t.Fatalf("read manifest: %v", err)
+	}
+	if string(manifestAfter) != string(manifestBefore) {
+		t.Fatalf("expected fetch to leave the manifest alone")
+	}
+
+	cmd, stdout, _ = newTestCommand()
+	cmd.SetArgs([]string{"fetch"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("fetch again: %v", err)
+	}
+	if !strings.Contains(stdout.String(), "0 fetched, 1 already present") {
+		t.Fatalf("expected origin to be already present, got %q", stdout.String())
+	}
+
+	bogus := strings.Repeat("a", 40)
+	if err := manifest.SaveLockWithSkills(lockPath, map[manifest.LockKey]string{key: bogus}, config.Skills); err != nil {
+		t.Fatalf("save lock: %v", err)
+	}
+	cmd, stdout, _ = newTestCommand()
+	cmd.SetArgs([]string{"fetch"})
+	err = cmd.Execute()
+	if err == nil || !strings.Contains(err.Error(), "could not fetch 1 locked revision(s)") {
+		t.Fatalf("expected missing revision error, got %v", err)
+	}
+	if !strings.Contains(stdout.String(), "Missing: "+origin+" v1.0.0 "+bogus) {
+		t.Fatalf("expected missing revision listed, got %q", stdout.String())
+	}
+}
@@ -26,7 +26,7 @@ func runIndex(cmd *cobra.Command, _ []string) error {
 		return err
 	}
 
-	report, err := asm.Index(output)
+	report, err := asm.Index(cmd.Context(), output)
 	if err != nil {
 		return err
 	}
@@ -4,6 +4,14 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/jmmarotta/agent_skills_manager/internal/asm"
+	"github.com/jmmarotta/agent_skills_manager/internal/workpool"
+)
+
+const (
+	installProfileFlag = "profile"
+	installWithoutFlag = "without"
+	installAllFlag     = "all"
+	installOfflineFlag = "offline"
 )
 
 func newInstallCommand() *cobra.Command {
@@ -14,11 +22,61 @@ func newInstallCommand() *cobra.Command {
 		RunE:    runInstall,
 	}
 
+	cmd.Flags().Bool(globalFlag, false, "Install user-scope skills from the global manifest")
+	cmd.Flags().StringSlice(installProfileFlag, nil, "Link only ungrouped skills and skills in these groups (remembered)")
+	cmd.Flags().StringSlice(installWithoutFlag, nil, "Skip skills in these groups (remembered)")
+	cmd.Flags().Bool(installAllFlag, false, "Forget the remembered selection and link every skill")
+	cmd.Flags().Bool(installOfflineFlag, false, "Resolve only from the store and replace paths, never the network")
+	cmd.Flags().Int(jobsFlag, workpool.DefaultJobs, "Number of origins to fetch and resolve at once")
+
 	return cmd
 }
 
 func runInstall(cmd *cobra.Command, _ []string) error {
-	report, err := asm.Install()
+	global, err := cmd.Flags().GetBool(globalFlag)
+	if err != nil {
+		return err
+	}
+
+	profiles, err := cmd.Flags().GetStringSlice(installProfileFlag)
+	if err != nil {
+		return err
+	}
+
+	without, err := cmd.Flags().GetStringSlice(installWithoutFlag)
+	if err != nil {
+		return err
+	}
+
+	all, err := cmd.Flags().GetBool(installAllFlag)
+	if err != nil {
+		return err
+	}
+
+	offline, err := cmd.Flags().GetBool(installOfflineFlag)
+	if err != nil {
+		return err
+	}
+
+	jobs, err := readJobsFlag(cmd)
+	if err != nil {
+		return err
+	}
+
+	wait, err := readBoolFlag(cmd, waitFlag)
+	if err != nil {
+		return err
+	}
+
+	report, err := asm.Install(cmd.Context(), asm.InstallOptions{
+		Global:   global,
+		Profiles: profiles,
+		Without:  without,
+		All:      all,
+		Jobs:     jobs,
+		Offline:  offline,
+		Wait:     wait,
+	})
 	if err != nil {
 		return err
 	}
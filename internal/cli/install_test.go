@@ -0,0 +1,334 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+
+	"github.com/jmmarotta/agent_skills_manager/internal/gitstore"
+	"github.com/jmmarotta/agent_skills_manager/internal/manifest"
+)
+
+func TestInstallRefusesContentHashMismatch(t *testing.T) {
+	originDir := t.TempDir()
+	repo, err := git.PlainInit(originDir, false)
+	if err != nil {
+		t.Fatalf("init repo: %v", err)
+	}
+	touchSkill(t, filepath.Join(originDir, "skills", "alpha"))
+	commitPaths(t, repo, "init", time.Now().Add(-time.Minute), filepath.Join("skills", "alpha", "SKILL.md"))
+
+	origin := "https://github.com/acme/skills"
+	useGitRewrite(t, originDir, origin)
+
+	repoRoot := t.TempDir()
+	setWorkingDir(t, repoRoot)
+
+	cmd, _, _ := newTestCommand()
+	cmd.SetArgs([]string{"add", origin})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("add: %v", err)
+	}
+
+	lockPath := filepath.Join(repoRoot, "skills-lock.json")
+	entries, hashes, err := manifest.LoadLockWithHashes(lockPath)
+	if err != nil {
+		t.Fatalf("load lock: %v", err)
+	}
+	if len(hashes) != 1 {
+		t.Fatalf("expected 1 recorded hash, got %d", len(hashes))
+	}
+	for key := range hashes {
+		hashes[key] = "h1:47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU="
+	}
+	loaded, err := manifest.Load(filepath.Join(repoRoot, "skills.jsonc"))
+	if err != nil {
+		t.Fatalf("load manifest: %v", err)
+	}
+	if err := manifest.SaveLockWithHashes(lockPath, entries, hashes, loaded.Skills); err != nil {
+		t.Fatalf("save lock: %v", err)
+	}
+	if err := os.RemoveAll(filepath.Join(repoRoot, "skills")); err != nil {
+		t.Fatalf("remove skills: %v", err)
+	}
+
+	cmd, _, _ = newTestCommand()
+	cmd.SetArgs([]string{"install"})
+	err = cmd.Execute()
+	if err == nil {
+		t.Fatalf("expected install to fail")
+	}
+	if !strings.Contains(err.Error(), "content hash mismatch") {
+		t.Fatalf("expected hash mismatch error, got %v", err)
+	}
+	if _, err := os.Lstat(filepath.Join(repoRoot, "skills", "alpha")); !os.IsNotExist(err) {
+		t.Fatalf("expected tampered skill not to be linked")
+	}
+}
+
+func TestInstallRefusesSkillsMissingFromCheckout(t *testing.T) {
+	originDir := t.TempDir()
+	repo, err := git.PlainInit(originDir, false)
+	if err != nil {
+		t.Fatalf("init repo: %v", err)
+	}
+	touchSkill(t, filepath.Join(originDir, "skills", "alpha"))
+	commitPaths(t, repo, "init", time.Now().Add(-time.Minute), filepath.Join("skills", "alpha", "SKILL.md"))
+
+	origin := "https://github.com/acme/skills"
+	useGitRewrite(t, originDir, origin)
+
+	repoRoot := t.TempDir()
+	setWorkingDir(t, repoRoot)
+
+	cmd, _, _ := newTestCommand()
+	cmd.SetArgs([]string{"add", origin})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("add: %v", err)
+	}
+
+	linked, err := os.Readlink(filepath.Join(repoRoot, "skills", "alpha"))
+	if err != nil {
+		t.Fatalf("read link: %v", err)
+	}
+	if err := os.RemoveAll(linked); err != nil {
+		t.Fatalf("remove checkout skill: %v", err)
+	}
+	if err := os.RemoveAll(filepath.Join(repoRoot, "skills")); err != nil {
+		t.Fatalf("remove skills: %v", err)
+	}
+
+	cmd, _, _ = newTestCommand()
+	cmd.SetArgs([]string{"install"})
+	err = cmd.Execute()
+	if err == nil || !strings.Contains(err.Error(), "missing from checkout") {
+		t.Fatalf("expected install to refuse the missing skill, got %v", err)
+	}
+	if _, err := os.Lstat(filepath.Join(repoRoot, "skills", "alpha")); !os.IsNotExist(err) {
+		t.Fatalf("expected the missing skill not to be linked")
+	}
+}
+
+func TestInstallLinksEachSkillToItsOwnVersion(t *testing.T) {
+	originDir := t.TempDir()
+	repo, err := git.PlainInit(originDir, false)
+	if err != nil {
+		t.Fatalf("init repo: %v", err)
+	}
+	touchSkill(t, filepath.Join(originDir, "plugins", "foo"))
+	touchSkill(t, filepath.Join(originDir, "plugins", "bar"))
+	commitPaths(t, repo, "init", time.Now().Add(-2*time.Minute),
+		filepath.Join("plugins", "foo", "SKILL.md"),
+		filepath.Join("plugins", "bar", "SKILL.md"),
+	)
+	tagHead(t, repo, "v1.2.0")
+
+	for _, name := range []string{"foo", "bar"} {
+		path := filepath.Join(originDir, "plugins", name, "SKILL.md")
+		if err := os.WriteFile(path, []byte("# "+name+" v1.4.0"), 0o644); err != nil {
+			t.Fatalf("write skill: %v", err)
+		}
+	}
+	commitPaths(t, repo, "bump", time.Now().Add(-time.Minute),
+		filepath.Join("plugins", "foo", "SKILL.md"),
+		filepath.Join("plugins", "bar", "SKILL.md"),
+	)
+	tagHead(t, repo, "v1.4.0")
+
+	origin := "https://github.com/acme/marketplace"
+	useGitRewrite(t, originDir, origin)
+
+	repoRoot := t.TempDir()
+	setWorkingDir(t, repoRoot)
+	saveConfig(t, repoRoot, manifest.Config{
+		Skills: []manifest.Skill{
+			{Name: "foo", Origin: origin, Subdir: "plugins/foo", Version: "v1.2.0"},
+			{Name: "bar", Origin: origin, Subdir: "plugins/bar", Version: "v1.4.0"},
+		},
+	})
+
+	cmd, _, _ := newTestCommand()
+	cmd.SetArgs([]string{"install"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("install: %v", err)
+	}
+
+	assertSkillContent(t, filepath.Join(repoRoot, "skills", "foo", "SKILL.md"), "# skill")
+	assertSkillContent(t, filepath.Join(repoRoot, "skills", "bar", "SKILL.md"), "# bar v1.4.0")
+
+	lock, err := manifest.LoadLock(filepath.Join(repoRoot, "skills-lock.json"))
+	if err != nil {
+		t.Fatalf("load lock: %v", err)
+	}
+	if len(lock) != 2 {
+		t.Fatalf("expected 2 lock entries, got %d", len(lock))
+	}
+
+	cmd, _, _ = newTestCommand()
+	cmd.SetArgs([]string{"update", "foo"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+
+	loaded, err := manifest.Load(filepath.Join(repoRoot, "skills.jsonc"))
+	if err != nil {
+		t.Fatalf("load manifest: %v", err)
+	}
+	for _, skill := range loaded.Skills {
+		if skill.Version != "v1.4.0" {
+			t.Fatalf("expected %s at v1.4.0, got %s", skill.Name, skill.Version)
+		}
+	}
+	assertSkillContent(t, filepath.Join(repoRoot, "skills", "foo", "SKILL.md"), "# foo v1.4.0")
+
+	lock, err = manifest.LoadLock(filepath.Join(repoRoot, "skills-lock.json"))
+	if err != nil {
+		t.Fatalf("load lock: %v", err)
+	}
+	if len(lock) != 1 {
+		t.Fatalf("expected unused lock entry to be pruned, got %d entries", len(lock))
+	}
+}
+
+func TestInstallFromLockFetchesOnlyLockedSubdirs(t *testing.T) {
+	originDir := t.TempDir()
+	repo, err := git.PlainInit(originDir, false)
+	if err != nil {
+		t.Fatalf("init repo: %v", err)
+	}
+	touchSkill(t, filepath.Join(originDir, "docs"))
+	commitPaths(t, repo, "docs", time.Now().Add(-2*time.Minute), filepath.Join("docs", "SKILL.md"))
+	old, err := repo.Head()
+	if err != nil {
+		t.Fatalf("head: %v", err)
+	}
+	touchSkill(t, filepath.Join(originDir, "plugins", "foo"))
+	touchSkill(t, filepath.Join(originDir, "plugins", "bar"))
+	commitPaths(t, repo, "plugins", time.Now().Add(-time.Minute),
+		filepath.Join("plugins", "foo", "SKILL.md"),
+		filepath.Join("plugins", "bar", "SKILL.md"),
+	)
+	tagHead(t, repo, "v1.0.0")
+
+	origin := "https://github.com/acme/marketplace"
+	useGitRewrite(t, originDir, origin)
+
+	repoRoot := t.TempDir()
+	setWorkingDir(t, repoRoot)
+	saveConfig(t, repoRoot, manifest.Config{
+		Skills: []manifest.Skill{
+			{Name: "foo", Origin: origin, Subdir: "plugins/foo", Version: "v1.0.0"},
+		},
+	})
+
+	cmd, _, _ := newTestCommand()
+	cmd.SetArgs([]string{"install"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("install: %v", err)
+	}
+	if err := os.RemoveAll(filepath.Join(repoRoot, ".asm")); err != nil {
+		t.Fatalf("remove store: %v", err)
+	}
+
+	cmd, _, _ = newTestCommand()
+	cmd.SetArgs([]string{"install"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("install from lock: %v", err)
+	}
+	assertSkillContent(t, filepath.Join(repoRoot, "skills", "foo", "SKILL.md"), "# skill")
+
+	paths := manifest.RepoPaths(repoRoot)
+	if exists, _ := gitstore.CommitExists(gitstore.RepoPath(paths.StoreDir, origin), old.Hash().String()); exists {
+		t.Fatalf("expected history before the locked tag to be skipped")
+	}
+	lock, err := manifest.LoadLock(filepath.Join(repoRoot, "skills-lock.json"))
+	if err != nil {
+		t.Fatalf("load lock: %v", err)
+	}
+	checkout := gitstore.CheckoutPath(paths.CheckoutsDir, origin, lock[manifest.LockKey{Origin: origin, Version: "v1.0.0"}])
+	for _, skipped := range []string{"docs", filepath.Join("plugins", "bar")} {
+		if _, err := os.Stat(filepath.Join(checkout, skipped)); !os.IsNotExist(err) {
+			t.Fatalf("expected %s not to be exported", skipped)
+		}
+	}
+}
+
+func TestInstallReportsEveryFailedOrigin(t *testing.T) {
+	missing := t.TempDir()
+	configPath := filepath.Join(t.TempDir(), "gitconfig")
+	config := ""
+	for _, name := range []string{"one", "two"} {
+		config += fmt.Sprintf("[url \"%s\"]\n\tinsteadOf = https://github.com/acme/%s\n", filepath.Join(missing, name), name)
+	}
+	if err := os.WriteFile(configPath, []byte(config), 0o644); err != nil {
+		t.Fatalf("write gitconfig: %v", err)
+	}
+	t.Setenv("GIT_CONFIG_GLOBAL", configPath)
+
+	repoRoot := t.TempDir()
+	setWorkingDir(t, repoRoot)
+	saveConfig(t, repoRoot, manifest.Config{
+		Skills: []manifest.Skill{
+			{Name: "one", Origin: "https://github.com/acme/one", Version: "v1.0.0"},
+			{Name: "two", Origin: "https://github.com/acme/two", Version: "v1.0.0"},
+		},
+	})
+
+	cmd, _, _ := newTestCommand()
+	cmd.SetArgs([]string{"install", "--jobs", "2"})
+	err := cmd.Execute()
+	if err == nil {
+		t.Fatalf("expected install to fail")
+	}
+	for _, expected := range []string{"2 origins failed", "github.com/acme/one@v1.0.0", "github.com/acme/two@v1.0.0"} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Fatalf("expected error to contain %q, got %v", expected, err)
+		}
+	}
+
+	cmd, _, _ = newTestCommand()
+	cmd.SetArgs([]string{"install", "--jobs", "0"})
+	if err := cmd.Execute(); err == nil || !strings.Contains(err.Error(), "--jobs must be at least 1") {
+		t.Fatalf("expected --jobs validation error, got %v", err)
+	}
+}
+
+func tagHead(t *testing.T, repo *git.Repository, tag string) {
+	t.Helper()
+
+	head, err := repo.Head()
+	if err != nil {
+		t.Fatalf("head: %v", err)
+	}
+	if _, err := repo.CreateTag(tag, head.Hash(), nil); err != nil {
+		t.Fatalf("create tag: %v", err)
+	}
+}
+
+func assertSkillContent(t *testing.T, path string, expected string) {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read %s: %v", path, err)
+	}
+	if string(data) != expected {
+		t.Fatalf("expected %s to contain %q, got %q", path, expected, string(data))
+	}
+}
+
+func useGitRewrite(t *testing.T, localPath string, origin string) {
+	t.Helper()
+
+	configPath := filepath.Join(t.TempDir(), "gitconfig")
+	config := fmt.Sprintf("[url \"%s\"]\n\tinsteadOf = %s\n", localPath, origin)
+	if err := os.WriteFile(configPath, []byte(config), 0o644); err != nil {
+		t.Fatalf("write gitconfig: %v", err)
+	}
+	t.Setenv("GIT_CONFIG_GLOBAL", configPath)
+}
@@ -17,7 +17,7 @@ func newLsCommand() *cobra.Command {
 }
 
 func runLs(cmd *cobra.Command, _ []string) error {
-	report, err := asm.List()
+	report, err := asm.List(cmd.Context())
 	if err != nil {
 		return err
 	}
@@ -93,6 +93,67 @@ func TestLsOutputsSkillsAlphabetically(t *testing.T) {
 	}
 }
 
+func TestGlobalAddInstallsToUserScopeAndListsScopes(t *testing.T) {
+	repo := t.TempDir()
+	setWorkingDir(t, repo)
+	configHome := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", configHome)
+	globalSkills := filepath.Join(t.TempDir(), "user-skills")
+	t.Setenv("ASM_GLOBAL_SKILLS_DIR", globalSkills)
+
+	personal := filepath.Join(t.TempDir(), "personal")
+	touchSkill(t, personal)
+	shared := filepath.Join(t.TempDir(), "shared")
+	touchSkill(t, shared)
+
+	cmd, _, _ := newTestCommand()
+	cmd.SetArgs([]string{"add", "--global", personal})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("add --global: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(configHome, "asm", "skills.jsonc")); err != nil {
+		t.Fatalf("expected global manifest: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(repo, "skills.jsonc")); !os.IsNotExist(err) {
+		t.Fatalf("expected no repo manifest after global add")
+	}
+	assertSymlink(t, filepath.Join(globalSkills, "personal"), personal)
+
+	cmd, _, _ = newTestCommand()
+	cmd.SetArgs([]string{"add", shared})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("add: %v", err)
+	}
+
+	if err := os.RemoveAll(globalSkills); err != nil {
+		t.Fatalf("remove global skills: %v", err)
+	}
+	cmd, _, _ = newTestCommand()
+	cmd.SetArgs([]string{"install", "--global"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("install --global: %v", err)
+	}
+	assertSymlink(t, filepath.Join(globalSkills, "personal"), personal)
+
+	cmd, stdout, _ := newTestCommand()
+	cmd.SetArgs([]string{"ls"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("ls: %v", err)
+	}
+	output := stdout.String()
+	if !strings.Contains(output, "SCOPE") || !strings.Contains(output, "personal") || !strings.Contains(output, "shared") {
+		t.Fatalf("expected both scopes listed, got %q", output)
+	}
+	for _, line := range strings.Split(output, "\n") {
+		if strings.HasPrefix(line, "personal") && !strings.Contains(line, "global") {
+			t.Fatalf("expected personal to be global, got %q", line)
+		}
+		if strings.HasPrefix(line, "shared") && !strings.Contains(line, "repo") {
+			t.Fatalf("expected shared to be repo scoped, got %q", line)
+		}
+	}
+}
+
 func TestDebugFlagWritesToStderr(t *testing.T) {
 	repo := t.TempDir()
 	setWorkingDir(t, repo)
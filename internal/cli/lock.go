@@ -0,0 +1,50 @@
+package cli
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/jmmarotta/agent_skills_manager/internal/asm"
+)
+
+func newLockCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "lock",
+		Short: "Maintain skills-lock.json",
+	}
+
+	cmd.AddCommand(newLockFixCommand())
+
+	return cmd
+}
+
+func newLockFixCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "fix",
+		Short: "Resolve merge conflicts in skills-lock.json",
+		Args:  cobra.NoArgs,
+		RunE:  runLockFix,
+	}
+
+	cmd.Flags().Bool(globalFlag, false, "Fix the global lockfile")
+
+	return cmd
+}
+
+func runLockFix(cmd *cobra.Command, _ []string) error {
+	global, err := cmd.Flags().GetBool(globalFlag)
+	if err != nil {
+		return err
+	}
+
+	wait, err := readBoolFlag(cmd, waitFlag)
+	if err != nil {
+		return err
+	}
+
+	report, err := asm.FixLock(cmd.Context(), asm.FixLockOptions{Global: global, Wait: wait})
+	if err != nil {
+		return err
+	}
+	printLockFixReport(report, cmd.OutOrStdout(), cmd.ErrOrStderr())
+	return nil
+}
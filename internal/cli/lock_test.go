@@ -0,0 +1,96 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+
+	"github.com/jmmarotta/agent_skills_manager/internal/manifest"
+)
+
+func TestInstallFixesConflictedLock(t *testing.T) {
+	originDir := t.TempDir()
+	repo, err := git.PlainInit(originDir, false)
+	if err != nil {
+		t.Fatalf("init repo: %v", err)
+	}
+	touchSkill(t, filepath.Join(originDir, "skills", "alpha"))
+	commitPaths(t, repo, "init", time.Now().Add(-time.Minute), filepath.Join("skills", "alpha", "SKILL.md"))
+	tagHead(t, repo, "v1.0.0")
+	head, err := repo.Head()
+	if err != nil {
+		t.Fatalf("head: %v", err)
+	}
+
+	origin := "https://github.com/acme/skills"
+	useGitRewrite(t, originDir, origin)
+
+	repoRoot := t.TempDir()
+	setWorkingDir(t, repoRoot)
+	saveConfig(t, repoRoot, manifest.Config{
+		Skills: []manifest.Skill{{Name: "alpha", Origin: origin, Subdir: "skills/alpha", Version: "v1.0.0"}},
+	})
+	conflicted := `{
+  "schema": 2,
+  "entries": [
+<<<<<<< HEAD
+    {"origin": "` + origin + `", "version": "v1.0.0", "rev": "` + head.Hash().String() + `", "subdir": "skills/alpha", "name": "alpha"}
+=======
+    {"origin": "` + origin + `", "version": "v1.0.0", "rev": "1111111111111111111111111111111111111111", "subdir": "skills/alpha", "name": "alpha"},
+    {"origin": "https://github.com/acme/removed", "version": "v2.0.0", "rev": "2222222222222222222222222222222222222222"}
+>>>>>>> feature
+  ]
+}
+`
+	lockPath := filepath.Join(repoRoot, "skills-lock.json")
+	if err := os.WriteFile(lockPath, []byte(conflicted), 0o644); err != nil {
+		t.Fatalf("write lock: %v", err)
+	}
+
+	cmd, _, _ := newTestCommand()
+	cmd.SetArgs([]string{"show", "alpha"})
+	if err := cmd.Execute(); err == nil || !strings.Contains(err.Error(), "asm lock fix") {
+		t.Fatalf("expected conflict error pointing at asm lock fix, got %v", err)
+	}
+
+	// An invalid command line is refused before the lockfile is touched.
+	cmd, _, _ = newTestCommand()
+	cmd.SetArgs([]string{"install", "--all", "--profile", "ci"})
+	if err := cmd.Execute(); err == nil || !strings.Contains(err.Error(), "--all cannot be combined") {
+		t.Fatalf("expected conflicting selection flags to be refused, got %v", err)
+	}
+	if data, err := os.ReadFile(lockPath); err != nil || string(data) != conflicted {
+		t.Fatalf("expected the conflicted lock untouched, got %q, %v", data, err)
+	}
+
+	cmd, _, stderr := newTestCommand()
+	cmd.SetArgs([]string{"install"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("install: %v", err)
+	}
+	if !strings.Contains(stderr.String(), "resolved merge conflicts in skills-lock.json (kept 1, dropped 1)") {
+		t.Fatalf("expected conflict warning, got %q", stderr.String())
+	}
+	assertSkillContent(t, filepath.Join(repoRoot, "skills", "alpha", "SKILL.md"), "# skill")
+
+	lock, err := manifest.LoadLock(lockPath)
+	if err != nil {
+		t.Fatalf("load lock: %v", err)
+	}
+	if len(lock) != 1 || lock[manifest.LockKey{Origin: origin, Version: "v1.0.0"}] != head.Hash().String() {
+		t.Fatalf("expected the store revision to win, got %v", lock)
+	}
+
+	cmd, stdout, _ := newTestCommand()
+	cmd.SetArgs([]string{"lock", "fix"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("lock fix: %v", err)
+	}
+	if !strings.Contains(stdout.String(), "No lock conflicts found.") {
+		t.Fatalf("expected clean lock, got %q", stdout.String())
+	}
+}
@@ -0,0 +1,76 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+
+	"github.com/jmmarotta/agent_skills_manager/internal/manifest"
+)
+
+func TestInstallUsesStoreWithoutNetwork(t *testing.T) {
+	originDir := t.TempDir()
+	repo, err := git.PlainInit(originDir, false)
+	if err != nil {
+		t.Fatalf("init repo: %v", err)
+	}
+	touchSkill(t, filepath.Join(originDir, "plugins", "foo"))
+	commitPaths(t, repo, "init", time.Now().Add(-time.Minute), filepath.Join("plugins", "foo", "SKILL.md"))
+	tagHead(t, repo, "v1.0.0")
+
+	origin := "https://github.com/acme/marketplace"
+	useGitRewrite(t, originDir, origin)
+
+	repoRoot := t.TempDir()
+	setWorkingDir(t, repoRoot)
+	saveConfig(t, repoRoot, manifest.Config{
+		Skills: []manifest.Skill{
+			{Name: "foo", Origin: origin, Subdir: "plugins/foo", Version: "v1.0.0"},
+		},
+	})
+
+	cmd, _, _ := newTestCommand()
+	cmd.SetArgs([]string{"install"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("install: %v", err)
+	}
+
+	// With the origin gone, only the store can satisfy the lock.
+	if err := os.RemoveAll(originDir); err != nil {
+		t.Fatalf("remove origin: %v", err)
+	}
+	if err := os.RemoveAll(filepath.Join(repoRoot, "skills")); err != nil {
+		t.Fatalf("remove skills: %v", err)
+	}
+
+	cmd, _, _ = newTestCommand()
+	cmd.SetArgs([]string{"install"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("install with locked revs in store: %v", err)
+	}
+	assertSkillContent(t, filepath.Join(repoRoot, "skills", "foo", "SKILL.md"), "# skill")
+
+	cmd, _, _ = newTestCommand()
+	cmd.SetArgs([]string{"install", "--offline"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("install --offline: %v", err)
+	}
+
+	if err := os.RemoveAll(filepath.Join(repoRoot, ".asm")); err != nil {
+		t.Fatalf("remove store: %v", err)
+	}
+	t.Setenv("ASM_OFFLINE", "1")
+	cmd, _, _ = newTestCommand()
+	cmd.SetArgs([]string{"install"})
+	err = cmd.Execute()
+	if err == nil {
+		t.Fatalf("expected offline install to fail without a store")
+	}
+	if !strings.Contains(err.Error(), "for origin https://github.com/acme/marketplace not in store") {
+		t.Fatalf("expected missing rev error, got %v", err)
+	}
+}
@@ -0,0 +1,96 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jmmarotta/agent_skills_manager/internal/manifest"
+)
+
+func TestInstallProfileIsRemembered(t *testing.T) {
+	repoRoot := t.TempDir()
+	setWorkingDir(t, repoRoot)
+
+	common := filepath.Join(t.TempDir(), "common")
+	lint := filepath.Join(t.TempDir(), "lint")
+	docs := filepath.Join(t.TempDir(), "docs")
+	for _, dir := range []string{common, lint, docs} {
+		touchSkill(t, dir)
+	}
+	saveConfig(t, repoRoot, manifest.Config{
+		Skills: []manifest.Skill{
+			{Name: "common", Origin: common},
+			{Name: "lint", Origin: lint, Groups: []string{"ci"}},
+			{Name: "docs", Origin: docs, Groups: []string{"docs"}},
+		},
+	})
+
+	cmd, stdout, _ := newTestCommand()
+	cmd.SetArgs([]string{"install", "--profile", "ci"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("install --profile: %v", err)
+	}
+	if !strings.Contains(stdout.String(), "Selection: profile ci (skipped 1)") {
+		t.Fatalf("expected selection summary, got %q", stdout.String())
+	}
+	assertSymlink(t, filepath.Join(repoRoot, "skills", "common"), common)
+	assertSymlink(t, filepath.Join(repoRoot, "skills", "lint"), lint)
+	if _, err := os.Lstat(filepath.Join(repoRoot, "skills", "docs")); !os.IsNotExist(err) {
+		t.Fatalf("expected docs to be skipped")
+	}
+
+	cmd, _, _ = newTestCommand()
+	cmd.SetArgs([]string{"install"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("install: %v", err)
+	}
+	if _, err := os.Lstat(filepath.Join(repoRoot, "skills", "docs")); !os.IsNotExist(err) {
+		t.Fatalf("expected remembered profile to keep docs skipped")
+	}
+
+	cmd, _, _ = newTestCommand()
+	cmd.SetArgs([]string{"install", "--all"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("install --all: %v", err)
+	}
+	assertSymlink(t, filepath.Join(repoRoot, "skills", "docs"), docs)
+	if _, err := os.Stat(filepath.Join(repoRoot, ".asm", "selection.json")); !os.IsNotExist(err) {
+		t.Fatalf("expected --all to clear the remembered selection")
+	}
+}
+
+func TestInstallFailureKeepsRememberedSelection(t *testing.T) {
+	repoRoot := t.TempDir()
+	setWorkingDir(t, repoRoot)
+
+	lint := filepath.Join(t.TempDir(), "lint")
+	touchSkill(t, lint)
+	saveConfig(t, repoRoot, manifest.Config{
+		Skills: []manifest.Skill{
+			{Name: "lint", Origin: lint, Groups: []string{"ci"}},
+			{Name: "remote", Origin: "https://github.com/acme/skills", Version: "v1.0.0", Groups: []string{"docs"}},
+		},
+	})
+
+	cmd, _, _ := newTestCommand()
+	cmd.SetArgs([]string{"install", "--profile", "ci"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("install --profile ci: %v", err)
+	}
+
+	cmd, _, _ = newTestCommand()
+	cmd.SetArgs([]string{"install", "--profile", "docs", "--offline"})
+	if err := cmd.Execute(); err == nil {
+		t.Fatalf("expected the offline install of an unfetched origin to fail")
+	}
+
+	selection, err := manifest.LoadSelection(filepath.Join(repoRoot, ".asm", "selection.json"))
+	if err != nil {
+		t.Fatalf("load selection: %v", err)
+	}
+	if len(selection.Profiles) != 1 || selection.Profiles[0] != "ci" {
+		t.Fatalf("expected the failed install to keep profile ci, got %+v", selection)
+	}
+}
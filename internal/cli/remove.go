@@ -15,11 +15,23 @@ func newRemoveCommand() *cobra.Command {
 		RunE:    runRemove,
 	}
 
+	cmd.Flags().Bool(globalFlag, false, "Remove from the global manifest")
+
 	return cmd
 }
 
 func runRemove(cmd *cobra.Command, args []string) error {
-	report, err := asm.Remove(args)
+	global, err := cmd.Flags().GetBool(globalFlag)
+	if err != nil {
+		return err
+	}
+
+	wait, err := readBoolFlag(cmd, waitFlag)
+	if err != nil {
+		return err
+	}
+
+	report, err := asm.Remove(cmd.Context(), asm.RemoveOptions{Names: args, Global: global, Wait: wait})
 	if err != nil {
 		return err
 	}
@@ -8,6 +8,7 @@ import (
 	"text/tabwriter"
 
 	"github.com/jmmarotta/agent_skills_manager/internal/asm"
+	"github.com/jmmarotta/agent_skills_manager/internal/manifest"
 )
 
 const findResultsLimit = 6
@@ -27,6 +28,16 @@ func printInstallReport(report asm.InstallReport, out io.Writer, errOut io.Write
 	}
 
 	fmt.Fprintf(out, "Installed: %d, Pruned: %d, Warnings: %d\n", report.Linked, report.Pruned, len(report.Warnings))
+	if !report.Selection.IsEmpty() {
+		parts := []string{}
+		if len(report.Selection.Profiles) > 0 {
+			parts = append(parts, "profile "+strings.Join(report.Selection.Profiles, ","))
+		}
+		if len(report.Selection.Without) > 0 {
+			parts = append(parts, "without "+strings.Join(report.Selection.Without, ","))
+		}
+		fmt.Fprintf(out, "Selection: %s (skipped %d)\n", strings.Join(parts, ", "), len(report.Skipped))
+	}
 }
 
 func printListReport(report asm.ListReport, out io.Writer) error {
@@ -36,12 +47,13 @@ func printListReport(report asm.ListReport, out io.Writer) error {
 	}
 
 	writer := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
-	fmt.Fprintln(writer, "NAME\tORIGIN\tVERSION\tSUBDIR")
+	fmt.Fprintln(writer, "NAME\tSCOPE\tORIGIN\tVERSION\tSUBDIR")
 	for _, skill := range report.Skills {
 		fmt.Fprintf(
 			writer,
-			"%s\t%s\t%s\t%s\n",
+			"%s\t%s\t%s\t%s\t%s\n",
 			skill.Name,
+			skill.Scope,
 			skill.Origin,
 			skill.Version,
 			skill.Subdir,
@@ -137,3 +149,93 @@ func printRemoveReport(report asm.RemoveReport, out io.Writer, errOut io.Writer)
 		fmt.Fprintf(out, "Pruned store: %s\n", origin)
 	}
 }
+
+func printValidateReport(report asm.ValidateReport, out io.Writer) error {
+	failures := 0
+	for _, diagnostic := range report.Diagnostics {
+		fmt.Fprintln(out, diagnostic.String())
+		if diagnostic.Severity == manifest.SeverityError {
+			failures++
+		}
+	}
+	if manifest.HasErrors(report.Diagnostics) {
+		return fmt.Errorf("%d error(s) in %s", failures, report.ManifestPath)
+	}
+	if len(report.Diagnostics) == 0 {
+		fmt.Fprintln(out, "No problems found.")
+	}
+	return nil
+}
+
+func printLockFixReport(report asm.LockFixReport, out io.Writer, errOut io.Writer) {
+	for _, warning := range report.Warnings {
+		fmt.Fprintf(errOut, "warning: %s\n", warning)
+	}
+	if report.Clean {
+		fmt.Fprintln(out, "No lock conflicts found.")
+		return
+	}
+	for _, resolution := range report.Resolved {
+		fmt.Fprintf(out, "Resolved: %s %s -> %s\n", resolution.Origin, resolution.Version, resolution.Rev)
+	}
+	fmt.Fprintf(out, "Fixed lockfile: kept %d, dropped %d\n", report.Kept, report.Dropped)
+}
+
+func printFetchReport(report asm.FetchReport, out io.Writer) error {
+	if report.NoEntries {
+		fmt.Fprintln(out, "No locked revisions to fetch.")
+		return nil
+	}
+	for _, origin := range report.Fetched {
+		fmt.Fprintf(out, "Fetched: %s\n", origin)
+	}
+	for _, missing := range report.Missing {
+		if missing.Sha256 != "" {
+			fmt.Fprintf(out, "Missing: %s sha256:%s (%s)\n", missing.Origin, missing.Sha256, missing.Reason)
+			continue
+		}
+		fmt.Fprintf(out, "Missing: %s %s %s (%s)\n", missing.Origin, missing.Version, missing.Rev, missing.Reason)
+	}
+	if len(report.Missing) > 0 {
+		return fmt.Errorf("could not fetch %d locked revision(s)", len(report.Missing))
+	}
+	fmt.Fprintf(out, "Store ready: %d fetched, %d already present\n", len(report.Fetched), len(report.Present))
+	return nil
+}
+
+func printAuthStatusReport(report asm.AuthStatusReport, out io.Writer) error {
+	if report.NoOrigins {
+		fmt.Fprintln(out, "No git origins configured.")
+		return nil
+	}
+	failures := 0
+	for index, status := range report.Origins {
+		if index > 0 {
+			fmt.Fprintln(out)
+		}
+		fmt.Fprintln(out, status.Origin)
+		if status.Err != nil {
+			failures++
+			fmt.Fprintf(out, "  error:       %v\n", status.Err)
+			continue
+		}
+		url := status.URL
+		if status.Rewritten {
+			url += " (rewritten by insteadOf)"
+		}
+		fmt.Fprintf(out, "  url:         %s\n", url)
+		switch {
+		case status.Source == "":
+			fmt.Fprintln(out, "  credentials: none")
+		case status.Credential == "none":
+			fmt.Fprintf(out, "  credentials: %s\n", status.Source)
+		default:
+			fmt.Fprintf(out, "  credentials: %s (%s)\n", status.Source, status.Credential)
+		}
+		fmt.Fprintf(out, "  anonymous:   %s\n", status.Anonymous)
+	}
+	if failures > 0 {
+		return fmt.Errorf("could not resolve credentials for %d origin(s)", failures)
+	}
+	return nil
+}
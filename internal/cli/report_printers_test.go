@@ -176,6 +176,7 @@ func TestPrintListReport(t *testing.T) {
 	report := asm.ListReport{
 		Skills: []asm.SkillSummary{{
 			Name:    "foo",
+			Scope:   "global",
 			Origin:  "/tmp/skill",
 			Version: "",
 			Subdir:  "",
@@ -188,7 +189,7 @@ func TestPrintListReport(t *testing.T) {
 	if !strings.Contains(out.String(), "NAME") || !strings.Contains(out.String(), "ORIGIN") {
 		t.Fatalf("missing header: %q", out.String())
 	}
-	if !strings.Contains(out.String(), "foo") {
+	if !strings.Contains(out.String(), "foo") || !strings.Contains(out.String(), "global") {
 		t.Fatalf("missing skill row: %q", out.String())
 	}
 }
@@ -1,17 +1,31 @@
 package cli
 
 import (
+	"context"
 	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/spf13/cobra"
 
 	"github.com/jmmarotta/agent_skills_manager/internal/debug"
 )
 
-const debugFlag = "debug"
+const (
+	debugFlag  = "debug"
+	globalFlag = "global"
+	jobsFlag   = "jobs"
+	waitFlag   = "wait"
+)
 
+// Execute runs asm until the command finishes or the user interrupts it.
+// Interrupting cancels the command's context, which stops network operations
+// and discards any clone in progress, and ends a --wait for the state lock.
 func Execute() error {
-	return newRootCommand().Execute()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	return newRootCommand().ExecuteContext(ctx)
 }
 
 func newRootCommand() *cobra.Command {
@@ -34,6 +48,7 @@ func newRootCommand() *cobra.Command {
 	}
 
 	cmd.PersistentFlags().Bool(debugFlag, false, "Enable debug logging")
+	cmd.PersistentFlags().Bool(waitFlag, false, "Wait for another asm process to release the state lock")
 
 	cmd.AddCommand(newLsCommand())
 	cmd.AddCommand(newFindCommand())
@@ -43,7 +58,12 @@ func newRootCommand() *cobra.Command {
 	cmd.AddCommand(newUpdateCommand())
 	cmd.AddCommand(newRemoveCommand())
 	cmd.AddCommand(newInstallCommand())
+	cmd.AddCommand(newFetchCommand())
 	cmd.AddCommand(newInitCommand())
+	cmd.AddCommand(newValidateCommand())
+	cmd.AddCommand(newSchemaCommand())
+	cmd.AddCommand(newLockCommand())
+	cmd.AddCommand(newAuthCommand())
 
 	return cmd
 }
@@ -60,3 +80,15 @@ func readBoolFlag(cmd *cobra.Command, name string) (bool, error) {
 	}
 	return false, fmt.Errorf("flag %q not found", name)
 }
+
+// readJobsFlag returns the --jobs value, rejecting values below one.
+func readJobsFlag(cmd *cobra.Command) (int, error) {
+	jobs, err := cmd.Flags().GetInt(jobsFlag)
+	if err != nil {
+		return 0, err
+	}
+	if jobs < 1 {
+		return 0, fmt.Errorf("--%s must be at least 1", jobsFlag)
+	}
+	return jobs, nil
+}
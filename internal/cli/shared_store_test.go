@@ -0,0 +1,69 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+
+	"github.com/jmmarotta/agent_skills_manager/internal/gitstore"
+)
+
+func TestSharedStoreServesSeveralRepos(t *testing.T) {
+	originDir := t.TempDir()
+	repo, err := git.PlainInit(originDir, false)
+	if err != nil {
+		t.Fatalf("init repo: %v", err)
+	}
+	touchSkill(t, filepath.Join(originDir, "skills", "alpha"))
+	commitPaths(t, repo, "init", time.Now().Add(-time.Minute), filepath.Join("skills", "alpha", "SKILL.md"))
+
+	origin := "https://github.com/acme/skills"
+	useGitRewrite(t, originDir, origin)
+
+	cacheDir := t.TempDir()
+	first := t.TempDir()
+	second := t.TempDir()
+	for _, root := range []string{first, second} {
+		setWorkingDir(t, root)
+		t.Setenv("XDG_CACHE_HOME", cacheDir)
+		t.Setenv("ASM_SHARED_STORE", "1")
+
+		cmd, _, _ := newTestCommand()
+		cmd.SetArgs([]string{"add", origin})
+		if err := cmd.Execute(); err != nil {
+			t.Fatalf("add in %s: %v", root, err)
+		}
+	}
+
+	storeDir := filepath.Join(cacheDir, "asm", "store")
+	repoPath := gitstore.RepoPath(storeDir, origin)
+	if _, err := os.Stat(filepath.Join(repoPath, "HEAD")); err != nil {
+		t.Fatalf("expected a bare clone in the shared store: %v", err)
+	}
+	for _, root := range []string{first, second} {
+		if _, err := os.Stat(gitstore.RepoPath(filepath.Join(root, ".asm", "store"), origin)); !os.IsNotExist(err) {
+			t.Fatalf("expected no per-repo clone in %s", root)
+		}
+		if _, err := os.Stat(filepath.Join(root, "skills", "alpha", "SKILL.md")); err != nil {
+			t.Fatalf("expected alpha linked in %s: %v", root, err)
+		}
+	}
+
+	cmd, _, _ := newTestCommand()
+	cmd.SetArgs([]string{"remove", "alpha"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+	if _, err := os.Stat(repoPath); err != nil {
+		t.Fatalf("expected remove to keep the shared clone: %v", err)
+	}
+	if _, err := os.Stat(gitstore.CheckoutsPath(filepath.Join(second, ".asm", "store", "checkouts"), origin)); !os.IsNotExist(err) {
+		t.Fatalf("expected remove to prune the repo's checkouts")
+	}
+	if _, err := os.Stat(filepath.Join(first, "skills", "alpha", "SKILL.md")); err != nil {
+		t.Fatalf("expected alpha to stay linked in the first repo: %v", err)
+	}
+}
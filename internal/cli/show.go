@@ -17,7 +17,7 @@ func newShowCommand() *cobra.Command {
 }
 
 func runShow(cmd *cobra.Command, args []string) error {
-	report, err := asm.Show(args[0])
+	report, err := asm.Show(cmd.Context(), args[0])
 	if err != nil {
 		return err
 	}
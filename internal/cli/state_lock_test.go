@@ -0,0 +1,137 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jmmarotta/agent_skills_manager/internal/filelock"
+	"github.com/jmmarotta/agent_skills_manager/internal/manifest"
+)
+
+func setupLocalSkillRepo(t *testing.T) string {
+	t.Helper()
+	sourceDir := t.TempDir()
+	touchSkill(t, sourceDir)
+
+	repoRoot := t.TempDir()
+	setWorkingDir(t, repoRoot)
+	saveConfig(t, repoRoot, manifest.Config{
+		Skills: []manifest.Skill{{Name: "foo", Origin: sourceDir}},
+	})
+	return repoRoot
+}
+
+func holdStateLock(t *testing.T, repoRoot string, pid string) *filelock.Lock {
+	t.Helper()
+	lock, err := filelock.Acquire(manifest.StateLockPath(manifest.RepoPaths(repoRoot)))
+	if err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+	if err := lock.Write([]byte(pid + "\n")); err != nil {
+		t.Fatalf("write pid: %v", err)
+	}
+	return lock
+}
+
+func TestInstallTimesOutNamingLockHolder(t *testing.T) {
+	repoRoot := setupLocalSkillRepo(t)
+	t.Setenv("ASM_LOCK_TIMEOUT", "0s")
+	lock := holdStateLock(t, repoRoot, "4242")
+	defer lock.Release()
+
+	cmd, _, _ := newTestCommand()
+	cmd.SetArgs([]string{"install"})
+	err := cmd.Execute()
+	if err == nil {
+		t.Fatalf("expected install to fail while the state is locked")
+	}
+	if !strings.Contains(err.Error(), "pid 4242") || !strings.Contains(err.Error(), "--wait") {
+		t.Fatalf("expected error naming the holder and --wait, got %v", err)
+	}
+	if _, err := os.Lstat(filepath.Join(repoRoot, "skills", "foo")); !os.IsNotExist(err) {
+		t.Fatalf("expected nothing linked while locked")
+	}
+}
+
+func TestInstallWaitsForLockHolder(t *testing.T) {
+	repoRoot := setupLocalSkillRepo(t)
+	t.Setenv("ASM_LOCK_TIMEOUT", "0s")
+	lock := holdStateLock(t, repoRoot, "4242")
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		lock.Release()
+	}()
+
+	cmd, _, _ := newTestCommand()
+	cmd.SetArgs([]string{"install", "--wait"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("install --wait: %v", err)
+	}
+	assertSkillContent(t, filepath.Join(repoRoot, "skills", "foo", "SKILL.md"), "# skill")
+}
+
+func TestInstallWaitStopsWhenCanceled(t *testing.T) {
+	repoRoot := setupLocalSkillRepo(t)
+	lock := holdStateLock(t, repoRoot, "4242")
+	defer lock.Release()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		cancel()
+	}()
+	cmd, _, _ := newTestCommand()
+	cmd.SetArgs([]string{"install", "--wait"})
+	if err := cmd.ExecuteContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the canceled wait to stop install, got %v", err)
+	}
+}
+
+func TestInstallTakesOverStaleLock(t *testing.T) {
+	repoRoot := setupLocalSkillRepo(t)
+	lockPath := manifest.StateLockPath(manifest.RepoPaths(repoRoot))
+	// A holder that died leaves its PID behind but no lock.
+	if err := os.MkdirAll(filepath.Dir(lockPath), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(lockPath, []byte("4242\n"), 0o644); err != nil {
+		t.Fatalf("write stale lock: %v", err)
+	}
+
+	cmd, _, _ := newTestCommand()
+	cmd.SetArgs([]string{"install"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("install: %v", err)
+	}
+	data, err := os.ReadFile(lockPath)
+	if err != nil {
+		t.Fatalf("read lock: %v", err)
+	}
+	if len(data) != 0 {
+		t.Fatalf("expected released lock to be cleared, got %q", data)
+	}
+}
+
+func TestFirstAddTakesStateLock(t *testing.T) {
+	sourceDir := t.TempDir()
+	touchSkill(t, sourceDir)
+	repoRoot := t.TempDir()
+	setWorkingDir(t, repoRoot)
+	t.Setenv("ASM_LOCK_TIMEOUT", "0s")
+	lock := holdStateLock(t, repoRoot, "4242")
+	defer lock.Release()
+
+	cmd, _, _ := newTestCommand()
+	cmd.SetArgs([]string{"add", sourceDir})
+	if err := cmd.Execute(); err == nil || !strings.Contains(err.Error(), "pid 4242") {
+		t.Fatalf("expected add to wait on the state lock before a manifest exists, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(repoRoot, "skills.jsonc")); !os.IsNotExist(err) {
+		t.Fatalf("expected no manifest while locked, got %v", err)
+	}
+}
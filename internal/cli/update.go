@@ -4,9 +4,13 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/jmmarotta/agent_skills_manager/internal/asm"
+	"github.com/jmmarotta/agent_skills_manager/internal/workpool"
 )
 
-const updatePathFlag = "path"
+const (
+	updatePathFlag       = "path"
+	updatePrereleaseFlag = "prerelease"
+)
 
 func newUpdateCommand() *cobra.Command {
 	cmd := &cobra.Command{
@@ -18,6 +22,9 @@ func newUpdateCommand() *cobra.Command {
 	}
 
 	cmd.Flags().String(updatePathFlag, "", "Subdirectory path used with an origin selector")
+	cmd.Flags().Bool(updatePrereleaseFlag, false, "Allow prerelease tags when resolving constraints")
+	cmd.Flags().Bool(globalFlag, false, "Update the global manifest")
+	cmd.Flags().Int(jobsFlag, workpool.DefaultJobs, "Number of origins to fetch and resolve at once")
 
 	return cmd
 }
@@ -33,7 +40,34 @@ func runUpdate(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	report, err := asm.Update(selector, pathFlag)
+	prerelease, err := cmd.Flags().GetBool(updatePrereleaseFlag)
+	if err != nil {
+		return err
+	}
+
+	global, err := cmd.Flags().GetBool(globalFlag)
+	if err != nil {
+		return err
+	}
+
+	jobs, err := readJobsFlag(cmd)
+	if err != nil {
+		return err
+	}
+
+	wait, err := readBoolFlag(cmd, waitFlag)
+	if err != nil {
+		return err
+	}
+
+	report, err := asm.Update(cmd.Context(), asm.UpdateOptions{
+		Selector:   selector,
+		Path:       pathFlag,
+		Prerelease: prerelease,
+		Global:     global,
+		Jobs:       jobs,
+		Wait:       wait,
+	})
 	if err != nil {
 		return err
 	}
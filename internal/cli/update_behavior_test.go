@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
 
 	"github.com/jmmarotta/agent_skills_manager/internal/gitstore"
 	"github.com/jmmarotta/agent_skills_manager/internal/manifest"
@@ -159,6 +160,203 @@ func TestUpdateOriginPathSelectorAdvancesPinnedSemver(t *testing.T) {
 	}
 }
 
+func TestUpdateResolvesConstraintToNewestMatchingTag(t *testing.T) {
+	originPath := t.TempDir()
+	repo, err := git.PlainInit(originPath, false)
+	if err != nil {
+		t.Fatalf("init repo: %v", err)
+	}
+	when := time.Now().Add(-time.Hour)
+	for _, tag := range []string{"v1.2.0", "v1.4.0", "v1.5.0-rc.1", "v2.0.0"} {
+		dir := filepath.Join(originPath, "skills", "foo")
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "SKILL.md"), []byte("# foo "+tag), 0o644); err != nil {
+			t.Fatalf("write skill: %v", err)
+		}
+		when = when.Add(time.Minute)
+		commitPaths(t, repo, tag, when, filepath.Join("skills", "foo", "SKILL.md"))
+		tagHead(t, repo, tag)
+	}
+
+	origin := "https://github.com/acme/skills"
+	useGitRewrite(t, originPath, origin)
+
+	repoRoot := t.TempDir()
+	setWorkingDir(t, repoRoot)
+	saveConfig(t, repoRoot, manifest.Config{
+		Skills: []manifest.Skill{{
+			Name:       "foo",
+			Origin:     origin,
+			Subdir:     "skills/foo",
+			Version:    "v1.2.0",
+			Constraint: "^1.2",
+		}},
+	})
+
+	cmd, _, _ := newTestCommand()
+	cmd.SetArgs([]string{"update"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	assertSkillVersion(t, repoRoot, "v1.4.0")
+	assertSkillContent(t, filepath.Join(repoRoot, "skills", "foo", "SKILL.md"), "# foo v1.4.0")
+
+	cmd, _, _ = newTestCommand()
+	cmd.SetArgs([]string{"update", "--prerelease"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("update --prerelease: %v", err)
+	}
+	assertSkillVersion(t, repoRoot, "v1.5.0-rc.1")
+
+	lock, err := manifest.LoadLock(filepath.Join(repoRoot, "skills-lock.json"))
+	if err != nil {
+		t.Fatalf("load lock: %v", err)
+	}
+	if _, ok := lock[manifest.LockKey{Origin: origin, Version: "v1.5.0-rc.1"}]; !ok || len(lock) != 1 {
+		t.Fatalf("expected lock to hold only v1.5.0-rc.1, got %v", lock)
+	}
+}
+
+func TestUpdateFollowsTrackedBranch(t *testing.T) {
+	originPath := t.TempDir()
+	repo, err := git.PlainInit(originPath, false)
+	if err != nil {
+		t.Fatalf("init repo: %v", err)
+	}
+	when := time.Now().Add(-time.Hour)
+	writeTrackedSkill(t, originPath, "main")
+	commitPaths(t, repo, "init", when, filepath.Join("skills", "foo", "SKILL.md"))
+
+	worktree, err := repo.Worktree()
+	if err != nil {
+		t.Fatalf("worktree: %v", err)
+	}
+	branch := plumbing.NewBranchReferenceName("release/2.x")
+	if err := worktree.Checkout(&git.CheckoutOptions{Branch: branch, Create: true}); err != nil {
+		t.Fatalf("checkout release branch: %v", err)
+	}
+	writeTrackedSkill(t, originPath, "release one")
+	commitPaths(t, repo, "release one", when.Add(time.Minute), filepath.Join("skills", "foo", "SKILL.md"))
+
+	origin := "https://github.com/acme/skills"
+	useGitRewrite(t, originPath, origin)
+
+	repoRoot := t.TempDir()
+	setWorkingDir(t, repoRoot)
+
+	cmd, _, _ := newTestCommand()
+	cmd.SetArgs([]string{"add", origin, "--track", "release/2.x"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("add: %v", err)
+	}
+	assertSkillContent(t, filepath.Join(repoRoot, "skills", "foo", "SKILL.md"), "release one")
+
+	writeTrackedSkill(t, originPath, "release two")
+	commitPaths(t, repo, "release two", when.Add(2*time.Minute), filepath.Join("skills", "foo", "SKILL.md"))
+	releaseTip, err := repo.Head()
+	if err != nil {
+		t.Fatalf("head: %v", err)
+	}
+	if err := worktree.Checkout(&git.CheckoutOptions{Branch: plumbing.NewBranchReferenceName("master")}); err != nil {
+		t.Fatalf("checkout master: %v", err)
+	}
+	writeTrackedSkill(t, originPath, "main two")
+	commitPaths(t, repo, "main two", when.Add(3*time.Minute), filepath.Join("skills", "foo", "SKILL.md"))
+
+	cmd, _, _ = newTestCommand()
+	cmd.SetArgs([]string{"update"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	assertSkillContent(t, filepath.Join(repoRoot, "skills", "foo", "SKILL.md"), "release two")
+
+	loaded, err := manifest.Load(filepath.Join(repoRoot, "skills.jsonc"))
+	if err != nil {
+		t.Fatalf("load manifest: %v", err)
+	}
+	if loaded.Skills[0].Track != "release/2.x" {
+		t.Fatalf("expected track release/2.x, got %q", loaded.Skills[0].Track)
+	}
+	lock, err := manifest.LoadLock(filepath.Join(repoRoot, "skills-lock.json"))
+	if err != nil {
+		t.Fatalf("load lock: %v", err)
+	}
+	if rev := lock[loaded.Skills[0].LockKey()]; rev != releaseTip.Hash().String() {
+		t.Fatalf("expected lock rev %s, got %s", releaseTip.Hash(), rev)
+	}
+}
+
+func TestAddExplicitVersionStopsTracking(t *testing.T) {
+	originPath := t.TempDir()
+	repo, err := git.PlainInit(originPath, false)
+	if err != nil {
+		t.Fatalf("init repo: %v", err)
+	}
+	when := time.Now().Add(-time.Hour)
+	writeTrackedSkill(t, originPath, "one")
+	commitPaths(t, repo, "one", when, filepath.Join("skills", "foo", "SKILL.md"))
+	tagHead(t, repo, "v1.2.0")
+	writeTrackedSkill(t, originPath, "two")
+	commitPaths(t, repo, "two", when.Add(time.Minute), filepath.Join("skills", "foo", "SKILL.md"))
+
+	origin := "https://github.com/acme/skills"
+	useGitRewrite(t, originPath, origin)
+
+	repoRoot := t.TempDir()
+	setWorkingDir(t, repoRoot)
+
+	cmd, _, _ := newTestCommand()
+	cmd.SetArgs([]string{"add", origin, "--track", "master"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("add --track: %v", err)
+	}
+	assertSkillContent(t, filepath.Join(repoRoot, "skills", "foo", "SKILL.md"), "two")
+
+	cmd, _, _ = newTestCommand()
+	cmd.SetArgs([]string{"add", origin + "@v1.2.0"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("add @v1.2.0: %v", err)
+	}
+	assertSkillContent(t, filepath.Join(repoRoot, "skills", "foo", "SKILL.md"), "one")
+
+	loaded, err := manifest.Load(filepath.Join(repoRoot, "skills.jsonc"))
+	if err != nil {
+		t.Fatalf("load manifest: %v", err)
+	}
+	if skill := loaded.Skills[0]; skill.Version != "v1.2.0" || skill.Track != "" {
+		t.Fatalf("expected the skill pinned to v1.2.0 without a track, got %+v", skill)
+	}
+}
+
+func writeTrackedSkill(t *testing.T, root string, content string) {
+	t.Helper()
+
+	dir := filepath.Join(root, "skills", "foo")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "SKILL.md"), []byte(content), 0o644); err != nil {
+		t.Fatalf("write skill: %v", err)
+	}
+}
+
+func assertSkillVersion(t *testing.T, repoRoot string, expected string) {
+	t.Helper()
+
+	loaded, err := manifest.Load(filepath.Join(repoRoot, "skills.jsonc"))
+	if err != nil {
+		t.Fatalf("load manifest: %v", err)
+	}
+	if loaded.Skills[0].Version != expected {
+		t.Fatalf("expected version %q, got %q", expected, loaded.Skills[0].Version)
+	}
+	if loaded.Skills[0].Constraint == "" {
+		t.Fatalf("expected constraint to be kept")
+	}
+}
+
 func setupUpdateRepo(t *testing.T, subdir string, tag string) (string, gitstore.Resolved, gitstore.Resolved) {
 	t.Helper()
 
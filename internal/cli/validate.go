@@ -0,0 +1,55 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jmmarotta/agent_skills_manager/internal/asm"
+)
+
+func newValidateCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "validate",
+		Short: "Report every problem in skills.jsonc and skills-lock.json",
+		Args:  cobra.NoArgs,
+		RunE:  runValidate,
+	}
+
+	cmd.Flags().Bool(globalFlag, false, "Validate the global manifest")
+
+	return cmd
+}
+
+func runValidate(cmd *cobra.Command, _ []string) error {
+	global, err := cmd.Flags().GetBool(globalFlag)
+	if err != nil {
+		return err
+	}
+
+	report, err := asm.Validate(global)
+	if err != nil {
+		return err
+	}
+	return printValidateReport(report, cmd.OutOrStdout())
+}
+
+func newSchemaCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "schema",
+		Short: "Print the JSON Schema for skills.jsonc",
+		Args:  cobra.NoArgs,
+		RunE:  runSchema,
+	}
+	return cmd
+}
+
+func runSchema(cmd *cobra.Command, _ []string) error {
+	payload, err := json.MarshalIndent(asm.Schema(), "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(cmd.OutOrStdout(), string(payload))
+	return nil
+}
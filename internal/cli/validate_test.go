@@ -0,0 +1,67 @@
+package cli
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidateReportsProblemsAndFails(t *testing.T) {
+	repoRoot := t.TempDir()
+	setWorkingDir(t, repoRoot)
+	skillDir := filepath.Join(repoRoot, "local")
+	touchSkill(t, skillDir)
+
+	content := `{
+  "skills": [
+    {"name": "local", "origin": "./local"},
+    {"name": "local", "origin": "./local", "subdir": "nested"}
+  ]
+}
+`
+	if err := os.WriteFile(filepath.Join(repoRoot, "skills.jsonc"), []byte(content), 0o644); err != nil {
+		t.Fatalf("write manifest: %v", err)
+	}
+
+	cmd, stdout, _ := newTestCommand()
+	cmd.SetArgs([]string{"validate"})
+	err := cmd.Execute()
+	if err == nil {
+		t.Fatalf("expected validate to fail")
+	}
+	if !strings.Contains(stdout.String(), "skills.jsonc:4:6: error: skills[1]: duplicate name") {
+		t.Fatalf("expected positioned duplicate name error, got %q", stdout.String())
+	}
+
+	content = strings.Replace(content, `{"name": "local", "origin": "./local", "subdir": "nested"}`, `{"name": "other", "origin": "./local", "subdir": "nested"}`, 1)
+	if err := os.WriteFile(filepath.Join(repoRoot, "skills.jsonc"), []byte(content), 0o644); err != nil {
+		t.Fatalf("write manifest: %v", err)
+	}
+	cmd, stdout, _ = newTestCommand()
+	cmd.SetArgs([]string{"validate"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("validate: %v\n%s", err, stdout.String())
+	}
+	if !strings.Contains(stdout.String(), "No problems found.") {
+		t.Fatalf("expected clean report, got %q", stdout.String())
+	}
+}
+
+func TestSchemaPrintsJSONSchema(t *testing.T) {
+	cmd, stdout, _ := newTestCommand()
+	cmd.SetArgs([]string{"schema"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("schema: %v", err)
+	}
+
+	var schema map[string]any
+	if err := json.Unmarshal(stdout.Bytes(), &schema); err != nil {
+		t.Fatalf("parse schema: %v", err)
+	}
+	properties, ok := schema["properties"].(map[string]any)
+	if !ok || properties["skills"] == nil || properties["extends"] == nil {
+		t.Fatalf("expected skills and extends properties, got %v", schema["properties"])
+	}
+}
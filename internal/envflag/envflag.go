@@ -0,0 +1,25 @@
+// Package envflag reads boolean settings from environment variables the way
+// git reads boolean config values.
+package envflag
+
+import (
+	"os"
+	"strings"
+)
+
+// IsTrue reports whether value is one of git's true spellings: 1, true, yes
+// or on, in any case. Anything else, including an empty value, is false.
+func IsTrue(value string) bool {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "1", "true", "yes", "on":
+		return true
+	default:
+		return false
+	}
+}
+
+// Enabled reports whether the environment variable name is set to a true
+// value.
+func Enabled(name string) bool {
+	return IsTrue(os.Getenv(name))
+}
@@ -0,0 +1,32 @@
+package envflag
+
+import "testing"
+
+func TestIsTrue(t *testing.T) {
+	for value, want := range map[string]bool{
+		"1":     true,
+		"true":  true,
+		"YES":   true,
+		" on ":  true,
+		"0":     false,
+		"false": false,
+		"no":    false,
+		"":      false,
+		"maybe": false,
+	} {
+		if got := IsTrue(value); got != want {
+			t.Errorf("IsTrue(%q) = %t, want %t", value, got, want)
+		}
+	}
+}
+
+func TestEnabled(t *testing.T) {
+	t.Setenv("ASM_TEST_FLAG", "yes")
+	if !Enabled("ASM_TEST_FLAG") {
+		t.Fatalf("expected yes to enable the flag")
+	}
+	t.Setenv("ASM_TEST_FLAG", "off")
+	if Enabled("ASM_TEST_FLAG") {
+		t.Fatalf("expected off to leave the flag disabled")
+	}
+}
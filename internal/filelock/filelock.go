@@ -0,0 +1,82 @@
+// Package filelock takes advisory locks on files shared by concurrent asm
+// processes.
+package filelock
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// ErrLocked is returned by TryAcquire while another holder has the lock.
+var ErrLocked = errors.New("file is locked")
+
+// Lock is a lock held on an open file.
+type Lock struct {
+	file *os.File
+}
+
+// Acquire blocks until it holds an exclusive lock on path, creating the file
+// and its parent directory when needed.
+func Acquire(path string) (*Lock, error) {
+	return acquire(path, lockFile)
+}
+
+// TryAcquire takes the lock on path if it is free and returns ErrLocked
+// otherwise.
+func TryAcquire(path string) (*Lock, error) {
+	return acquire(path, tryLockFile)
+}
+
+// AcquireShared blocks until it holds a shared lock on the existing file at
+// path, which any number of readers may hold at once while no one holds it
+// exclusively. It does not create the file: a missing one returns an error
+// satisfying os.IsNotExist, so readers never leave lock files behind.
+func AcquireShared(path string) (*Lock, error) {
+	file, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+	if err := lockFileShared(file); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("lock %s: %w", path, err)
+	}
+	return &Lock{file: file}, nil
+}
+
+func acquire(path string, lock func(*os.File) error) (*Lock, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	if err := lock(file); err != nil {
+		file.Close()
+		if errors.Is(err, ErrLocked) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("lock %s: %w", path, err)
+	}
+	return &Lock{file: file}, nil
+}
+
+// Write replaces the lock file's contents, such as the holder's PID.
+func (lock *Lock) Write(data []byte) error {
+	if err := lock.file.Truncate(0); err != nil {
+		return err
+	}
+	_, err := lock.file.WriteAt(data, 0)
+	return err
+}
+
+// Release drops the lock. The lock file stays on disk for the next holder.
+func (lock *Lock) Release() error {
+	err := unlockFile(lock.file)
+	if closeErr := lock.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
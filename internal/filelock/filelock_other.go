@@ -0,0 +1,26 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd || windows)
+
+package filelock
+
+import "os"
+
+// Supported reports whether locks are enforced on this platform. Platforms
+// without flock or LockFileEx run unlocked, so callers sharing files with
+// other processes should refuse to.
+const Supported = false
+
+func lockFile(file *os.File) error {
+	return nil
+}
+
+func lockFileShared(file *os.File) error {
+	return nil
+}
+
+func tryLockFile(file *os.File) error {
+	return nil
+}
+
+func unlockFile(file *os.File) error {
+	return nil
+}
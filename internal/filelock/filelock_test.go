@@ -0,0 +1,114 @@
+package filelock
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAcquireWaitsForRelease(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "repo.lock")
+	first, err := Acquire(path)
+	if err != nil {
+		t.Fatalf("acquire first: %v", err)
+	}
+
+	acquired := make(chan *Lock)
+	go func() {
+		second, err := Acquire(path)
+		if err != nil {
+			t.Errorf("acquire second: %v", err)
+		}
+		acquired <- second
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatalf("expected second acquire to wait")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	if err := first.Release(); err != nil {
+		t.Fatalf("release first: %v", err)
+	}
+	select {
+	case second := <-acquired:
+		if second == nil {
+			return
+		}
+		if err := second.Release(); err != nil {
+			t.Fatalf("release second: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("expected second acquire after release")
+	}
+}
+
+func TestTryAcquireReportsHeldLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "asm.lock")
+	held, err := Acquire(path)
+	if err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+	if err := held.Write([]byte("4242\n")); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	if _, err := TryAcquire(path); !errors.Is(err, ErrLocked) {
+		t.Fatalf("expected ErrLocked, got %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != "4242\n" {
+		t.Fatalf("expected holder contents to stay readable, got %q (%v)", data, err)
+	}
+
+	if err := held.Release(); err != nil {
+		t.Fatalf("release: %v", err)
+	}
+	lock, err := TryAcquire(path)
+	if err != nil {
+		t.Fatalf("expected lock after release: %v", err)
+	}
+	if err := lock.Release(); err != nil {
+		t.Fatalf("release: %v", err)
+	}
+}
+
+func TestAcquireSharedAllowsReadersAndBlocksWriters(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "repo.lock")
+	if _, err := AcquireShared(path); !os.IsNotExist(err) {
+		t.Fatalf("expected a missing lock file to be reported, got %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("expected AcquireShared not to create the file, got %v", err)
+	}
+	if err := os.WriteFile(path, nil, 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	first, err := AcquireShared(path)
+	if err != nil {
+		t.Fatalf("acquire first reader: %v", err)
+	}
+	second, err := AcquireShared(path)
+	if err != nil {
+		t.Fatalf("acquire second reader: %v", err)
+	}
+	if _, err := TryAcquire(path); !errors.Is(err, ErrLocked) {
+		t.Fatalf("expected readers to block a writer, got %v", err)
+	}
+	for _, lock := range []*Lock{first, second} {
+		if err := lock.Release(); err != nil {
+			t.Fatalf("release reader: %v", err)
+		}
+	}
+	lock, err := TryAcquire(path)
+	if err != nil {
+		t.Fatalf("expected the writer lock once readers left: %v", err)
+	}
+	if err := lock.Release(); err != nil {
+		t.Fatalf("release: %v", err)
+	}
+}
@@ -0,0 +1,39 @@
+//go:build windows
+
+package filelock
+
+import (
+	"errors"
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// Supported reports whether locks are enforced on this platform.
+const Supported = true
+
+// lockedRegion locks a byte far past the file's contents: Windows locks are
+// mandatory, and other processes still need to read what the holder wrote.
+func lockedRegion() *windows.Overlapped {
+	return &windows.Overlapped{OffsetHigh: 0x7fffffff}
+}
+
+func lockFile(file *os.File) error {
+	return windows.LockFileEx(windows.Handle(file.Fd()), windows.LOCKFILE_EXCLUSIVE_LOCK, 0, 1, 0, lockedRegion())
+}
+
+func lockFileShared(file *os.File) error {
+	return windows.LockFileEx(windows.Handle(file.Fd()), 0, 0, 1, 0, lockedRegion())
+}
+
+func tryLockFile(file *os.File) error {
+	err := windows.LockFileEx(windows.Handle(file.Fd()), windows.LOCKFILE_EXCLUSIVE_LOCK|windows.LOCKFILE_FAIL_IMMEDIATELY, 0, 1, 0, lockedRegion())
+	if errors.Is(err, windows.ERROR_LOCK_VIOLATION) {
+		return ErrLocked
+	}
+	return err
+}
+
+func unlockFile(file *os.File) error {
+	return windows.UnlockFileEx(windows.Handle(file.Fd()), 0, 1, 0, lockedRegion())
+}
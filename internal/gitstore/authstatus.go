@@ -0,0 +1,58 @@
+package gitstore
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	gitssh "github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	"github.com/go-git/go-git/v5/storage/memory"
+
+	"github.com/jmmarotta/agent_skills_manager/internal/debug"
+)
+
+// RedactedAuth describes access's credentials without their secrets, such
+// as "user x-access-token, password redacted".
+func (access RemoteAccess) RedactedAuth() string {
+	switch auth := access.Auth.(type) {
+	case nil:
+		return "none"
+	case *githttp.BasicAuth:
+		if auth.Password == "" {
+			return fmt.Sprintf("user %s, no password", auth.Username)
+		}
+		return fmt.Sprintf("user %s, password redacted", auth.Username)
+	case *gitssh.PublicKeysCallback:
+		return fmt.Sprintf("user %s, public key", auth.User)
+	default:
+		return auth.Name()
+	}
+}
+
+// IsSSH reports whether access connects over SSH, which always
+// authenticates.
+func (access RemoteAccess) IsSSH() bool {
+	info, err := parseRemoteInfo(access.URL)
+	return err == nil && info.Scheme == "ssh"
+}
+
+// ListRemoteAnonymous lists origin's refs without any credentials, to tell
+// whether the remote is reachable and public.
+func ListRemoteAnonymous(ctx context.Context, origin string) error {
+	access, err := ResolveRemoteAccess(origin)
+	if err != nil {
+		return err
+	}
+	remote := git.NewRemote(memory.NewStorage(), &config.RemoteConfig{
+		Name: "origin",
+		URLs: []string{access.URL},
+	})
+	return runNetwork(ctx, opLsRemote, origin, func(ctx context.Context) error {
+		if _, err := remote.ListContext(ctx, access.listOptions(nil)); err != nil {
+			return fmt.Errorf("list remote refs for %s: %w", debug.SanitizeOrigin(origin), err)
+		}
+		return nil
+	})
+}
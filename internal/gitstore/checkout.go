@@ -0,0 +1,289 @@
+package gitstore
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+
+	"github.com/jmmarotta/agent_skills_manager/internal/debug"
+)
+
+// ExportRevision writes the tree at rev into dest. Checkouts are keyed by rev
+// and never change once written, so an existing dest is left alone; several
+// revisions of one origin can be exported side by side.
+//
+// With subdirs set only those directories are written, and the list is kept
+// beside dest in a .sparse file. A sparse checkout missing a requested
+// directory is widened in place: the missing files are moved in beside the
+// ones already there, which installed skills keep linking to throughout. An
+// empty subdir or a nil list asks for the whole tree.
+func ExportRevision(repoPath string, rev string, dest string, subdirs []string) error {
+	full := len(subdirs) == 0 || containsSubdir(subdirs, "")
+	exported, existing, err := exportedSubdirs(dest)
+	if err != nil {
+		return err
+	}
+	if exported {
+		if existing == nil {
+			return nil
+		}
+		missing := full
+		for _, subdir := range subdirs {
+			if !containsSubdir(existing, subdir) {
+				missing = true
+			}
+		}
+		if !missing {
+			return nil
+		}
+		subdirs = append(append([]string{}, existing...), subdirs...)
+	}
+
+	debug.Logf("export repo=%s rev=%s dest=%s subdirs=%q", repoPath, rev, dest, subdirs)
+	repo, release, err := readRepo(repoPath)
+	if err != nil {
+		return err
+	}
+	defer release()
+	commit, err := repo.CommitObject(plumbing.NewHash(rev))
+	if err != nil {
+		return fmt.Errorf("load commit %s: %w", rev, err)
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		return fmt.Errorf("load tree %s: %w", rev, err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+		return err
+	}
+	staging, err := os.MkdirTemp(filepath.Dir(dest), ".export-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(staging)
+
+	if full {
+		if err := tree.Files().ForEach(func(file *object.File) error {
+			return writeTreeFile(staging, file.Name, file)
+		}); err != nil {
+			return fmt.Errorf("export %s: %w", rev, err)
+		}
+	} else {
+		subdirs = sparseSubdirs(subdirs)
+		for _, subdir := range subdirs {
+			if err := exportSubdir(tree, staging, subdir); err != nil {
+				return fmt.Errorf("export %s %s: %w", rev, subdir, err)
+			}
+		}
+	}
+
+	if exported {
+		if err := mergeTree(staging, dest); err != nil {
+			return fmt.Errorf("widen checkout %s: %w", dest, err)
+		}
+		if full {
+			return removeSparseList(dest)
+		}
+		return os.WriteFile(dest+sparseSuffix, []byte(strings.Join(subdirs, "\n")+"\n"), 0o644)
+	}
+	// A checkout without a .sparse list reads as the whole tree, so the list
+	// goes in place before a sparse checkout is published. A stale list left
+	// beside a full checkout only asks for another export.
+	if !full {
+		if err := os.WriteFile(dest+sparseSuffix, []byte(strings.Join(subdirs, "\n")+"\n"), 0o644); err != nil {
+			return err
+		}
+	}
+	if err := os.Rename(staging, dest); err != nil {
+		if _, statErr := os.Stat(dest); statErr == nil {
+			return nil
+		}
+		if !full {
+			os.Remove(dest + sparseSuffix)
+		}
+		return err
+	}
+	if full {
+		return removeSparseList(dest)
+	}
+	return nil
+}
+
+const sparseSuffix = ".sparse"
+
+func removeSparseList(dest string) error {
+	if err := os.Remove(dest + sparseSuffix); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// mergeTree moves every entry of src that dest lacks into dest. Entries
+// dest already has come from the same rev, so they are kept as they are;
+// nothing in dest is ever removed.
+func mergeTree(src string, dest string) error {
+	entries, err := os.ReadDir(src)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		from := filepath.Join(src, entry.Name())
+		to := filepath.Join(dest, entry.Name())
+		info, err := os.Lstat(to)
+		if os.IsNotExist(err) {
+			if err := os.Rename(from, to); err == nil {
+				continue
+			}
+			// Another export may have moved the same entry in first.
+			info, err = os.Lstat(to)
+		}
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() && info.IsDir() {
+			if err := mergeTree(from, to); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// exportedSubdirs reports whether dest exists and, for a sparse checkout,
+// which directories it holds. A full checkout returns a nil list.
+func exportedSubdirs(dest string) (bool, []string, error) {
+	if _, err := os.Stat(dest); err != nil {
+		if os.IsNotExist(err) {
+			return false, nil, nil
+		}
+		return false, nil, err
+	}
+	data, err := os.ReadFile(dest + sparseSuffix)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return true, nil, nil
+		}
+		return false, nil, err
+	}
+	return true, strings.Fields(string(data)), nil
+}
+
+func exportSubdir(tree *object.Tree, root string, subdir string) error {
+	subtree, err := tree.Tree(subdir)
+	if err != nil {
+		if errors.Is(err, object.ErrDirectoryNotFound) {
+			return nil
+		}
+		return err
+	}
+	return subtree.Files().ForEach(func(file *object.File) error {
+		return writeTreeFile(root, path.Join(subdir, file.Name), file)
+	})
+}
+
+// sparseSubdirs sorts and dedupes subdirs, dropping any nested inside
+// another one.
+func sparseSubdirs(subdirs []string) []string {
+	cleaned := make([]string, 0, len(subdirs))
+	for _, subdir := range subdirs {
+		cleaned = append(cleaned, path.Clean(strings.Trim(filepath.ToSlash(subdir), "/")))
+	}
+	sort.Strings(cleaned)
+	result := []string{}
+	for _, subdir := range cleaned {
+		if len(result) > 0 {
+			last := result[len(result)-1]
+			if subdir == last || strings.HasPrefix(subdir, last+"/") {
+				continue
+			}
+		}
+		result = append(result, subdir)
+	}
+	return result
+}
+
+func containsSubdir(subdirs []string, subdir string) bool {
+	subdir = strings.Trim(filepath.ToSlash(subdir), "/")
+	for _, candidate := range subdirs {
+		candidate = strings.Trim(filepath.ToSlash(candidate), "/")
+		if candidate == subdir || (candidate != "" && strings.HasPrefix(subdir, candidate+"/")) {
+			return true
+		}
+	}
+	return false
+}
+
+// PruneCheckouts removes exported revisions of origin that are not in keep.
+func PruneCheckouts(checkoutsDir string, origin string, keep []string) error {
+	root := CheckoutsPath(checkoutsDir, origin)
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	kept := make(map[string]struct{}, len(keep))
+	for _, rev := range keep {
+		kept[rev] = struct{}{}
+	}
+	for _, entry := range entries {
+		if _, ok := kept[strings.TrimSuffix(entry.Name(), sparseSuffix)]; ok {
+			continue
+		}
+		debug.Logf("prune checkout origin=%s rev=%s", debug.SanitizeOrigin(origin), entry.Name())
+		if err := os.RemoveAll(filepath.Join(root, entry.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeTreeFile(root string, name string, file *object.File) error {
+	if file.Mode == filemode.Submodule {
+		return nil
+	}
+	path := filepath.Join(root, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	reader, err := file.Reader()
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	if file.Mode == filemode.Symlink {
+		target, err := io.ReadAll(reader)
+		if err != nil {
+			return err
+		}
+		return os.Symlink(filepath.FromSlash(string(target)), path)
+	}
+
+	perm := os.FileMode(0o644)
+	if file.Mode == filemode.Executable {
+		perm = 0o755
+	}
+	out, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, reader); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
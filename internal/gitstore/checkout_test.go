@@ -0,0 +1,109 @@
+package gitstore
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExportRevisionKeepsRevisionsSideBySide(t *testing.T) {
+	repoDir := t.TempDir()
+	repo := initRepo(t, repoDir)
+	wt, err := repo.Worktree()
+	if err != nil {
+		t.Fatalf("worktree: %v", err)
+	}
+	writeFile(t, repoDir, "plugins/foo/SKILL.md", "v1")
+	if _, err := wt.Add("plugins"); err != nil {
+		t.Fatalf("add: %v", err)
+	}
+	first := commit(t, repo, wt, "first")
+	writeFile(t, repoDir, "plugins/foo/SKILL.md", "v2")
+	if _, err := wt.Add("plugins"); err != nil {
+		t.Fatalf("add: %v", err)
+	}
+	second := commit(t, repo, wt, "second")
+
+	checkoutsDir := t.TempDir()
+	origin := "https://example.com/repo"
+	for _, rev := range []string{first.String(), second.String()} {
+		if err := ExportRevision(repoDir, rev, CheckoutPath(checkoutsDir, origin, rev), nil); err != nil {
+			t.Fatalf("export %s: %v", rev, err)
+		}
+	}
+
+	for rev, expected := range map[string]string{first.String(): "v1", second.String(): "v2"} {
+		data, err := os.ReadFile(filepath.Join(CheckoutPath(checkoutsDir, origin, rev), "plugins", "foo", "SKILL.md"))
+		if err != nil {
+			t.Fatalf("read %s: %v", rev, err)
+		}
+		if string(data) != expected {
+			t.Fatalf("expected %q at %s, got %q", expected, rev, string(data))
+		}
+	}
+
+	if err := PruneCheckouts(checkoutsDir, origin, []string{second.String()}); err != nil {
+		t.Fatalf("prune: %v", err)
+	}
+	if _, err := os.Stat(CheckoutPath(checkoutsDir, origin, first.String())); !os.IsNotExist(err) {
+		t.Fatalf("expected first checkout to be pruned")
+	}
+	if _, err := os.Stat(CheckoutPath(checkoutsDir, origin, second.String())); err != nil {
+		t.Fatalf("expected second checkout to remain: %v", err)
+	}
+}
+
+func TestExportRevisionSparseSubdirs(t *testing.T) {
+	repoDir := t.TempDir()
+	repo := initRepo(t, repoDir)
+	wt, err := repo.Worktree()
+	if err != nil {
+		t.Fatalf("worktree: %v", err)
+	}
+	writeFile(t, repoDir, "plugins/foo/SKILL.md", "foo")
+	writeFile(t, repoDir, "plugins/bar/SKILL.md", "bar")
+	writeFile(t, repoDir, "docs/large.md", "docs")
+	if _, err := wt.Add("."); err != nil {
+		t.Fatalf("add: %v", err)
+	}
+	rev := commit(t, repo, wt, "init").String()
+
+	dest := filepath.Join(t.TempDir(), rev)
+	exists := func(relative string) bool {
+		_, err := os.Stat(filepath.Join(dest, filepath.FromSlash(relative)))
+		return err == nil
+	}
+
+	if err := ExportRevision(repoDir, rev, dest, []string{"plugins/foo"}); err != nil {
+		t.Fatalf("export foo: %v", err)
+	}
+	if !exists("plugins/foo/SKILL.md") || exists("plugins/bar") || exists("docs") {
+		t.Fatalf("expected only plugins/foo to be exported")
+	}
+
+	// Widening leaves the files installed skills already link to in place.
+	before, err := os.Stat(filepath.Join(dest, "plugins", "foo", "SKILL.md"))
+	if err != nil {
+		t.Fatalf("stat foo: %v", err)
+	}
+	if err := ExportRevision(repoDir, rev, dest, []string{"plugins/bar"}); err != nil {
+		t.Fatalf("export bar: %v", err)
+	}
+	if !exists("plugins/foo/SKILL.md") || !exists("plugins/bar/SKILL.md") || exists("docs") {
+		t.Fatalf("expected plugins/foo and plugins/bar to be exported")
+	}
+	after, err := os.Stat(filepath.Join(dest, "plugins", "foo", "SKILL.md"))
+	if err != nil || !os.SameFile(before, after) {
+		t.Fatalf("expected plugins/foo to be kept while widening, got %v", err)
+	}
+
+	if err := ExportRevision(repoDir, rev, dest, nil); err != nil {
+		t.Fatalf("export full: %v", err)
+	}
+	if !exists("docs/large.md") {
+		t.Fatalf("expected a full export")
+	}
+	if _, err := os.Stat(dest + ".sparse"); !os.IsNotExist(err) {
+		t.Fatalf("expected the sparse list to be removed")
+	}
+}
@@ -1,46 +1,269 @@
 package gitstore
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"golang.org/x/mod/module"
 
 	"github.com/jmmarotta/agent_skills_manager/internal/debug"
+	"github.com/jmmarotta/agent_skills_manager/internal/filelock"
 )
 
-func EnsureRepo(path string, origin string) error {
+// EnsureRepo clones origin into the store at path, or fetches it when the
+// clone already exists. Store repos are bare: skills are read from exported
+// checkouts, never from a worktree. The repo's lock file is held throughout
+// so processes sharing a store take turns.
+//
+// EnsureRepo always leaves a full clone, since resolving refs and computing
+// pseudo-versions walk history; a partial clone left by EnsureRevisions is
+// replaced.
+func EnsureRepo(ctx context.Context, path string, origin string) error {
+	lock, err := filelock.Acquire(path + ".lock")
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+
+	if err := discardIncompleteRepo(path); err != nil {
+		return err
+	}
 	if _, err := os.Stat(path); err == nil {
-		debug.Logf("update repo path=%s origin=%s", path, debug.SanitizeOrigin(origin))
-		return UpdateRepo(path, origin)
+		shallow, err := isShallow(path)
+		if err != nil {
+			return err
+		}
+		if !shallow {
+			debug.Logf("update repo path=%s origin=%s", path, debug.SanitizeOrigin(origin))
+			return updateRepo(ctx, path, origin)
+		}
+		debug.Logf("replace partial repo path=%s origin=%s", path, debug.SanitizeOrigin(origin))
+	}
+
+	return cloneRepo(ctx, path, origin)
+}
+
+// EnsureRevisions makes the locked revisions of origin available at path,
+// keyed by version. A new clone fetches only those commits and their trees:
+// semver versions through their tag, pseudo-versions by hash where the server
+// allows it. An existing full clone is fetched as usual, and servers that
+// refuse fetching by hash get a full clone.
+func EnsureRevisions(ctx context.Context, path string, origin string, revs map[string]string) error {
+	lock, err := filelock.Acquire(path + ".lock")
+	if err != nil {
+		return err
 	}
+	defer lock.Release()
+
+	if err := discardIncompleteRepo(path); err != nil {
+		return err
+	}
+	if _, statErr := os.Stat(path); statErr == nil {
+		shallow, shallowErr := isShallow(path)
+		if shallowErr != nil {
+			return shallowErr
+		}
+		if !shallow {
+			debug.Logf("update repo path=%s origin=%s", path, debug.SanitizeOrigin(origin))
+			return updateRepo(ctx, path, origin)
+		}
+		err = fetchRevisions(ctx, path, origin, revs)
+	} else {
+		err = stageRepo(path, func(staging string) error {
+			if err := initPartialRepo(staging, origin); err != nil {
+				return err
+			}
+			return fetchRevisions(ctx, staging, origin, revs)
+		})
+	}
+	if errors.Is(err, git.ErrExactSHA1NotSupported) {
+		debug.Logf("partial fetch unsupported origin=%s; cloning", debug.SanitizeOrigin(origin))
+		return cloneRepo(ctx, path, origin)
+	}
+	return err
+}
 
+// UpdateRepo fetches origin into the store repo at path.
+func UpdateRepo(ctx context.Context, path string, origin string) error {
+	lock, err := filelock.Acquire(path + ".lock")
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+
+	return updateRepo(ctx, path, origin)
+}
+
+const (
+	stagingSuffix  = ".partial"
+	replacedSuffix = ".replaced"
+)
+
+// stageRepo builds a new store repo beside path and moves it into place only
+// once build succeeds, so an interrupted or failed clone never leaves a
+// half-written repo where the next run would trust it. A repo already at
+// path, such as a partial clone, is kept until its replacement is ready and
+// restored if the swap fails. Staging directories left by a killed process
+// are discarded first.
+func stageRepo(path string, build func(staging string) error) error {
+	staging := path + stagingSuffix
+	replaced := path + replacedSuffix
+	for _, leftover := range []string{staging, replaced} {
+		if err := os.RemoveAll(leftover); err != nil {
+			return err
+		}
+	}
 	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
 		return err
 	}
+	if err := build(staging); err != nil {
+		_ = os.RemoveAll(staging)
+		return err
+	}
+	if _, err := os.Stat(path); err != nil {
+		return os.Rename(staging, path)
+	}
+	if err := os.Rename(path, replaced); err != nil {
+		_ = os.RemoveAll(staging)
+		return err
+	}
+	if err := os.Rename(staging, path); err != nil {
+		_ = os.Rename(replaced, path)
+		_ = os.RemoveAll(staging)
+		return err
+	}
+	return os.RemoveAll(replaced)
+}
 
+// discardIncompleteRepo removes a store repo at path that cannot be used: one
+// that does not open, or one with no refs, as left by a clone interrupted
+// before staging existed. The caller clones it again.
+func discardIncompleteRepo(path string) error {
+	if _, err := os.Stat(path); err != nil {
+		return nil
+	}
+	repo, err := git.PlainOpen(path)
+	if err == nil {
+		refs, refsErr := repo.References()
+		if refsErr == nil {
+			found := false
+			_ = refs.ForEach(func(ref *plumbing.Reference) error {
+				if ref.Name() != plumbing.HEAD {
+					found = true
+					return storer.ErrStop
+				}
+				return nil
+			})
+			if found {
+				return nil
+			}
+		}
+	}
+	debug.Logf("discard incomplete repo path=%s", path)
+	return os.RemoveAll(path)
+}
+
+func cloneRepo(ctx context.Context, path string, origin string) error {
 	access, err := ResolveRemoteAccess(origin)
 	if err != nil {
 		return err
 	}
 	options := &git.CloneOptions{
-		URL:   access.URL,
-		Auth:  access.Auth,
-		Tags:  git.AllTags,
-		Depth: 0,
+		URL:          access.URL,
+		Tags:         git.AllTags,
+		Depth:        0,
+		CABundle:     access.http.CABundle,
+		ClientCert:   access.http.ClientCert,
+		ClientKey:    access.http.ClientKey,
+		ProxyOptions: access.http.Proxy,
 	}
 
 	debug.Logf("clone repo path=%s origin=%s", path, debug.SanitizeOrigin(origin))
-	if _, err := git.PlainClone(path, false, options); err != nil {
-		return fmt.Errorf("clone %s: %w", debug.SanitizeOrigin(origin), err)
+	return stageRepo(path, func(staging string) error {
+		return access.authenticate(ctx, func(auth transport.AuthMethod) error {
+			options.Auth = auth
+			return runNetwork(ctx, opClone, origin, func(ctx context.Context) error {
+				// A failed attempt may leave objects behind; start over.
+				if err := os.RemoveAll(staging); err != nil {
+					return err
+				}
+				if _, err := git.PlainCloneContext(ctx, staging, true, options); err != nil {
+					return fmt.Errorf("clone %s: %w", debug.SanitizeOrigin(origin), err)
+				}
+				return nil
+			})
+		})
+	})
+}
+
+func initPartialRepo(path string, origin string) error {
+	access, err := ResolveRemoteAccess(origin)
+	if err != nil {
+		return err
 	}
 
+	debug.Logf("init partial repo path=%s origin=%s", path, debug.SanitizeOrigin(origin))
+	repo, err := git.PlainInit(path, true)
+	if err != nil {
+		return fmt.Errorf("init repo %s: %w", path, err)
+	}
+	if _, err := repo.CreateRemote(&config.RemoteConfig{Name: "origin", URLs: []string{access.URL}}); err != nil {
+		return fmt.Errorf("configure remote: %w", err)
+	}
 	return nil
 }
 
-func UpdateRepo(path string, origin string) error {
+func fetchRevisions(ctx context.Context, path string, origin string, revs map[string]string) error {
+	repo, err := git.PlainOpen(path)
+	if err != nil {
+		return fmt.Errorf("open repo %s: %w", path, err)
+	}
+	access, err := ResolveRemoteAccess(origin)
+	if err != nil {
+		return err
+	}
+
+	refSpecs := make([]config.RefSpec, 0, len(revs))
+	for version, rev := range revs {
+		if module.IsPseudoVersion(version) {
+			refSpecs = append(refSpecs, config.RefSpec(fmt.Sprintf("+%s:refs/asm/%s", rev, rev)))
+			continue
+		}
+		refSpecs = append(refSpecs, config.RefSpec(fmt.Sprintf("+refs/tags/%s:refs/tags/%s", version, version)))
+	}
+
+	debug.Logf("fetch revisions path=%s origin=%s count=%d", path, debug.SanitizeOrigin(origin), len(refSpecs))
+	return access.authenticate(ctx, func(auth transport.AuthMethod) error {
+		return runNetwork(ctx, opFetch, origin, func(ctx context.Context) error {
+			err := repo.FetchContext(ctx, &git.FetchOptions{
+				RemoteName:   "origin",
+				RemoteURL:    access.URL,
+				Auth:         auth,
+				Depth:        1,
+				Tags:         git.NoTags,
+				RefSpecs:     refSpecs,
+				CABundle:     access.http.CABundle,
+				ClientCert:   access.http.ClientCert,
+				ClientKey:    access.http.ClientKey,
+				ProxyOptions: access.http.Proxy,
+			})
+			if err != nil && err != git.NoErrAlreadyUpToDate {
+				return fmt.Errorf("fetch %s: %w", debug.SanitizeOrigin(origin), err)
+			}
+			return nil
+		})
+	})
+}
+
+func updateRepo(ctx context.Context, path string, origin string) error {
 	repo, err := git.PlainOpen(path)
 	if err != nil {
 		return fmt.Errorf("open repo %s: %w", path, err)
@@ -55,16 +278,36 @@ func UpdateRepo(path string, origin string) error {
 		RemoteName: "origin",
 		RemoteURL:  access.URL,
 		Tags:       git.AllTags,
-		Auth:       access.Auth,
 		RefSpecs: []config.RefSpec{
 			"+refs/heads/*:refs/remotes/origin/*",
 			"+refs/tags/*:refs/tags/*",
 		},
+		CABundle:     access.http.CABundle,
+		ClientCert:   access.http.ClientCert,
+		ClientKey:    access.http.ClientKey,
+		ProxyOptions: access.http.Proxy,
 	}
 
-	if err := repo.Fetch(fetchOptions); err != nil && err != git.NoErrAlreadyUpToDate {
-		return fmt.Errorf("fetch %s: %w", debug.SanitizeOrigin(origin), err)
-	}
+	return access.authenticate(ctx, func(auth transport.AuthMethod) error {
+		fetchOptions.Auth = auth
+		return runNetwork(ctx, opFetch, origin, func(ctx context.Context) error {
+			if err := repo.FetchContext(ctx, fetchOptions); err != nil && err != git.NoErrAlreadyUpToDate {
+				return fmt.Errorf("fetch %s: %w", debug.SanitizeOrigin(origin), err)
+			}
+			return nil
+		})
+	})
+}
 
-	return nil
+// isShallow reports whether the repo at path was fetched partially.
+func isShallow(path string) (bool, error) {
+	repo, err := git.PlainOpen(path)
+	if err != nil {
+		return false, fmt.Errorf("open repo %s: %w", path, err)
+	}
+	shallow, err := repo.Storer.Shallow()
+	if err != nil {
+		return false, fmt.Errorf("read shallow commits: %w", err)
+	}
+	return len(shallow) > 0, nil
 }
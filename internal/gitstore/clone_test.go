@@ -1,12 +1,17 @@
 package gitstore
 
 import (
+	"context"
+	"errors"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/plumbing"
+
+	"github.com/jmmarotta/agent_skills_manager/internal/filelock"
 )
 
 func TestEnsureRepoClonesAndUpdates(t *testing.T) {
@@ -21,16 +26,23 @@ func TestEnsureRepoClonesAndUpdates(t *testing.T) {
 	if _, err := wt.Add("plugins/foo/SKILL.md"); err != nil {
 		t.Fatalf("add: %v", err)
 	}
-	commit(t, repo, wt, "init")
+	first := commit(t, repo, wt, "init")
 
 	cloneDir := filepath.Join(t.TempDir(), "clone")
-	if err := EnsureRepo(cloneDir, repoDir); err != nil {
+	if err := EnsureRepo(context.Background(), cloneDir, repoDir); err != nil {
 		t.Fatalf("EnsureRepo: %v", err)
 	}
+	if _, err := os.Stat(filepath.Join(cloneDir, "plugins")); !os.IsNotExist(err) {
+		t.Fatalf("expected a bare clone without a worktree")
+	}
 
-	contents, err := os.ReadFile(filepath.Join(cloneDir, "plugins", "foo", "SKILL.md"))
+	checkout := filepath.Join(t.TempDir(), "checkout")
+	if err := ExportRevision(cloneDir, first.String(), checkout, nil); err != nil {
+		t.Fatalf("export: %v", err)
+	}
+	contents, err := os.ReadFile(filepath.Join(checkout, "plugins", "foo", "SKILL.md"))
 	if err != nil {
-		t.Fatalf("read clone: %v", err)
+		t.Fatalf("read checkout: %v", err)
 	}
 	if string(contents) != "v1" {
 		t.Fatalf("expected v1, got %q", string(contents))
@@ -42,7 +54,7 @@ func TestEnsureRepoClonesAndUpdates(t *testing.T) {
 	}
 	newCommit := commit(t, repo, wt, "update")
 
-	if err := EnsureRepo(cloneDir, repoDir); err != nil {
+	if err := EnsureRepo(context.Background(), cloneDir, repoDir); err != nil {
 		t.Fatalf("EnsureRepo update: %v", err)
 	}
 
@@ -55,6 +67,51 @@ func TestEnsureRepoClonesAndUpdates(t *testing.T) {
 	}
 }
 
+func TestStoreReadsWaitForRepoLock(t *testing.T) {
+	repoDir := t.TempDir()
+	repo := initRepo(t, repoDir)
+	writeFile(t, repoDir, "plugins/foo/SKILL.md", "v1")
+	wt, err := repo.Worktree()
+	if err != nil {
+		t.Fatalf("worktree: %v", err)
+	}
+	if _, err := wt.Add("plugins/foo/SKILL.md"); err != nil {
+		t.Fatalf("add: %v", err)
+	}
+	rev := commit(t, repo, wt, "init")
+
+	cloneDir := filepath.Join(t.TempDir(), "clone")
+	if err := EnsureRepo(context.Background(), cloneDir, repoDir); err != nil {
+		t.Fatalf("EnsureRepo: %v", err)
+	}
+	// A writer such as EnsureRepo replacing the clone holds the lock.
+	lock, err := filelock.Acquire(cloneDir + ".lock")
+	if err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+	done := make(chan error, 1)
+	go func() {
+		_, err := CommitExists(cloneDir, rev.String())
+		done <- err
+	}()
+	select {
+	case <-done:
+		t.Fatalf("expected the read to wait for the writer")
+	case <-time.After(100 * time.Millisecond):
+	}
+	if err := lock.Release(); err != nil {
+		t.Fatalf("release: %v", err)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("CommitExists: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("expected the read once the writer released the lock")
+	}
+}
+
 func TestResolveForRefPrefersRemoteBranch(t *testing.T) {
 	originDir := t.TempDir()
 	originRepo := initRepo(t, originDir)
@@ -78,7 +135,7 @@ func TestResolveForRefPrefersRemoteBranch(t *testing.T) {
 	}
 
 	cloneDir := filepath.Join(t.TempDir(), "clone")
-	if err := EnsureRepo(cloneDir, originDir); err != nil {
+	if err := EnsureRepo(context.Background(), cloneDir, originDir); err != nil {
 		t.Fatalf("EnsureRepo: %v", err)
 	}
 
@@ -94,17 +151,13 @@ func TestResolveForRefPrefersRemoteBranch(t *testing.T) {
 		t.Fatalf("expected local main %s, got %s", commitA.String(), localMain.Hash())
 	}
 
-	if err := CheckoutRevision(cloneDir, commitA.String()); err != nil {
-		t.Fatalf("checkout clone: %v", err)
-	}
-
 	writeFile(t, originDir, "README.md", "v2")
 	if _, err := wt.Add("README.md"); err != nil {
 		t.Fatalf("add update: %v", err)
 	}
 	commitB := commit(t, originRepo, wt, "update")
 
-	if err := EnsureRepo(cloneDir, originDir); err != nil {
+	if err := EnsureRepo(context.Background(), cloneDir, originDir); err != nil {
 		t.Fatalf("EnsureRepo update: %v", err)
 	}
 
@@ -143,3 +196,96 @@ func TestResolveForRefPrefersRemoteBranch(t *testing.T) {
 		t.Fatalf("expected refs/heads/main to resolve to %s, got %s", commitA.String(), resolvedLocal.Rev)
 	}
 }
+
+func TestEnsureRevisionsFetchesOnlyLockedTags(t *testing.T) {
+	repoDir, repo, wt, first := createTaggedRepo(t, "v1.0.0")
+	writeFile(t, repoDir, "README.md", "v2")
+	if _, err := wt.Add("README.md"); err != nil {
+		t.Fatalf("add: %v", err)
+	}
+	second := commit(t, repo, wt, "update")
+	if _, err := repo.CreateTag("v1.1.0", second, nil); err != nil {
+		t.Fatalf("tag: %v", err)
+	}
+
+	cloneDir := filepath.Join(t.TempDir(), "clone")
+	if err := EnsureRevisions(context.Background(), cloneDir, repoDir, map[string]string{"v1.1.0": second.String()}); err != nil {
+		t.Fatalf("EnsureRevisions: %v", err)
+	}
+	if exists, _ := CommitExists(cloneDir, second.String()); !exists {
+		t.Fatalf("expected locked commit to be fetched")
+	}
+	if exists, _ := CommitExists(cloneDir, first.String()); exists {
+		t.Fatalf("expected older history to be skipped")
+	}
+	rev, err := ResolveForVersionAt(cloneDir, "v1.1.0")
+	if err != nil || rev != second.String() {
+		t.Fatalf("expected v1.1.0 to resolve to %s, got %s (%v)", second, rev, err)
+	}
+
+	if err := EnsureRepo(context.Background(), cloneDir, repoDir); err != nil {
+		t.Fatalf("EnsureRepo: %v", err)
+	}
+	if exists, _ := CommitExists(cloneDir, first.String()); !exists {
+		t.Fatalf("expected EnsureRepo to replace the partial clone")
+	}
+	resolved, err := ResolveForRefAt(cloneDir, second.String())
+	if err != nil || resolved.Version != "v1.1.0" {
+		t.Fatalf("expected full history to resolve v1.1.0, got %q (%v)", resolved.Version, err)
+	}
+}
+
+func TestEnsureRevisionsFallsBackToCloneForHashes(t *testing.T) {
+	repoDir, _, _, first := createTaggedRepo(t, "v1.0.0")
+
+	cloneDir := filepath.Join(t.TempDir(), "clone")
+	version := "v1.0.1-0.20240101000000-" + first.String()[:12]
+	if err := EnsureRevisions(context.Background(), cloneDir, repoDir, map[string]string{version: first.String()}); err != nil {
+		t.Fatalf("EnsureRevisions: %v", err)
+	}
+	if exists, _ := CommitExists(cloneDir, first.String()); !exists {
+		t.Fatalf("expected pinned commit after falling back to a clone")
+	}
+	if shallow, err := isShallow(cloneDir); err != nil || shallow {
+		t.Fatalf("expected a full clone, shallow=%t err=%v", shallow, err)
+	}
+}
+
+func TestEnsureRepoReplacesIncompleteClone(t *testing.T) {
+	repoDir, _, _, first := createTaggedRepo(t, "v1.0.0")
+
+	cloneDir := filepath.Join(t.TempDir(), "clone")
+	// An interrupted clone: an empty repo and a leftover staging directory.
+	if _, err := git.PlainInit(cloneDir, true); err != nil {
+		t.Fatalf("init: %v", err)
+	}
+	if err := os.MkdirAll(cloneDir+stagingSuffix, 0o755); err != nil {
+		t.Fatalf("mkdir staging: %v", err)
+	}
+
+	if err := EnsureRepo(context.Background(), cloneDir, repoDir); err != nil {
+		t.Fatalf("EnsureRepo: %v", err)
+	}
+	if exists, _ := CommitExists(cloneDir, first.String()); !exists {
+		t.Fatalf("expected a fresh clone")
+	}
+	if _, err := os.Stat(cloneDir + stagingSuffix); !os.IsNotExist(err) {
+		t.Fatalf("expected staging directory to be gone")
+	}
+}
+
+func TestEnsureRepoCancelledLeavesNoClone(t *testing.T) {
+	repoDir, _, _, _ := createTaggedRepo(t, "v1.0.0")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	cloneDir := filepath.Join(t.TempDir(), "clone")
+	if err := EnsureRepo(ctx, cloneDir, repoDir); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected cancellation, got %v", err)
+	}
+	for _, path := range []string{cloneDir, cloneDir + stagingSuffix} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Fatalf("expected %s to be removed", path)
+		}
+	}
+}
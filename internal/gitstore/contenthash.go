@@ -0,0 +1,137 @@
+package gitstore
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"golang.org/x/mod/sumdb/dirhash"
+)
+
+var ErrPathNotFound = errors.New("path not found")
+
+// TreeHash returns the h1: hash of subdir at rev, computed from git objects so
+// the result does not depend on the state of any worktree.
+func TreeHash(repoPath string, rev string, subdir string) (string, error) {
+	repo, release, err := readRepo(repoPath)
+	if err != nil {
+		return "", err
+	}
+	defer release()
+
+	commit, err := repo.CommitObject(plumbing.NewHash(rev))
+	if err != nil {
+		return "", fmt.Errorf("load commit %s: %w", rev, err)
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		return "", fmt.Errorf("load tree %s: %w", rev, err)
+	}
+	if subdir = strings.Trim(path.Clean(filepath.ToSlash(subdir)), "/"); subdir != "" && subdir != "." {
+		tree, err = tree.Tree(subdir)
+		if err != nil {
+			if errors.Is(err, object.ErrDirectoryNotFound) {
+				return "", fmt.Errorf("subdir %q at %s: %w", subdir, rev, ErrPathNotFound)
+			}
+			return "", fmt.Errorf("subdir %q at %s: %w", subdir, rev, err)
+		}
+	}
+
+	files := map[string]*object.File{}
+	if err := tree.Files().ForEach(func(file *object.File) error {
+		if file.Mode == filemode.Submodule {
+			return nil
+		}
+		files[file.Name] = file
+		return nil
+	}); err != nil {
+		return "", fmt.Errorf("list files %s: %w", rev, err)
+	}
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+
+	return dirhash.Hash1(names, func(name string) (io.ReadCloser, error) {
+		file, ok := files[name]
+		if !ok {
+			return nil, fmt.Errorf("file %s not found", name)
+		}
+		return file.Reader()
+	})
+}
+
+// DirHash returns the h1: hash of the files under dir as they exist on disk.
+// Symlinks hash their target, matching how git stores them.
+func DirHash(dir string) (string, error) {
+	names := []string{}
+	if err := filepath.WalkDir(dir, func(current string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if entry.Name() == ".git" && current != dir {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !entry.Type().IsRegular() && entry.Type()&os.ModeSymlink == 0 {
+			return nil
+		}
+		relative, err := filepath.Rel(dir, current)
+		if err != nil {
+			return err
+		}
+		names = append(names, filepath.ToSlash(relative))
+		return nil
+	}); err != nil {
+		return "", err
+	}
+
+	return dirhash.Hash1(names, func(name string) (io.ReadCloser, error) {
+		full := filepath.Join(dir, filepath.FromSlash(name))
+		info, err := os.Lstat(full)
+		if err != nil {
+			return nil, err
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			target, err := os.Readlink(full)
+			if err != nil {
+				return nil, err
+			}
+			return io.NopCloser(bytes.NewReader([]byte(filepath.ToSlash(target)))), nil
+		}
+		return os.Open(full)
+	})
+}
+
+type HashMismatchError struct {
+	Path     string
+	Expected string
+	Actual   string
+}
+
+func (err HashMismatchError) Error() string {
+	return fmt.Sprintf("content hash mismatch at %s: expected %s, got %s", err.Path, err.Expected, err.Actual)
+}
+
+func VerifyDirHash(dir string, expected string) error {
+	actual, err := DirHash(dir)
+	if err != nil {
+		return fmt.Errorf("hash %s: %w", dir, err)
+	}
+	if actual != expected {
+		return HashMismatchError{Path: dir, Expected: expected, Actual: actual}
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package gitstore
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTreeHashMatchesCheckout(t *testing.T) {
+	repoDir := t.TempDir()
+	repo := initRepo(t, repoDir)
+	wt, err := repo.Worktree()
+	if err != nil {
+		t.Fatalf("worktree: %v", err)
+	}
+	writeFile(t, repoDir, "plugins/foo/SKILL.md", "foo")
+	writeFile(t, repoDir, "plugins/foo/docs/usage.md", "usage")
+	writeFile(t, repoDir, "plugins/bar/SKILL.md", "bar")
+	if _, err := wt.Add("plugins"); err != nil {
+		t.Fatalf("add: %v", err)
+	}
+	commitHash := commit(t, repo, wt, "init")
+
+	treeHash, err := TreeHash(repoDir, commitHash.String(), "plugins/foo")
+	if err != nil {
+		t.Fatalf("TreeHash: %v", err)
+	}
+	dirHash, err := DirHash(filepath.Join(repoDir, "plugins", "foo"))
+	if err != nil {
+		t.Fatalf("DirHash: %v", err)
+	}
+	if treeHash != dirHash {
+		t.Fatalf("expected tree hash %s to match dir hash %s", treeHash, dirHash)
+	}
+
+	rootTree, err := TreeHash(repoDir, commitHash.String(), "")
+	if err != nil {
+		t.Fatalf("TreeHash root: %v", err)
+	}
+	rootDir, err := DirHash(repoDir)
+	if err != nil {
+		t.Fatalf("DirHash root: %v", err)
+	}
+	if rootTree != rootDir {
+		t.Fatalf("expected root hashes to match, got %s and %s", rootTree, rootDir)
+	}
+
+	if _, err := TreeHash(repoDir, commitHash.String(), "plugins/missing"); !errors.Is(err, ErrPathNotFound) {
+		t.Fatalf("expected ErrPathNotFound, got %v", err)
+	}
+}
+
+func TestVerifyDirHashDetectsExtraFile(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "SKILL.md", "foo")
+
+	expected, err := DirHash(dir)
+	if err != nil {
+		t.Fatalf("DirHash: %v", err)
+	}
+	if err := VerifyDirHash(dir, expected); err != nil {
+		t.Fatalf("VerifyDirHash: %v", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "extra.sh"), []byte("echo"), 0o644); err != nil {
+		t.Fatalf("write extra: %v", err)
+	}
+	err = VerifyDirHash(dir, expected)
+	var mismatch HashMismatchError
+	if !errors.As(err, &mismatch) {
+		t.Fatalf("expected HashMismatchError, got %v", err)
+	}
+	if mismatch.Expected != expected {
+		t.Fatalf("expected %s, got %s", expected, mismatch.Expected)
+	}
+}
@@ -0,0 +1,240 @@
+package gitstore
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+
+	"github.com/jmmarotta/agent_skills_manager/internal/debug"
+	"github.com/jmmarotta/agent_skills_manager/internal/envflag"
+)
+
+// credentialHelpers are the git credential helpers configured for one HTTP
+// remote, with the request git would send them for it.
+type credentialHelpers struct {
+	Helpers []string
+	Request credentialRequest
+}
+
+// credentialRequest is the description of a remote passed to a credential
+// helper, plus the username and password it answered with.
+type credentialRequest struct {
+	Protocol string
+	Host     string
+	Path     string
+	Username string
+	Password string
+}
+
+// loadCredentialHelpers returns the credential.helper entries that apply to
+// origin, in the order git consults them, or nil when there are none. Like
+// git, an empty helper value clears the helpers configured before it.
+func loadCredentialHelpers(origin string) (*credentialHelpers, error) {
+	parsed, err := url.Parse(origin)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := loadGitConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	helpers := []string{}
+	useHTTPPath := false
+	for _, entry := range entries {
+		if entry.Section != "credential" || !credentialURLMatches(entry.Subsection, parsed) {
+			continue
+		}
+		switch entry.Key {
+		case "helper":
+			if entry.Value == "" {
+				helpers = helpers[:0]
+				continue
+			}
+			helpers = append(helpers, entry.Value)
+		case "usehttppath":
+			useHTTPPath = envflag.IsTrue(entry.Value)
+		}
+	}
+	if len(helpers) == 0 {
+		return nil, nil
+	}
+
+	request := credentialRequest{Protocol: strings.ToLower(parsed.Scheme), Host: parsed.Host}
+	if useHTTPPath {
+		request.Path = strings.TrimPrefix(parsed.Path, "/")
+	}
+	return &credentialHelpers{Helpers: helpers, Request: request}, nil
+}
+
+// credentialURLMatches reports whether a credential.<url> subsection applies
+// to target: same scheme and host, and a path prefix of target's.
+func credentialURLMatches(pattern string, target *url.URL) bool {
+	if pattern == "" {
+		return true
+	}
+	parsed, err := url.Parse(pattern)
+	if err != nil || parsed.Host == "" {
+		return false
+	}
+	if !strings.EqualFold(parsed.Scheme, target.Scheme) || !strings.EqualFold(parsed.Host, target.Host) {
+		return false
+	}
+	if parsed.User != nil && target.User != nil && parsed.User.Username() != target.User.Username() {
+		return false
+	}
+	prefix := strings.TrimSuffix(parsed.Path, "/")
+	return prefix == "" || target.Path == prefix || strings.HasPrefix(target.Path, prefix+"/")
+}
+
+// fill asks each helper for credentials until one supplies both a username
+// and a password, as git credential fill does.
+func (helpers *credentialHelpers) fill(ctx context.Context) (credentialRequest, bool, error) {
+	request := helpers.Request
+	for _, helper := range helpers.Helpers {
+		output, err := runCredentialHelper(ctx, helper, "get", request)
+		if err != nil {
+			if ctx.Err() != nil {
+				return credentialRequest{}, false, ctx.Err()
+			}
+			debug.Logf("credential helper %q get failed: %v", helper, err)
+			continue
+		}
+		quit := false
+		for key, value := range output {
+			switch key {
+			case "username":
+				request.Username = value
+			case "password":
+				request.Password = value
+			case "quit":
+				quit = envflag.IsTrue(value)
+			}
+		}
+		if request.Username != "" && request.Password != "" {
+			debug.Logf("credential helper %q supplied credentials for %s", helper, request.Host)
+			return request, true, nil
+		}
+		if quit {
+			break
+		}
+	}
+	return credentialRequest{}, false, nil
+}
+
+// approve tells every helper that request's credentials worked, so they
+// can store them. Failures are logged and ignored, as git does.
+func (helpers *credentialHelpers) approve(ctx context.Context, request credentialRequest) {
+	helpers.report(ctx, "store", request)
+}
+
+// reject tells every helper that request's credentials were refused, so
+// they can forget them.
+func (helpers *credentialHelpers) reject(ctx context.Context, request credentialRequest) {
+	helpers.report(ctx, "erase", request)
+}
+
+func (helpers *credentialHelpers) report(ctx context.Context, action string, request credentialRequest) {
+	for _, helper := range helpers.Helpers {
+		if _, err := runCredentialHelper(ctx, helper, action, request); err != nil {
+			debug.Logf("credential helper %q %s failed: %v", helper, action, err)
+		}
+	}
+}
+
+// runCredentialHelper runs helper with action the way git does: "!cmd" runs
+// cmd in the shell, an absolute path runs that program, and anything else
+// runs git credential-<helper>. It returns the helper's key=value answers.
+func runCredentialHelper(ctx context.Context, helper string, action string, request credentialRequest) (map[string]string, error) {
+	command := helper
+	switch {
+	case strings.HasPrefix(helper, "!"):
+		command = helper[1:]
+	case filepath.IsAbs(helper):
+	default:
+		command = "git credential-" + helper
+	}
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", command+" "+action)
+	cmd.Stdin = strings.NewReader(request.encode())
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return nil, fmt.Errorf("%w: %s", err, message)
+		}
+		return nil, err
+	}
+
+	output := map[string]string{}
+	scanner := bufio.NewScanner(&stdout)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			break
+		}
+		if key, value, ok := strings.Cut(line, "="); ok {
+			output[key] = value
+		}
+	}
+	return output, scanner.Err()
+}
+
+// encode writes request in the git credential protocol format.
+func (request credentialRequest) encode() string {
+	var builder strings.Builder
+	for _, field := range [][2]string{
+		{"protocol", request.Protocol},
+		{"host", request.Host},
+		{"path", request.Path},
+		{"username", request.Username},
+		{"password", request.Password},
+	} {
+		if field[1] != "" {
+			fmt.Fprintf(&builder, "%s=%s\n", field[0], field[1])
+		}
+	}
+	builder.WriteString("\n")
+	return builder.String()
+}
+
+// authenticate runs a remote operation with access's credentials. When the
+// remote asks for credentials that no other source supplied, it fills them
+// from the git credential helpers, runs the operation again, and reports
+// back to the helpers whether they were accepted.
+func (access RemoteAccess) authenticate(ctx context.Context, run func(transport.AuthMethod) error) error {
+	err := run(access.Auth)
+	if access.credentials == nil || !isAuthFailure(err) {
+		return err
+	}
+
+	request, ok, fillErr := access.credentials.fill(ctx)
+	if fillErr != nil {
+		return fillErr
+	}
+	if !ok {
+		return err
+	}
+	err = run(&githttp.BasicAuth{Username: request.Username, Password: request.Password})
+	switch {
+	case err == nil:
+		access.credentials.approve(ctx, request)
+	case isAuthFailure(err):
+		access.credentials.reject(ctx, request)
+	}
+	return err
+}
+
+func isAuthFailure(err error) bool {
+	return errors.Is(err, transport.ErrAuthenticationRequired) || errors.Is(err, transport.ErrAuthorizationFailed)
+}
@@ -0,0 +1,118 @@
+package gitstore
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+)
+
+// useCredentialHelper configures a fake credential helper that answers get
+// with user/secret and logs every call to the returned file.
+func useCredentialHelper(t *testing.T) string {
+	root := t.TempDir()
+	logPath := filepath.Join(root, "helper.log")
+	script := filepath.Join(root, "helper.sh")
+	writeFile(t, root, "helper.sh", fmt.Sprintf(`#!/bin/sh
+{ echo "action=$1"; cat; } >> %q
+if [ "$1" = get ]; then
+	echo username=user
+	echo password=secret
+fi
+`, logPath))
+	if err := os.Chmod(script, 0o755); err != nil {
+		t.Fatalf("chmod helper: %v", err)
+	}
+	writeFile(t, root, "gitconfig", fmt.Sprintf(
+		"[credential \"https://other.example.com\"]\n\thelper = !echo unused\n[credential]\n\thelper = %s\n", script))
+
+	t.Setenv("HOME", root)
+	t.Setenv("GIT_CONFIG_GLOBAL", filepath.Join(root, "gitconfig"))
+	t.Setenv("NETRC", filepath.Join(root, "netrc"))
+	t.Setenv("ASM_GIT_TOKEN", "")
+	t.Setenv("ASM_GIT_USERNAME", "")
+	t.Setenv("ASM_GIT_PASSWORD", "")
+	return logPath
+}
+
+func TestCredentialHelperFillsAndApproves(t *testing.T) {
+	logPath := useCredentialHelper(t)
+
+	access, err := ResolveRemoteAccess("https://git.example.com/org/repo")
+	if err != nil {
+		t.Fatalf("ResolveRemoteAccess: %v", err)
+	}
+	if access.Auth != nil {
+		t.Fatalf("expected no up-front auth, got %T", access.Auth)
+	}
+
+	attempts := 0
+	err = access.authenticate(context.Background(), func(auth transport.AuthMethod) error {
+		attempts++
+		basic, ok := auth.(*githttp.BasicAuth)
+		if !ok || basic.Username != "user" || basic.Password != "secret" {
+			return fmt.Errorf("list: %w", transport.ErrAuthenticationRequired)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("authenticate: %v", err)
+	}
+	if attempts != 2 {
+		t.Fatalf("expected an anonymous attempt and a helper attempt, got %d", attempts)
+	}
+
+	data, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatalf("read helper log: %v", err)
+	}
+	want := "action=get\nprotocol=https\nhost=git.example.com\n\n" +
+		"action=store\nprotocol=https\nhost=git.example.com\nusername=user\npassword=secret\n\n"
+	if string(data) != want {
+		t.Fatalf("unexpected helper calls:\n%s", string(data))
+	}
+}
+
+func TestCredentialHelperRejectsRefusedCredentials(t *testing.T) {
+	logPath := useCredentialHelper(t)
+
+	access, err := ResolveRemoteAccess("https://git.example.com/org/repo")
+	if err != nil {
+		t.Fatalf("ResolveRemoteAccess: %v", err)
+	}
+	err = access.authenticate(context.Background(), func(auth transport.AuthMethod) error {
+		return fmt.Errorf("list: %w", transport.ErrAuthorizationFailed)
+	})
+	if err == nil {
+		t.Fatalf("expected authorization failure")
+	}
+
+	data, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatalf("read helper log: %v", err)
+	}
+	if !strings.Contains(string(data), "action=erase\nprotocol=https\nhost=git.example.com\nusername=user\npassword=secret\n") {
+		t.Fatalf("expected helper to erase refused credentials:\n%s", string(data))
+	}
+}
+
+func TestCredentialHelperSkippedWithOtherCredentials(t *testing.T) {
+	logPath := useCredentialHelper(t)
+	t.Setenv("ASM_GIT_TOKEN", "token")
+
+	access, err := ResolveRemoteAccess("https://git.example.com/org/repo")
+	if err != nil {
+		t.Fatalf("ResolveRemoteAccess: %v", err)
+	}
+	if access.credentials != nil {
+		t.Fatalf("expected helpers to be skipped when ASM_GIT_TOKEN is set")
+	}
+	if _, err := os.Stat(logPath); !os.IsNotExist(err) {
+		t.Fatalf("expected helper not to run")
+	}
+}
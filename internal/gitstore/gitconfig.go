@@ -2,15 +2,23 @@ package gitstore
 
 import (
 	"bufio"
+	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
+	"runtime"
 	"strconv"
 	"strings"
+
+	"github.com/jmmarotta/agent_skills_manager/internal/envflag"
 )
 
 type urlRewrite struct {
 	Base      string
 	InsteadOf string
+	// Push marks a pushInsteadOf rule, which only rewrites push URLs and so
+	// never applies to the fetches asm makes.
+	Push bool
 }
 
 func applyInsteadOf(origin string) (string, bool, error) {
@@ -22,7 +30,7 @@ func applyInsteadOf(origin string) (string, bool, error) {
 	var match urlRewrite
 	found := false
 	for _, rule := range rules {
-		if rule.InsteadOf == "" {
+		if rule.InsteadOf == "" || rule.Push {
 			continue
 		}
 		if strings.HasPrefix(origin, rule.InsteadOf) {
@@ -41,42 +49,184 @@ func applyInsteadOf(origin string) (string, bool, error) {
 }
 
 func loadURLRewrites() ([]urlRewrite, error) {
-	files := gitConfigFiles()
-	seen := make(map[string]struct{})
+	entries, err := loadGitConfig()
+	if err != nil {
+		return nil, err
+	}
 	rules := []urlRewrite{}
-	for _, path := range files {
-		parsed, err := parseGitConfigFile(path, seen)
+	for _, entry := range entries {
+		if entry.Section != "url" {
+			continue
+		}
+		switch entry.Key {
+		case "insteadof":
+			rules = append(rules, urlRewrite{Base: entry.Subsection, InsteadOf: entry.Value})
+		case "pushinsteadof":
+			rules = append(rules, urlRewrite{Base: entry.Subsection, InsteadOf: entry.Value, Push: true})
+		}
+	}
+	return rules, nil
+}
+
+// gitConfigEntry is one key from a git config file, with the section and
+// subsection it appeared under. Section and Key are lower-case.
+type gitConfigEntry struct {
+	Section    string
+	Subsection string
+	Key        string
+	Value      string
+}
+
+// loadGitConfig reads every entry of the git config files in the order git
+// applies them, following includes, so later entries win.
+func loadGitConfig() ([]gitConfigEntry, error) {
+	reader := gitConfigReader{gitDir: consumingGitDir(), seen: make(map[string]struct{})}
+	entries := []gitConfigEntry{}
+	for _, path := range gitConfigFiles(reader.gitDir) {
+		parsed, err := reader.parseFile(path)
 		if err != nil {
 			return nil, err
 		}
-		rules = append(rules, parsed...)
+		entries = append(entries, parsed...)
 	}
-	return rules, nil
+	return append(entries, commandConfigEntries()...), nil
 }
 
-func gitConfigFiles() []string {
+// gitConfigFiles lists the config files git reads, lowest precedence first:
+// the system file, the global files and the local config of gitDir.
+func gitConfigFiles(gitDir string) []string {
+	paths := []string{}
+	if !envflag.Enabled("GIT_CONFIG_NOSYSTEM") {
+		if system := os.Getenv("GIT_CONFIG_SYSTEM"); system != "" {
+			paths = append(paths, system)
+		} else if runtime.GOOS != "windows" {
+			paths = append(paths, "/etc/gitconfig")
+		}
+	}
+
 	if custom := os.Getenv("GIT_CONFIG_GLOBAL"); custom != "" {
-		return []string{custom}
+		paths = append(paths, custom)
+	} else {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			home = ""
+		}
+		xdg := os.Getenv("XDG_CONFIG_HOME")
+		if xdg == "" && home != "" {
+			xdg = filepath.Join(home, ".config")
+		}
+		if xdg != "" {
+			paths = append(paths, filepath.Join(xdg, "git", "config"))
+		}
+		if home != "" {
+			paths = append(paths, filepath.Join(home, ".gitconfig"))
+		}
 	}
 
-	paths := []string{}
-	home, err := os.UserHomeDir()
-	if err == nil && home != "" {
-		paths = append(paths, filepath.Join(home, ".gitconfig"))
+	if gitDir != "" {
+		paths = append(paths, filepath.Join(commonGitDir(gitDir), "config"))
 	}
+	return paths
+}
 
-	xdg := os.Getenv("XDG_CONFIG_HOME")
-	if xdg == "" && home != "" {
-		xdg = filepath.Join(home, ".config")
+// commandConfigEntries reads the GIT_CONFIG_COUNT, GIT_CONFIG_KEY_<n> and
+// GIT_CONFIG_VALUE_<n> variables, which git applies after every file.
+func commandConfigEntries() []gitConfigEntry {
+	count, err := strconv.Atoi(strings.TrimSpace(os.Getenv("GIT_CONFIG_COUNT")))
+	if err != nil || count <= 0 {
+		return nil
 	}
-	if xdg != "" {
-		paths = append(paths, filepath.Join(xdg, "git", "config"))
+	entries := []gitConfigEntry{}
+	for i := 0; i < count; i++ {
+		name := os.Getenv(fmt.Sprintf("GIT_CONFIG_KEY_%d", i))
+		first := strings.Index(name, ".")
+		last := strings.LastIndex(name, ".")
+		if first <= 0 || last == len(name)-1 {
+			continue
+		}
+		entries = append(entries, gitConfigEntry{
+			Section:    strings.ToLower(name[:first]),
+			Subsection: strings.TrimPrefix(name[first:last], "."),
+			Key:        strings.ToLower(name[last+1:]),
+			Value:      os.Getenv(fmt.Sprintf("GIT_CONFIG_VALUE_%d", i)),
+		})
 	}
+	return entries
+}
 
-	return paths
+// consumingGitDir returns the .git directory of the repository asm runs in,
+// found from $GIT_DIR or by walking up from the working directory, or "" when
+// there is none. Its local config and includeIf conditions apply as they
+// would to git commands run there.
+func consumingGitDir() string {
+	if gitDir := os.Getenv("GIT_DIR"); gitDir != "" {
+		if absolute, err := filepath.Abs(gitDir); err == nil {
+			return absolute
+		}
+		return gitDir
+	}
+	dir, err := os.Getwd()
+	if err != nil {
+		return ""
+	}
+	for {
+		candidate := filepath.Join(dir, ".git")
+		if info, err := os.Stat(candidate); err == nil {
+			if info.IsDir() {
+				return candidate
+			}
+			if linked := readGitDirFile(candidate); linked != "" {
+				return linked
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// readGitDirFile follows a "gitdir: <path>" file, as worktrees and
+// submodules use in place of a .git directory.
+func readGitDirFile(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	target, ok := strings.CutPrefix(strings.TrimSpace(string(data)), "gitdir:")
+	if !ok {
+		return ""
+	}
+	target = strings.TrimSpace(target)
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(filepath.Dir(path), target)
+	}
+	return filepath.Clean(target)
+}
+
+// commonGitDir returns the directory holding the shared config of gitDir,
+// which differs from gitDir for linked worktrees.
+func commonGitDir(gitDir string) string {
+	data, err := os.ReadFile(filepath.Join(gitDir, "commondir"))
+	if err != nil {
+		return gitDir
+	}
+	common := strings.TrimSpace(string(data))
+	if !filepath.IsAbs(common) {
+		common = filepath.Join(gitDir, common)
+	}
+	return filepath.Clean(common)
 }
 
-func parseGitConfigFile(path string, seen map[string]struct{}) ([]urlRewrite, error) {
+// gitConfigReader parses config files for the repository at gitDir, which
+// decides the includeIf conditions.
+type gitConfigReader struct {
+	gitDir string
+	seen   map[string]struct{}
+}
+
+func (reader gitConfigReader) parseFile(path string) ([]gitConfigEntry, error) {
 	if path == "" {
 		return nil, nil
 	}
@@ -85,10 +235,10 @@ func parseGitConfigFile(path string, seen map[string]struct{}) ([]urlRewrite, er
 	if err != nil {
 		absolute = path
 	}
-	if _, ok := seen[absolute]; ok {
+	if _, ok := reader.seen[absolute]; ok {
 		return nil, nil
 	}
-	seen[absolute] = struct{}{}
+	reader.seen[absolute] = struct{}{}
 
 	file, err := os.Open(absolute)
 	if err != nil {
@@ -99,7 +249,7 @@ func parseGitConfigFile(path string, seen map[string]struct{}) ([]urlRewrite, er
 	}
 	defer file.Close()
 
-	var rules []urlRewrite
+	var entries []gitConfigEntry
 	currentSection := ""
 	currentSubsection := ""
 	baseDir := filepath.Dir(absolute)
@@ -121,31 +271,33 @@ func parseGitConfigFile(path string, seen map[string]struct{}) ([]urlRewrite, er
 			continue
 		}
 
-		switch currentSection {
-		case "url":
-			if key == "insteadof" {
-				rules = append(rules, urlRewrite{Base: currentSubsection, InsteadOf: value})
-			}
-		case "include":
-			if key == "path" && value != "" {
+		if currentSection == "include" || currentSection == "includeif" {
+			if key == "path" && value != "" && (currentSection == "include" || reader.includeIfMatches(currentSubsection, baseDir)) {
 				includePath, err := resolveIncludePath(value, baseDir)
 				if err != nil {
 					return nil, err
 				}
-				included, err := parseGitConfigFile(includePath, seen)
+				included, err := reader.parseFile(includePath)
 				if err != nil {
 					return nil, err
 				}
-				rules = append(rules, included...)
+				entries = append(entries, included...)
 			}
+			continue
 		}
+		entries = append(entries, gitConfigEntry{
+			Section:    currentSection,
+			Subsection: currentSubsection,
+			Key:        key,
+			Value:      value,
+		})
 	}
 
 	if err := scanner.Err(); err != nil {
 		return nil, err
 	}
 
-	return rules, nil
+	return entries, nil
 }
 
 func parseSectionHeader(line string) (string, string, bool) {
@@ -238,3 +390,130 @@ func resolveIncludePath(value string, baseDir string) (string, error) {
 	}
 	return pathValue, nil
 }
+
+// includeIfMatches reports whether an [includeIf "<condition>"] section
+// applies. gitdir: and gitdir/i: match the consuming repository's .git
+// directory and onbranch: its checked-out branch; other conditions never
+// match, as with a git that does not know them.
+func (reader gitConfigReader) includeIfMatches(condition string, baseDir string) bool {
+	kind, pattern, ok := strings.Cut(condition, ":")
+	if !ok || reader.gitDir == "" {
+		return false
+	}
+	switch kind {
+	case "gitdir", "gitdir/i":
+		foldCase := kind == "gitdir/i"
+		pattern = gitdirPattern(pattern, baseDir)
+		for _, candidate := range gitdirCandidates(reader.gitDir) {
+			if wildmatch(pattern, candidate, foldCase) {
+				return true
+			}
+		}
+		return false
+	case "onbranch":
+		branch := currentBranch(reader.gitDir)
+		if branch == "" {
+			return false
+		}
+		if strings.HasSuffix(pattern, "/") {
+			pattern += "**"
+		}
+		return wildmatch(pattern, branch, false)
+	default:
+		return false
+	}
+}
+
+// gitdirPattern expands a gitdir: pattern the way git does: ~/ is the home
+// directory, ./ the directory of the config file, a pattern that is not
+// absolute matches at any depth, and a trailing slash matches everything
+// below it.
+func gitdirPattern(pattern string, baseDir string) string {
+	switch {
+	case strings.HasPrefix(pattern, "~/"):
+		if home, err := os.UserHomeDir(); err == nil {
+			pattern = filepath.ToSlash(home) + pattern[1:]
+		}
+	case strings.HasPrefix(pattern, "./"):
+		pattern = filepath.ToSlash(baseDir) + pattern[1:]
+	}
+	if !strings.HasPrefix(pattern, "/") && !isWindowsDrivePath(pattern) {
+		pattern = "**/" + pattern
+	}
+	if strings.HasSuffix(pattern, "/") {
+		pattern += "**"
+	}
+	return pattern
+}
+
+func isWindowsDrivePath(path string) bool {
+	return len(path) >= 3 && path[1] == ':' && path[2] == '/'
+}
+
+// gitdirCandidates returns gitDir as given and with symlinks resolved; git
+// matches either.
+func gitdirCandidates(gitDir string) []string {
+	candidates := []string{filepath.ToSlash(gitDir)}
+	if resolved, err := filepath.EvalSymlinks(gitDir); err == nil && resolved != gitDir {
+		candidates = append(candidates, filepath.ToSlash(resolved))
+	}
+	return candidates
+}
+
+func currentBranch(gitDir string) string {
+	data, err := os.ReadFile(filepath.Join(gitDir, "HEAD"))
+	if err != nil {
+		return ""
+	}
+	ref, ok := strings.CutPrefix(strings.TrimSpace(string(data)), "ref: refs/heads/")
+	if !ok {
+		return ""
+	}
+	return ref
+}
+
+// wildmatch matches name against a git wildmatch pattern with path
+// semantics: * and ? stop at slashes, ** crosses them.
+func wildmatch(pattern string, name string, foldCase bool) bool {
+	var expr strings.Builder
+	if foldCase {
+		expr.WriteString("(?i)")
+	}
+	expr.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '*':
+			if strings.HasPrefix(pattern[i:], "**/") {
+				expr.WriteString("(?:.*/)?")
+				i += 2
+			} else if strings.HasPrefix(pattern[i:], "**") {
+				expr.WriteString(".*")
+				i++
+			} else {
+				expr.WriteString("[^/]*")
+			}
+		case '?':
+			expr.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end == -1 {
+				expr.WriteString(regexp.QuoteMeta("["))
+				continue
+			}
+			class := pattern[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			expr.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	expr.WriteString("$")
+	matcher, err := regexp.Compile(expr.String())
+	if err != nil {
+		return false
+	}
+	return matcher.MatchString(name)
+}
@@ -67,3 +67,91 @@ func TestApplyInsteadOfHonorsGitConfigGlobal(t *testing.T) {
 		t.Fatalf("expected rewritten url, got %q", got)
 	}
 }
+
+// isolateGitConfig points git's system and global config at files under a
+// fresh home and returns it.
+func isolateGitConfig(t *testing.T) string {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "xdg"))
+	t.Setenv("GIT_CONFIG_GLOBAL", "")
+	t.Setenv("GIT_CONFIG_SYSTEM", filepath.Join(home, "system-gitconfig"))
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "")
+	t.Setenv("GIT_CONFIG_COUNT", "")
+	t.Setenv("GIT_DIR", "")
+	return home
+}
+
+func assertRewrite(t *testing.T, origin string, want string) {
+	t.Helper()
+	got, _, err := applyInsteadOf(origin)
+	if err != nil {
+		t.Fatalf("applyInsteadOf: %v", err)
+	}
+	if got != want {
+		t.Fatalf("expected %q to rewrite to %q, got %q", origin, want, got)
+	}
+}
+
+func TestApplyInsteadOfReadsSystemAndLocalConfig(t *testing.T) {
+	home := isolateGitConfig(t)
+	writeFile(t, home, "system-gitconfig", "[url \"ssh://git@mirror.example.com/\"]\n\tinsteadOf = https://github.com/\n")
+	project := filepath.Join(home, "project")
+	writeFile(t, project, ".git/config", "[url \"ssh://git@internal.example.com/\"]\n\tinsteadOf = https://github.com/acme/\n")
+	writeFile(t, project, ".git/HEAD", "ref: refs/heads/main\n")
+	if err := os.MkdirAll(filepath.Join(project, "nested"), 0o755); err != nil {
+		t.Fatalf("mkdir nested: %v", err)
+	}
+	t.Chdir(filepath.Join(project, "nested"))
+
+	assertRewrite(t, "https://github.com/org/repo", "ssh://git@mirror.example.com/org/repo")
+	assertRewrite(t, "https://github.com/acme/repo", "ssh://git@internal.example.com/repo")
+
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	assertRewrite(t, "https://github.com/org/repo", "https://github.com/org/repo")
+
+	t.Setenv("GIT_CONFIG_COUNT", "1")
+	t.Setenv("GIT_CONFIG_KEY_0", "url.ssh://git@env.example.com/.insteadOf")
+	t.Setenv("GIT_CONFIG_VALUE_0", "https://github.com/")
+	assertRewrite(t, "https://github.com/org/repo", "ssh://git@env.example.com/org/repo")
+}
+
+func TestApplyInsteadOfHonorsIncludeIf(t *testing.T) {
+	home := isolateGitConfig(t)
+	writeFile(t, home, ".gitconfig", "[includeIf \"gitdir:~/work/\"]\n\tpath = work.gitconfig\n"+
+		"[includeIf \"gitdir/i:**/CLIENT/.git\"]\n\tpath = client.gitconfig\n"+
+		"[includeIf \"onbranch:release/\"]\n\tpath = release.gitconfig\n")
+	writeFile(t, home, "work.gitconfig", "[url \"ssh://git@work.example.com/\"]\n\tinsteadOf = https://github.com/\n")
+	writeFile(t, home, "client.gitconfig", "[url \"ssh://git@client.example.com/\"]\n\tinsteadOf = https://github.com/client/\n")
+	writeFile(t, home, "release.gitconfig", "[url \"ssh://git@release.example.com/\"]\n\tinsteadOf = https://github.com/release/\n")
+
+	work := filepath.Join(home, "work", "project")
+	writeFile(t, work, ".git/HEAD", "ref: refs/heads/release/1.0\n")
+	client := filepath.Join(home, "personal", "client")
+	writeFile(t, client, ".git/HEAD", "ref: refs/heads/main\n")
+
+	t.Chdir(work)
+	assertRewrite(t, "https://github.com/org/repo", "ssh://git@work.example.com/org/repo")
+	assertRewrite(t, "https://github.com/release/repo", "ssh://git@release.example.com/repo")
+	assertRewrite(t, "https://github.com/client/repo", "ssh://git@work.example.com/client/repo")
+
+	t.Chdir(client)
+	assertRewrite(t, "https://github.com/org/repo", "https://github.com/org/repo")
+	assertRewrite(t, "https://github.com/client/repo", "ssh://git@client.example.com/repo")
+	assertRewrite(t, "https://github.com/release/repo", "https://github.com/release/repo")
+}
+
+func TestApplyInsteadOfIgnoresPushInsteadOf(t *testing.T) {
+	home := isolateGitConfig(t)
+	writeFile(t, home, ".gitconfig", "[url \"git@github.com:\"]\n\tpushInsteadOf = https://github.com/\n")
+	t.Chdir(home)
+
+	assertRewrite(t, "https://github.com/org/repo", "https://github.com/org/repo")
+	rules, err := loadURLRewrites()
+	if err != nil {
+		t.Fatalf("loadURLRewrites: %v", err)
+	}
+	if len(rules) != 1 || !rules[0].Push {
+		t.Fatalf("expected one push rule, got %+v", rules)
+	}
+}
@@ -0,0 +1,163 @@
+package gitstore
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/tidwall/jsonc"
+
+	"github.com/jmmarotta/agent_skills_manager/internal/manifest"
+)
+
+const (
+	hostTokenEnvPrefix    = "ASM_TOKEN_"
+	hostUsernameEnvPrefix = "ASM_USERNAME_"
+	credentialsFileName   = "credentials"
+)
+
+// hostCredential is a token for one host in the credentials file. Provider
+// picks the username the host expects with a token; Username overrides it.
+type hostCredential struct {
+	Token    string `json:"token"`
+	Username string `json:"username,omitempty"`
+	Provider string `json:"provider,omitempty"`
+}
+
+// tokenUsernames are the usernames each provider expects alongside an access
+// token over HTTPS.
+var tokenUsernames = map[string]string{
+	"github":    "x-access-token",
+	"gitlab":    "oauth2",
+	"gitea":     "oauth2",
+	"bitbucket": "x-token-auth",
+}
+
+// hostTokenAuth returns the token configured for exactly host, from
+// $ASM_TOKEN_<HOST> or the credentials file, so a token is only ever sent to
+// the server it was issued by. The last value names where the token came
+// from, and is empty when host has none.
+func hostTokenAuth(host string) (string, string, string, error) {
+	suffix := hostEnvSuffix(host)
+	if token := os.Getenv(hostTokenEnvPrefix + suffix); token != "" {
+		username := os.Getenv(hostUsernameEnvPrefix + suffix)
+		if username == "" {
+			username = defaultTokenUsername(host, "")
+		}
+		return username, token, hostTokenEnvPrefix + suffix, nil
+	}
+
+	credentials, err := loadHostCredentials()
+	if err != nil {
+		return "", "", "", err
+	}
+	credential, ok := credentials[strings.ToLower(host)]
+	if !ok || credential.Token == "" {
+		return "", "", "", nil
+	}
+	username := credential.Username
+	if username == "" {
+		username = defaultTokenUsername(host, credential.Provider)
+	}
+	path, _ := credentialsPath()
+	return username, credential.Token, path, nil
+}
+
+// hostTokensConfigured reports whether any per-host token is set, in the
+// environment or the credentials file. Once one is, the generic ASM_GIT_*
+// credentials are no longer sent to every other host.
+func hostTokensConfigured() (bool, error) {
+	for _, entry := range os.Environ() {
+		name, value, _ := strings.Cut(entry, "=")
+		if strings.HasPrefix(name, hostTokenEnvPrefix) && value != "" {
+			return true, nil
+		}
+	}
+	credentials, err := loadHostCredentials()
+	if err != nil {
+		return false, err
+	}
+	for _, credential := range credentials {
+		if credential.Token != "" {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// hostEnvSuffix turns a host name into the suffix of its environment
+// variables: upper-case, with every other character replaced by "_", so
+// git.example.com becomes GIT_EXAMPLE_COM.
+func hostEnvSuffix(host string) string {
+	return strings.Map(func(char rune) rune {
+		switch {
+		case char >= 'a' && char <= 'z':
+			return char - 'a' + 'A'
+		case char >= 'A' && char <= 'Z', char >= '0' && char <= '9':
+			return char
+		default:
+			return '_'
+		}
+	}, host)
+}
+
+// defaultTokenUsername picks the username for a token from provider, or else
+// guesses the provider from the host name.
+func defaultTokenUsername(host string, provider string) string {
+	if username, ok := tokenUsernames[strings.ToLower(provider)]; ok {
+		return username
+	}
+	host = strings.ToLower(host)
+	for _, name := range []string{"gitlab", "gitea", "bitbucket"} {
+		if strings.Contains(host, name) {
+			return tokenUsernames[name]
+		}
+	}
+	return tokenUsernames["github"]
+}
+
+// credentialsPath is the per-host credentials file next to the global
+// manifest.
+func credentialsPath() (string, error) {
+	root, err := manifest.GlobalRoot()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(root, credentialsFileName), nil
+}
+
+// loadHostCredentials reads the credentials file, keyed by lower-case host.
+// It refuses a file that other users can read.
+func loadHostCredentials() (map[string]hostCredential, error) {
+	path, err := credentialsPath()
+	if err != nil {
+		return nil, nil
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm()&0o077 != 0 {
+		return nil, fmt.Errorf("credentials file %s has mode %04o; run chmod 600 %s", path, info.Mode().Perm(), path)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var parsed map[string]hostCredential
+	if err := json.Unmarshal(jsonc.ToJSON(data), &parsed); err != nil {
+		return nil, fmt.Errorf("parse credentials file %s: %w", path, err)
+	}
+	credentials := make(map[string]hostCredential, len(parsed))
+	for host, credential := range parsed {
+		credentials[strings.ToLower(host)] = credential
+	}
+	return credentials, nil
+}
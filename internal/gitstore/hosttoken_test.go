@@ -0,0 +1,114 @@
+package gitstore
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+)
+
+func isolateHTTPAuth(t *testing.T) string {
+	root := t.TempDir()
+	t.Setenv("HOME", root)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(root, "xdg"))
+	t.Setenv("GIT_CONFIG_GLOBAL", filepath.Join(root, "gitconfig"))
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	t.Setenv("NETRC", filepath.Join(root, "netrc"))
+	for _, key := range []string{"ASM_GITHUB_TOKEN", "GITHUB_TOKEN", "GH_TOKEN", "ASM_GIT_TOKEN", "ASM_GIT_USERNAME", "ASM_GIT_PASSWORD"} {
+		t.Setenv(key, "")
+	}
+	return root
+}
+
+func basicAuthFor(t *testing.T, origin string) *githttp.BasicAuth {
+	t.Helper()
+	access, err := ResolveRemoteAccess(origin)
+	if err != nil {
+		t.Fatalf("ResolveRemoteAccess %s: %v", origin, err)
+	}
+	if access.Auth == nil {
+		return nil
+	}
+	auth, ok := access.Auth.(*githttp.BasicAuth)
+	if !ok {
+		t.Fatalf("expected basic auth for %s, got %T", origin, access.Auth)
+	}
+	return auth
+}
+
+func TestHostTokenEnvOnlyGoesToItsHost(t *testing.T) {
+	isolateHTTPAuth(t)
+	t.Setenv("ASM_TOKEN_GHE_EXAMPLE_COM", "ghe-token")
+
+	auth := basicAuthFor(t, "https://ghe.example.com/org/repo")
+	if auth == nil || auth.Username != "x-access-token" || auth.Password != "ghe-token" {
+		t.Fatalf("expected GitHub Enterprise token, got %+v", auth)
+	}
+	if auth := basicAuthFor(t, "https://gitlab.example.com/org/repo"); auth != nil {
+		t.Fatalf("expected no credentials for another host, got %+v", auth)
+	}
+
+	t.Setenv("ASM_USERNAME_GHE_EXAMPLE_COM", "robot")
+	if auth := basicAuthFor(t, "https://ghe.example.com/org/repo"); auth.Username != "robot" {
+		t.Fatalf("expected username override, got %q", auth.Username)
+	}
+}
+
+func TestHostTokenCredentialsFile(t *testing.T) {
+	root := isolateHTTPAuth(t)
+	writeFile(t, root, "xdg/asm/credentials", `{
+  // self-hosted GitLab
+  "GitLab.Example.com": {"token": "gl-token"},
+  "code.example.com": {"token": "gitea-token", "provider": "gitea"},
+  "ghe.example.com": {"token": "ghe-token", "username": "robot"},
+}`)
+	path := filepath.Join(root, "xdg", "asm", "credentials")
+	if err := os.Chmod(path, 0o600); err != nil {
+		t.Fatalf("chmod: %v", err)
+	}
+
+	for origin, want := range map[string][2]string{
+		"https://gitlab.example.com/group/repo": {"oauth2", "gl-token"},
+		"https://code.example.com/org/repo":     {"oauth2", "gitea-token"},
+		"https://ghe.example.com/org/repo":      {"robot", "ghe-token"},
+	} {
+		auth := basicAuthFor(t, origin)
+		if auth == nil || auth.Username != want[0] || auth.Password != want[1] {
+			t.Fatalf("expected %v for %s, got %+v", want, origin, auth)
+		}
+	}
+	if auth := basicAuthFor(t, "https://github.com/org/repo"); auth != nil {
+		t.Fatalf("expected no credentials for an unlisted host, got %+v", auth)
+	}
+
+	if runtime.GOOS == "windows" {
+		return
+	}
+	if err := os.Chmod(path, 0o644); err != nil {
+		t.Fatalf("chmod: %v", err)
+	}
+	_, err := ResolveRemoteAccess("https://gitlab.example.com/group/repo")
+	if err == nil || !strings.Contains(err.Error(), "chmod 600") {
+		t.Fatalf("expected permissions error, got %v", err)
+	}
+}
+
+func TestGenericTokenIsScopedOnceHostTokensExist(t *testing.T) {
+	isolateHTTPAuth(t)
+	t.Setenv("ASM_GIT_TOKEN", "generic-token")
+
+	if auth := basicAuthFor(t, "https://code.example.com/org/repo"); auth == nil || auth.Password != "generic-token" {
+		t.Fatalf("expected the generic token without per-host tokens, got %+v", auth)
+	}
+
+	t.Setenv("ASM_TOKEN_GHE_EXAMPLE_COM", "ghe-token")
+	if auth := basicAuthFor(t, "https://ghe.example.com/org/repo"); auth == nil || auth.Password != "ghe-token" {
+		t.Fatalf("expected the per-host token, got %+v", auth)
+	}
+	if auth := basicAuthFor(t, "https://code.example.com/org/repo"); auth != nil {
+		t.Fatalf("expected the generic token to stop going to other hosts, got %+v", auth)
+	}
+}
@@ -0,0 +1,171 @@
+package gitstore
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"golang.org/x/net/http/httpproxy"
+)
+
+const (
+	caBundleEnv   = "ASM_CA_BUNDLE"
+	clientCertEnv = "ASM_CLIENT_CERT"
+	clientKeyEnv  = "ASM_CLIENT_KEY"
+)
+
+// httpSettings are the proxy, CA bundle and client certificate used for
+// every HTTPS request asm makes, to git remotes and to the skills API alike.
+type httpSettings struct {
+	// Proxy is git's http.proxy. When empty, HTTPS_PROXY and HTTP_PROXY
+	// apply; NO_PROXY applies either way.
+	Proxy      string
+	CABundle   []byte
+	ClientCert []byte
+	ClientKey  []byte
+}
+
+// httpOptions are httpSettings resolved for one remote, in the form go-git
+// takes them.
+type httpOptions struct {
+	CABundle   []byte
+	ClientCert []byte
+	ClientKey  []byte
+	Proxy      transport.ProxyOptions
+}
+
+// loadHTTPSettings reads the settings from the environment and the http.*
+// keys of the user's git config. $ASM_CA_BUNDLE, $ASM_CLIENT_CERT and
+// $ASM_CLIENT_KEY win over git's GIT_SSL_* variables, which win over
+// http.sslCAInfo, http.sslCert and http.sslKey. A client certificate file
+// may hold its key too.
+func loadHTTPSettings() (httpSettings, error) {
+	entries, err := loadGitConfig()
+	if err != nil {
+		return httpSettings{}, err
+	}
+	config := map[string]string{}
+	for _, entry := range entries {
+		if entry.Section == "http" && entry.Subsection == "" {
+			config[entry.Key] = entry.Value
+		}
+	}
+
+	settings := httpSettings{Proxy: config["proxy"]}
+	caPath := firstNonEmpty(os.Getenv(caBundleEnv), os.Getenv("GIT_SSL_CAINFO"), config["sslcainfo"])
+	certPath := firstNonEmpty(os.Getenv(clientCertEnv), os.Getenv("GIT_SSL_CERT"), config["sslcert"])
+	keyPath := firstNonEmpty(os.Getenv(clientKeyEnv), os.Getenv("GIT_SSL_KEY"), config["sslkey"], certPath)
+
+	if settings.CABundle, err = readHTTPFile(caPath, "CA bundle"); err != nil {
+		return httpSettings{}, err
+	}
+	if certPath != "" {
+		if settings.ClientCert, err = readHTTPFile(certPath, "client certificate"); err != nil {
+			return httpSettings{}, err
+		}
+		if settings.ClientKey, err = readHTTPFile(keyPath, "client key"); err != nil {
+			return httpSettings{}, err
+		}
+	}
+	return settings, nil
+}
+
+func readHTTPFile(path string, what string) ([]byte, error) {
+	if path == "" {
+		return nil, nil
+	}
+	path, err := resolveIncludePath(path, ".")
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", what, err)
+	}
+	return data, nil
+}
+
+// proxyFor returns the proxy for target, or nil to connect directly.
+func (settings httpSettings) proxyFor(target *url.URL) (*url.URL, error) {
+	config := httpproxy.FromEnvironment()
+	if settings.Proxy != "" {
+		proxy := settings.Proxy
+		if !strings.Contains(proxy, "://") {
+			proxy = "http://" + proxy
+		}
+		config.HTTPProxy = proxy
+		config.HTTPSProxy = proxy
+	}
+	return config.ProxyFunc()(target)
+}
+
+// optionsFor resolves settings for the remote at rawURL.
+func (settings httpSettings) optionsFor(rawURL string) (httpOptions, error) {
+	target, err := url.Parse(rawURL)
+	if err != nil {
+		return httpOptions{}, err
+	}
+	proxy, err := settings.proxyFor(target)
+	if err != nil {
+		return httpOptions{}, fmt.Errorf("proxy for %s: %w", target.Host, err)
+	}
+	options := httpOptions{
+		CABundle:   settings.CABundle,
+		ClientCert: settings.ClientCert,
+		ClientKey:  settings.ClientKey,
+	}
+	if proxy != nil {
+		options.Proxy.URL = proxy.String()
+	}
+	return options, nil
+}
+
+// NewHTTPClient returns a client for non-git HTTPS requests, such as the
+// skills API, that uses the same proxy, CA bundle and client certificate as
+// git remotes.
+func NewHTTPClient(timeout time.Duration) (*http.Client, error) {
+	settings, err := loadHTTPSettings()
+	if err != nil {
+		return nil, err
+	}
+	base, ok := http.DefaultTransport.(*http.Transport)
+	if !ok {
+		return nil, fmt.Errorf("unexpected default HTTP transport %T", http.DefaultTransport)
+	}
+	httpTransport := base.Clone()
+	httpTransport.Proxy = func(request *http.Request) (*url.URL, error) {
+		return settings.proxyFor(request.URL)
+	}
+
+	if len(settings.CABundle) > 0 || len(settings.ClientCert) > 0 {
+		tlsConfig := &tls.Config{}
+		if httpTransport.TLSClientConfig != nil {
+			tlsConfig = httpTransport.TLSClientConfig.Clone()
+		}
+		if len(settings.CABundle) > 0 {
+			roots, err := x509.SystemCertPool()
+			if err != nil {
+				roots = x509.NewCertPool()
+			}
+			if !roots.AppendCertsFromPEM(settings.CABundle) {
+				return nil, fmt.Errorf("CA bundle holds no PEM certificates")
+			}
+			tlsConfig.RootCAs = roots
+		}
+		if len(settings.ClientCert) > 0 {
+			certificate, err := tls.X509KeyPair(settings.ClientCert, settings.ClientKey)
+			if err != nil {
+				return nil, fmt.Errorf("load client certificate: %w", err)
+			}
+			tlsConfig.Certificates = []tls.Certificate{certificate}
+		}
+		httpTransport.TLSClientConfig = tlsConfig
+	}
+	return &http.Client{Transport: httpTransport, Timeout: timeout}, nil
+}
@@ -0,0 +1,162 @@
+package gitstore
+
+import (
+	"context"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const testRefHash = "0123456789abcdef0123456789abcdef01234567"
+
+// newTestGitTLSServer answers ref advertisements over TLS for any repo path.
+func newTestGitTLSServer(t *testing.T) *httptest.Server {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/search" {
+			_, _ = io.WriteString(w, `{"skills":[]}`)
+			return
+		}
+		if !strings.HasSuffix(r.URL.Path, "/info/refs") {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/x-git-upload-pack-advertisement")
+		pktLine := func(line string) string { return fmt.Sprintf("%04x%s", len(line)+4, line) }
+		_, _ = io.WriteString(w, pktLine("# service=git-upload-pack\n")+"0000"+
+			pktLine(testRefHash+" HEAD\x00symref=HEAD:refs/heads/main\n")+
+			pktLine(testRefHash+" refs/heads/main\n")+
+			pktLine(testRefHash+" refs/tags/v1.0.0\n")+"0000")
+	}))
+	// The untrusted-CA attempt fails the handshake; keep it out of the log.
+	server.Config.ErrorLog = log.New(io.Discard, "", 0)
+	server.StartTLS()
+	t.Cleanup(server.Close)
+	return server
+}
+
+// newTestConnectProxy tunnels every CONNECT to target and records the
+// requested hosts.
+func newTestConnectProxy(t *testing.T, target string) (*httptest.Server, func() []string) {
+	var mu sync.Mutex
+	var hosts []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodConnect {
+			http.Error(w, "CONNECT only", http.StatusMethodNotAllowed)
+			return
+		}
+		mu.Lock()
+		hosts = append(hosts, r.Host)
+		mu.Unlock()
+		upstream, err := net.Dial("tcp", target)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		client, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			upstream.Close()
+			return
+		}
+		_, _ = io.WriteString(client, "HTTP/1.1 200 Connection established\r\n\r\n")
+		go func() {
+			_, _ = io.Copy(upstream, client)
+			upstream.Close()
+		}()
+		_, _ = io.Copy(client, upstream)
+		client.Close()
+	}))
+	t.Cleanup(proxy.Close)
+	return proxy, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string{}, hosts...)
+	}
+}
+
+func isolateProxyEnv(t *testing.T) string {
+	root := isolateHTTPAuth(t)
+	for _, key := range []string{"HTTPS_PROXY", "https_proxy", "HTTP_PROXY", "http_proxy", "NO_PROXY", "no_proxy", "ASM_CA_BUNDLE", "GIT_SSL_CAINFO", "ASM_CLIENT_CERT", "ASM_CLIENT_KEY", "GIT_SSL_CERT", "GIT_SSL_KEY"} {
+		t.Setenv(key, "")
+	}
+	t.Setenv("ASM_GIT_RETRIES", "0")
+	return root
+}
+
+func writeServerCA(t *testing.T, dir string, server *httptest.Server) string {
+	writeFile(t, dir, "ca.pem", string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})))
+	return filepath.Join(dir, "ca.pem")
+}
+
+func TestListRemoteRefsUsesProxyAndCABundle(t *testing.T) {
+	root := isolateProxyEnv(t)
+	server := newTestGitTLSServer(t)
+	proxy, connects := newTestConnectProxy(t, server.Listener.Addr().String())
+	t.Setenv("HTTPS_PROXY", proxy.URL)
+
+	// The test certificate is issued for example.com; the proxy routes it to
+	// the local server.
+	origin := "https://example.com/org/repo"
+	if _, err := ListRemoteRefs(context.Background(), origin); err == nil {
+		t.Fatalf("expected the private CA to be untrusted without a bundle")
+	}
+
+	t.Setenv("ASM_CA_BUNDLE", writeServerCA(t, root, server))
+	refs, err := ListRemoteRefs(context.Background(), origin)
+	if err != nil {
+		t.Fatalf("ListRemoteRefs: %v", err)
+	}
+	if _, ok := refs.Tags["v1.0.0"]; !ok {
+		t.Fatalf("expected tag v1.0.0, got %v", refs.All)
+	}
+	if hosts := connects(); len(hosts) == 0 || hosts[len(hosts)-1] != "example.com:443" {
+		t.Fatalf("expected a CONNECT to example.com:443, got %v", hosts)
+	}
+}
+
+func TestHTTPClientUsesGitConfigProxyAndCA(t *testing.T) {
+	root := isolateProxyEnv(t)
+	server := newTestGitTLSServer(t)
+	proxy, connects := newTestConnectProxy(t, server.Listener.Addr().String())
+	caPath := writeServerCA(t, root, server)
+	proxyURL, err := url.Parse(proxy.URL)
+	if err != nil {
+		t.Fatalf("parse proxy url: %v", err)
+	}
+	writeFile(t, root, "gitconfig", fmt.Sprintf("[http]\n\tproxy = %s\n\tsslCAInfo = %s\n", proxyURL.Host, caPath))
+
+	client, err := NewHTTPClient(5 * time.Second)
+	if err != nil {
+		t.Fatalf("NewHTTPClient: %v", err)
+	}
+	resp, err := client.Get("https://example.com/api/search")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected status %s", resp.Status)
+	}
+	if hosts := connects(); len(hosts) != 1 || hosts[0] != "example.com:443" {
+		t.Fatalf("expected a CONNECT to example.com:443, got %v", hosts)
+	}
+
+	t.Setenv("NO_PROXY", "internal.example.com")
+	settings, err := loadHTTPSettings()
+	if err != nil {
+		t.Fatalf("loadHTTPSettings: %v", err)
+	}
+	direct, err := settings.proxyFor(&url.URL{Scheme: "https", Host: "git.internal.example.com"})
+	if err != nil || direct != nil {
+		t.Fatalf("expected NO_PROXY to bypass http.proxy, got %v, %v", direct, err)
+	}
+}
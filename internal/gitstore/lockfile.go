@@ -18,10 +18,11 @@ func ResolveRevision(repoPath string, origin string, version string, lock map[ma
 		lock = map[manifest.LockKey]string{}
 	}
 
-	repo, err := openRepo(repoPath)
+	repo, release, err := readRepo(repoPath)
 	if err != nil {
 		return "", false, err
 	}
+	defer release()
 
 	key := manifest.LockKey{Origin: origin, Version: version}
 	if semver.IsValid(version) {
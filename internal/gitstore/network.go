@@ -0,0 +1,154 @@
+package gitstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+
+	"github.com/jmmarotta/agent_skills_manager/internal/debug"
+)
+
+// networkOperation is a kind of remote git call with its own timeout.
+type networkOperation struct {
+	name           string
+	timeoutEnv     string
+	defaultTimeout time.Duration
+}
+
+var (
+	opClone    = networkOperation{name: "clone", timeoutEnv: "ASM_CLONE_TIMEOUT", defaultTimeout: 10 * time.Minute}
+	opFetch    = networkOperation{name: "fetch", timeoutEnv: "ASM_FETCH_TIMEOUT", defaultTimeout: 5 * time.Minute}
+	opLsRemote = networkOperation{name: "ls-remote", timeoutEnv: "ASM_LS_REMOTE_TIMEOUT", defaultTimeout: time.Minute}
+)
+
+const (
+	retriesEnv     = "ASM_GIT_RETRIES"
+	defaultRetries = 2
+	maxRetryDelay  = 30 * time.Second
+)
+
+// retryDelay is the wait before the first retry; each later one doubles it.
+var retryDelay = time.Second
+
+// timeout is how long one attempt of op may run: its environment variable
+// as a duration such as 90s, or the default. Zero disables the timeout.
+func (op networkOperation) timeout() (time.Duration, error) {
+	value := os.Getenv(op.timeoutEnv)
+	if value == "" {
+		return op.defaultTimeout, nil
+	}
+	timeout, err := time.ParseDuration(value)
+	if err != nil || timeout < 0 {
+		return 0, fmt.Errorf("invalid %s %q: expected a duration such as 90s", op.timeoutEnv, value)
+	}
+	return timeout, nil
+}
+
+// networkRetries is how many times a transient failure is retried:
+// $ASM_GIT_RETRIES, or two.
+func networkRetries() (int, error) {
+	value := os.Getenv(retriesEnv)
+	if value == "" {
+		return defaultRetries, nil
+	}
+	retries, err := strconv.Atoi(value)
+	if err != nil || retries < 0 {
+		return 0, fmt.Errorf("invalid %s %q: expected a number of retries", retriesEnv, value)
+	}
+	return retries, nil
+}
+
+// runNetwork runs one remote operation against origin under op's timeout,
+// retrying transient HTTP and SSH failures with exponential backoff. fn must
+// be safe to run again after a failed attempt. Cancelling ctx stops both the
+// attempt in flight and any further retries.
+func runNetwork(ctx context.Context, op networkOperation, origin string, fn func(context.Context) error) error {
+	timeout, err := op.timeout()
+	if err != nil {
+		return err
+	}
+	retries, err := networkRetries()
+	if err != nil {
+		return err
+	}
+
+	delay := retryDelay
+	for attempt := 0; ; attempt++ {
+		attemptCtx, cancel := ctx, context.CancelFunc(func() {})
+		if timeout > 0 {
+			attemptCtx, cancel = context.WithTimeout(ctx, timeout)
+		}
+		err = fn(attemptCtx)
+		timedOut := errors.Is(attemptCtx.Err(), context.DeadlineExceeded)
+		cancel()
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return fmt.Errorf("%s %s: %w", op.name, debug.SanitizeOrigin(origin), ctx.Err())
+		}
+		if timedOut {
+			err = fmt.Errorf("%s %s timed out after %s (set %s to allow longer): %w", op.name, debug.SanitizeOrigin(origin), timeout, op.timeoutEnv, context.DeadlineExceeded)
+		}
+		if attempt >= retries || !isTransient(err) {
+			return err
+		}
+		debug.Logf("retry %s origin=%s attempt=%d delay=%s err=%v", op.name, debug.SanitizeOrigin(origin), attempt+1, delay, err)
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%s %s: %w", op.name, debug.SanitizeOrigin(origin), ctx.Err())
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, maxRetryDelay)
+	}
+}
+
+// isTransient reports whether err is worth retrying: timeouts, dropped or
+// refused connections, and server-side HTTP errors. Authentication failures
+// and missing repositories are not.
+func isTransient(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	if errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNABORTED) ||
+		errors.Is(err, syscall.EPIPE) ||
+		errors.Is(err, syscall.ETIMEDOUT) {
+		return true
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return !dnsErr.IsNotFound
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	// go-git reports unexpected HTTP statuses without unwrapping them.
+	var unexpected *plumbing.UnexpectedError
+	if errors.As(err, &unexpected) {
+		var statusErr *githttp.Err
+		if errors.As(unexpected.Err, &statusErr) && statusErr.Response != nil {
+			status := statusErr.StatusCode()
+			return status >= http.StatusInternalServerError || status == http.StatusTooManyRequests || status == http.StatusRequestTimeout
+		}
+		return isTransient(unexpected.Err)
+	}
+	return false
+}
@@ -0,0 +1,99 @@
+package gitstore
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+)
+
+func useFastRetries(t *testing.T) {
+	t.Helper()
+	previous := retryDelay
+	retryDelay = time.Millisecond
+	t.Cleanup(func() { retryDelay = previous })
+	t.Setenv("ASM_GIT_RETRIES", "")
+}
+
+func TestRunNetworkRetriesTransientFailures(t *testing.T) {
+	useFastRetries(t)
+	calls := 0
+	err := runNetwork(context.Background(), opFetch, "https://example.com/repo", func(ctx context.Context) error {
+		calls++
+		if calls < 3 {
+			return syscall.ECONNRESET
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("expected success after retries, got %v", err)
+	}
+	if calls != 3 {
+		t.Fatalf("expected 3 attempts, got %d", calls)
+	}
+}
+
+func TestRunNetworkStopsOnPermanentFailure(t *testing.T) {
+	useFastRetries(t)
+	calls := 0
+	err := runNetwork(context.Background(), opFetch, "https://example.com/repo", func(ctx context.Context) error {
+		calls++
+		return transport.ErrAuthenticationRequired
+	})
+	if !errors.Is(err, transport.ErrAuthenticationRequired) || calls != 1 {
+		t.Fatalf("expected one failed attempt, got %d (%v)", calls, err)
+	}
+}
+
+func TestRunNetworkTimesOut(t *testing.T) {
+	useFastRetries(t)
+	t.Setenv("ASM_GIT_RETRIES", "0")
+	t.Setenv("ASM_LS_REMOTE_TIMEOUT", "20ms")
+	err := runNetwork(context.Background(), opLsRemote, "https://example.com/repo", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	if !errors.Is(err, context.DeadlineExceeded) || !strings.Contains(err.Error(), "ASM_LS_REMOTE_TIMEOUT") {
+		t.Fatalf("expected timeout naming ASM_LS_REMOTE_TIMEOUT, got %v", err)
+	}
+}
+
+func TestRunNetworkStopsWhenCancelled(t *testing.T) {
+	useFastRetries(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	calls := 0
+	err := runNetwork(ctx, opClone, "https://example.com/repo", func(ctx context.Context) error {
+		calls++
+		cancel()
+		return syscall.ECONNRESET
+	})
+	if !errors.Is(err, context.Canceled) || calls != 1 {
+		t.Fatalf("expected cancellation after one attempt, got %d (%v)", calls, err)
+	}
+}
+
+func TestIsTransientHTTPStatus(t *testing.T) {
+	statusErr := func(code int) error {
+		request, _ := http.NewRequest(http.MethodGet, "https://example.com/info/refs", nil)
+		return plumbing.NewUnexpectedError(&githttp.Err{Response: &http.Response{StatusCode: code, Request: request}})
+	}
+	if !isTransient(statusErr(http.StatusServiceUnavailable)) {
+		t.Fatalf("expected 503 to be transient")
+	}
+	if !isTransient(statusErr(http.StatusTooManyRequests)) {
+		t.Fatalf("expected 429 to be transient")
+	}
+	if isTransient(statusErr(http.StatusBadRequest)) {
+		t.Fatalf("expected 400 to be permanent")
+	}
+	if isTransient(transport.ErrRepositoryNotFound) {
+		t.Fatalf("expected a missing repository to be permanent")
+	}
+}
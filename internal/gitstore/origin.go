@@ -1,28 +1,137 @@
 package gitstore
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"golang.org/x/mod/module"
 
 	"github.com/jmmarotta/agent_skills_manager/internal/debug"
 	"github.com/jmmarotta/agent_skills_manager/internal/manifest"
+	"github.com/jmmarotta/agent_skills_manager/internal/workpool"
 )
 
 type OriginResolution struct {
-	Path         string
-	Rev          string
+	Path     string
+	Checkout string
+	Rev      string
+	// Signer is the verified signer fingerprint, for origins with a verify
+	// policy.
+	Signer       string
+	Subdirs      []string
 	UsingReplace bool
 	LockChanged  bool
 	Warning      string
 }
 
 type OriginPathsResult struct {
-	Paths       map[string]string
+	Paths       map[manifest.LockKey]string
+	Resolutions map[manifest.LockKey]OriginResolution
 	Warnings    []string
 	LockChanged bool
 }
 
-func ResolveOriginRevision(storeDir string, origin string, version string, replacePath string, lock map[manifest.LockKey]string, strict bool) (OriginResolution, error) {
+func ResolveOriginRevision(ctx context.Context, storeDir string, checkoutsDir string, origin string, version string, replacePath string, lock map[manifest.LockKey]string, strict bool) (OriginResolution, error) {
+	return resolveOriginRevision(ctx, storeDir, checkoutsDir, origin, version, replacePath, lock, strict, newStoreFetch(nil, false))
+}
+
+// storeFetch fetches each origin into the store at most once. Origins whose
+// versions are all locked fetch only the locked revisions, and not at all
+// when the store already has them. Offline, nothing is fetched.
+type storeFetch struct {
+	ensured map[string]bool
+	locked  map[string]map[string]string
+	offline bool
+}
+
+func newStoreFetch(locked map[string]map[string]string, offline bool) *storeFetch {
+	return &storeFetch{ensured: map[string]bool{}, locked: locked, offline: offline}
+}
+
+func (fetch *storeFetch) ensure(ctx context.Context, path string, origin string) error {
+	if fetch.ensured[origin] || fetch.offline {
+		return nil
+	}
+	var err error
+	if revs, ok := fetch.locked[origin]; ok {
+		if RevisionsPresent(path, revs) {
+			debug.Logf("skip fetch origin=%s; locked revisions in store", debug.SanitizeOrigin(origin))
+			fetch.ensured[origin] = true
+			return nil
+		}
+		err = EnsureRevisions(ctx, path, origin, revs)
+	} else {
+		err = EnsureRepo(ctx, path, origin)
+	}
+	if err != nil {
+		return err
+	}
+	fetch.ensured[origin] = true
+	return nil
+}
+
+// RevisionsPresent reports whether the store repo at path already resolves
+// every version in revs to its locked revision.
+func RevisionsPresent(path string, revs map[string]string) bool {
+	repo, release, err := readRepo(path)
+	if err != nil {
+		return false
+	}
+	defer release()
+	for version, rev := range revs {
+		if module.IsPseudoVersion(version) {
+			if _, err := repo.CommitObject(plumbing.NewHash(rev)); err != nil {
+				return false
+			}
+			continue
+		}
+		resolved, err := ResolveForVersion(repo, version)
+		if err != nil || resolved != rev {
+			return false
+		}
+	}
+	return true
+}
+
+// RequireStoredRevision fails unless the locked revision of origin at version
+// is already in the store repo at path. Offline installs resolve nothing else.
+func RequireStoredRevision(path string, origin string, version string, lock map[manifest.LockKey]string) error {
+	rev := lock[manifest.LockKey{Origin: origin, Version: version}]
+	if rev == "" {
+		return fmt.Errorf("%s %s is not locked; cannot resolve it offline", debug.SanitizeOrigin(origin), version)
+	}
+	if exists, err := CommitExists(path, rev); err != nil || !exists {
+		return fmt.Errorf("rev %s for origin %s not in store", rev, debug.SanitizeOrigin(origin))
+	}
+	return nil
+}
+
+// lockedRevisions groups the locked revisions of keys by origin, leaving out
+// origins with any version that is not locked yet.
+func lockedRevisions(keys []manifest.LockKey, lock map[manifest.LockKey]string) map[string]map[string]string {
+	locked := map[string]map[string]string{}
+	unlocked := map[string]bool{}
+	for _, key := range keys {
+		rev := lock[key]
+		if rev == "" {
+			unlocked[key.Origin] = true
+			continue
+		}
+		if locked[key.Origin] == nil {
+			locked[key.Origin] = map[string]string{}
+		}
+		locked[key.Origin][key.Version] = rev
+	}
+	for origin := range unlocked {
+		delete(locked, origin)
+	}
+	return locked
+}
+
+func resolveOriginRevision(ctx context.Context, storeDir string, checkoutsDir string, origin string, version string, replacePath string, lock map[manifest.LockKey]string, strict bool, fetch *storeFetch) (OriginResolution, error) {
 	if replacePath != "" {
 		if info, err := os.Stat(replacePath); err == nil && info.IsDir() {
 			rev, changed, err := ResolveRevision(replacePath, origin, version, lock, strict)
@@ -35,48 +144,190 @@ func ResolveOriginRevision(storeDir string, origin string, version string, repla
 				}, nil
 			}
 			warning := fmt.Sprintf("replace path for %s not usable (%v); falling back to remote", origin, err)
-			return resolveOriginFromStore(storeDir, origin, version, lock, strict, warning)
+			return resolveOriginFromStore(ctx, storeDir, checkoutsDir, origin, version, lock, strict, warning, fetch)
 		}
 		warning := fmt.Sprintf("replace path missing for %s (%s); falling back to remote", origin, replacePath)
-		return resolveOriginFromStore(storeDir, origin, version, lock, strict, warning)
+		return resolveOriginFromStore(ctx, storeDir, checkoutsDir, origin, version, lock, strict, warning, fetch)
 	}
 
-	return resolveOriginFromStore(storeDir, origin, version, lock, strict, "")
+	return resolveOriginFromStore(ctx, storeDir, checkoutsDir, origin, version, lock, strict, "", fetch)
 }
 
-func ResolveOrigins(storeDir string, origins map[string]string, replace map[string]string, lock map[manifest.LockKey]string, strict bool) (OriginPathsResult, error) {
-	result := OriginPathsResult{Paths: map[string]string{}}
-	for origin, version := range origins {
-		debug.Logf("resolve origin origin=%s version=%s", debug.SanitizeOrigin(origin), version)
-		replacePath := ""
-		if replace != nil {
-			replacePath = replace[origin]
+// ResolveOptions configures ResolveOrigins.
+type ResolveOptions struct {
+	StoreDir     string
+	CheckoutsDir string
+	Replace      map[string]string
+	Lock         map[manifest.LockKey]string
+	Strict       bool
+	// Subdirs limits each key's checkout to the listed directories.
+	Subdirs map[manifest.LockKey][]string
+	// Jobs bounds how many origins resolve at once; zero means
+	// workpool.DefaultJobs.
+	Jobs int
+	// Offline resolves only from the store and replace paths, never fetching.
+	Offline bool
+	// Verify requires each origin's resolved revisions to be signed by one
+	// of the policy's keys before they are checked out. Replace paths are
+	// not verified.
+	Verify map[string]manifest.VerifyPolicy
+}
+
+// ResolveOrigins resolves every origin/version pair to a directory that skills
+// can link into. Origins resolve concurrently, each fetched at most once into
+// the store however many of its versions are in use; cancelling ctx stops
+// every fetch in flight. Lock changes and warnings are merged in key order,
+// and every origin that failed is named in the returned OriginErrors.
+func ResolveOrigins(ctx context.Context, keys []manifest.LockKey, options ResolveOptions) (OriginPathsResult, error) {
+	result := OriginPathsResult{
+		Paths:       map[manifest.LockKey]string{},
+		Resolutions: map[manifest.LockKey]OriginResolution{},
+	}
+
+	origins := []string{}
+	byOrigin := map[string][]manifest.LockKey{}
+	for _, key := range keys {
+		if _, ok := byOrigin[key.Origin]; !ok {
+			origins = append(origins, key.Origin)
 		}
-		resolution, err := ResolveOriginRevision(storeDir, origin, version, replacePath, lock, strict)
-		if err != nil {
-			return result, fmt.Errorf("resolve origin %s: %w", debug.SanitizeOrigin(origin), err)
+		byOrigin[key.Origin] = append(byOrigin[key.Origin], key)
+	}
+	locked := lockedRevisions(keys, options.Lock)
+
+	type originOutcome struct {
+		resolutions   []OriginResolution
+		applyWarnings []string
+		lock          map[manifest.LockKey]string
+		err           *OriginError
+	}
+	outcomes := make([]originOutcome, len(origins))
+	workpool.Run(options.Jobs, len(origins), func(index int) {
+		origin := origins[index]
+		outcome := &outcomes[index]
+		// Each origin resolves against its own copy of its lock entries.
+		outcome.lock = map[manifest.LockKey]string{}
+		for _, key := range byOrigin[origin] {
+			if rev, ok := options.Lock[key]; ok {
+				outcome.lock[key] = rev
+			}
 		}
-		if resolution.Warning != "" {
-			result.Warnings = append(result.Warnings, resolution.Warning)
+		fetch := newStoreFetch(locked, options.Offline)
+		for _, key := range byOrigin[origin] {
+			debug.Logf("resolve origin origin=%s version=%s", debug.SanitizeOrigin(key.Origin), key.Version)
+			resolution, err := resolveOriginRevision(ctx, options.StoreDir, options.CheckoutsDir, key.Origin, key.Version, options.Replace[key.Origin], outcome.lock, options.Strict, fetch)
+			if policy, ok := options.Verify[key.Origin]; ok && err == nil && !resolution.UsingReplace {
+				resolution.Signer, err = VerifyRevision(resolution.Path, key.Version, resolution.Rev, policy)
+			}
+			if err == nil {
+				resolution.Subdirs = options.Subdirs[key]
+				var applyWarning string
+				applyWarning, err = ApplyOriginResolution(resolution)
+				outcome.applyWarnings = append(outcome.applyWarnings, applyWarning)
+			}
+			if err != nil {
+				outcome.err = &OriginError{Origin: key.Origin, Version: key.Version, Err: err}
+				return
+			}
+			outcome.resolutions = append(outcome.resolutions, resolution)
 		}
-		if resolution.LockChanged {
-			result.LockChanged = true
+	})
+
+	warned := map[string]bool{}
+	addWarning := func(warning string) {
+		if warning == "" || warned[warning] {
+			return
 		}
-		result.Paths[origin] = resolution.Path
-		applyWarning, err := ApplyOriginResolution(resolution)
-		if err != nil {
-			return result, err
+		warned[warning] = true
+		result.Warnings = append(result.Warnings, warning)
+	}
+	var failures OriginErrors
+	for index, origin := range origins {
+		outcome := outcomes[index]
+		if outcome.err != nil {
+			failures = append(failures, *outcome.err)
+			continue
 		}
-		if applyWarning != "" {
-			result.Warnings = append(result.Warnings, applyWarning)
+		for position, key := range byOrigin[origin] {
+			resolution := outcome.resolutions[position]
+			addWarning(resolution.Warning)
+			addWarning(outcome.applyWarnings[position])
+			if resolution.LockChanged {
+				result.LockChanged = true
+			}
+			if rev, ok := outcome.lock[key]; ok && options.Lock != nil {
+				options.Lock[key] = rev
+			}
+			result.Resolutions[key] = resolution
+			result.Paths[key] = resolution.SourcePath()
 		}
 	}
+	if len(failures) > 0 {
+		return result, failures
+	}
 	return result, nil
 }
 
-func resolveOriginFromStore(storeDir string, origin string, version string, lock map[manifest.LockKey]string, strict bool, warning string) (OriginResolution, error) {
+// OriginError is a failure to resolve one origin. Version is empty when the
+// whole origin failed, as during an update.
+type OriginError struct {
+	Origin  string
+	Version string
+	Err     error
+}
+
+func (failure OriginError) Error() string {
+	name := debug.SanitizeOrigin(failure.Origin)
+	if failure.Version != "" {
+		name += "@" + failure.Version
+	}
+	return fmt.Sprintf("resolve origin %s: %v", name, failure.Err)
+}
+
+func (failure OriginError) Unwrap() error {
+	return failure.Err
+}
+
+// OriginErrors collects every origin that failed in one pass.
+type OriginErrors []OriginError
+
+func (failures OriginErrors) Error() string {
+	if len(failures) == 1 {
+		return failures[0].Error()
+	}
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "%d origins failed:", len(failures))
+	for _, failure := range failures {
+		builder.WriteString("\n  ")
+		builder.WriteString(failure.Error())
+	}
+	return builder.String()
+}
+
+func (failures OriginErrors) Unwrap() []error {
+	errs := make([]error, 0, len(failures))
+	for _, failure := range failures {
+		errs = append(errs, failure)
+	}
+	return errs
+}
+
+// SourcePath is the directory skills link into: the replace path or the
+// exported checkout of the resolved revision.
+func (resolution OriginResolution) SourcePath() string {
+	if resolution.Checkout != "" {
+		return resolution.Checkout
+	}
+	return resolution.Path
+}
+
+func resolveOriginFromStore(ctx context.Context, storeDir string, checkoutsDir string, origin string, version string, lock map[manifest.LockKey]string, strict bool, warning string, fetch *storeFetch) (OriginResolution, error) {
 	path := RepoPath(storeDir, origin)
-	if err := EnsureRepo(path, origin); err != nil {
+	if fetch.offline {
+		if err := RequireStoredRevision(path, origin, version, lock); err != nil {
+			return OriginResolution{}, err
+		}
+	}
+	if err := fetch.ensure(ctx, path, origin); err != nil {
 		return OriginResolution{}, err
 	}
 
@@ -87,6 +338,7 @@ func resolveOriginFromStore(storeDir string, origin string, version string, lock
 
 	return OriginResolution{
 		Path:        path,
+		Checkout:    CheckoutPath(checkoutsDir, origin, rev),
 		Rev:         rev,
 		LockChanged: changed,
 		Warning:     warning,
@@ -107,6 +359,9 @@ func ApplyOriginResolution(resolution OriginResolution) (string, error) {
 		}
 		return "", nil
 	}
+	if resolution.Checkout != "" {
+		return "", ExportRevision(resolution.Path, resolution.Rev, resolution.Checkout, resolution.Subdirs)
+	}
 
 	return "", CheckoutRevision(resolution.Path, resolution.Rev)
 }
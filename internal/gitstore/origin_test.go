@@ -1,6 +1,7 @@
 package gitstore
 
 import (
+	"context"
 	"path/filepath"
 	"strings"
 	"testing"
@@ -28,7 +29,7 @@ func TestResolveOriginRevisionReplaceMissing(t *testing.T) {
 	replacePath := filepath.Join(t.TempDir(), "missing")
 	lock := map[manifest.LockKey]string{}
 
-	resolution, err := ResolveOriginRevision(storeDir, repoDir, "v1.0.0", replacePath, lock, true)
+	resolution, err := ResolveOriginRevision(context.Background(), storeDir, filepath.Join(storeDir, "checkouts"), repoDir, "v1.0.0", replacePath, lock, true)
 	if err != nil {
 		t.Fatalf("resolve: %v", err)
 	}
@@ -63,7 +64,7 @@ func TestResolveOriginRevisionReplaceNotUsable(t *testing.T) {
 	replacePath := t.TempDir()
 	lock := map[manifest.LockKey]string{}
 
-	resolution, err := ResolveOriginRevision(storeDir, repoDir, "v1.0.0", replacePath, lock, true)
+	resolution, err := ResolveOriginRevision(context.Background(), storeDir, filepath.Join(storeDir, "checkouts"), repoDir, "v1.0.0", replacePath, lock, true)
 	if err != nil {
 		t.Fatalf("resolve: %v", err)
 	}
@@ -1,11 +1,13 @@
 package gitstore
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/config"
 	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport"
 	"github.com/go-git/go-git/v5/storage/memory"
 
 	"github.com/jmmarotta/agent_skills_manager/internal/debug"
@@ -17,7 +19,7 @@ type RefIndex struct {
 	Tags     map[string]struct{}
 }
 
-func ListRemoteRefs(origin string) (RefIndex, error) {
+func ListRemoteRefs(ctx context.Context, origin string) (RefIndex, error) {
 	debug.Logf("list remote refs origin=%s", debug.SanitizeOrigin(origin))
 	access, err := ResolveRemoteAccess(origin)
 	if err != nil {
@@ -28,9 +30,9 @@ func ListRemoteRefs(origin string) (RefIndex, error) {
 		URLs: []string{access.URL},
 	})
 
-	refs, err := remote.List(&git.ListOptions{Auth: access.Auth})
+	refs, err := listRemote(ctx, remote, access, origin)
 	if err != nil {
-		return RefIndex{}, fmt.Errorf("list remote refs for %s: %w", debug.SanitizeOrigin(origin), err)
+		return RefIndex{}, err
 	}
 
 	index := RefIndex{
@@ -55,7 +57,7 @@ func ListRemoteRefs(origin string) (RefIndex, error) {
 	return index, nil
 }
 
-func RemoteHeadHash(origin string) (string, error) {
+func RemoteHeadHash(ctx context.Context, origin string) (string, error) {
 	debug.Logf("list remote head origin=%s", debug.SanitizeOrigin(origin))
 	access, err := ResolveRemoteAccess(origin)
 	if err != nil {
@@ -66,9 +68,9 @@ func RemoteHeadHash(origin string) (string, error) {
 		URLs: []string{access.URL},
 	})
 
-	refs, err := remote.List(&git.ListOptions{Auth: access.Auth})
+	refs, err := listRemote(ctx, remote, access, origin)
 	if err != nil {
-		return "", fmt.Errorf("list remote refs for %s: %w", debug.SanitizeOrigin(origin), err)
+		return "", err
 	}
 
 	var headRef *plumbing.Reference
@@ -100,3 +102,29 @@ func RemoteHeadHash(origin string) (string, error) {
 
 	return "", fmt.Errorf("remote head not found for %s", debug.SanitizeOrigin(origin))
 }
+
+func listRemote(ctx context.Context, remote *git.Remote, access RemoteAccess, origin string) ([]*plumbing.Reference, error) {
+	var refs []*plumbing.Reference
+	err := access.authenticate(ctx, func(auth transport.AuthMethod) error {
+		return runNetwork(ctx, opLsRemote, origin, func(ctx context.Context) error {
+			var err error
+			refs, err = remote.ListContext(ctx, access.listOptions(auth))
+			if err != nil {
+				return fmt.Errorf("list remote refs for %s: %w", debug.SanitizeOrigin(origin), err)
+			}
+			return nil
+		})
+	})
+	return refs, err
+}
+
+// listOptions lists refs with auth and access's proxy and TLS settings.
+func (access RemoteAccess) listOptions(auth transport.AuthMethod) *git.ListOptions {
+	return &git.ListOptions{
+		Auth:         auth,
+		CABundle:     access.http.CABundle,
+		ClientCert:   access.http.ClientCert,
+		ClientKey:    access.http.ClientKey,
+		ProxyOptions: access.http.Proxy,
+	}
+}
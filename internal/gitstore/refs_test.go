@@ -1,6 +1,7 @@
 package gitstore
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 	"testing"
@@ -36,7 +37,7 @@ func TestListRemoteRefs(t *testing.T) {
 	}
 	commit(t, repo, wt, "branch")
 
-	refs, err := ListRemoteRefs(repoDir)
+	refs, err := ListRemoteRefs(context.Background(), repoDir)
 	if err != nil {
 		t.Fatalf("ListRemoteRefs: %v", err)
 	}
@@ -62,7 +63,7 @@ func TestRemoteHeadHash(t *testing.T) {
 	}
 	commitHash := commit(t, repo, wt, "init")
 
-	headHash, err := RemoteHeadHash(repoDir)
+	headHash, err := RemoteHeadHash(context.Background(), repoDir)
 	if err != nil {
 		t.Fatalf("RemoteHeadHash: %v", err)
 	}
@@ -1,9 +1,11 @@
 package gitstore
 
 import (
+	"errors"
 	"fmt"
 	"net/url"
 	"os"
+	"os/user"
 	"regexp"
 	"strings"
 
@@ -11,17 +13,33 @@ import (
 	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
 	gitssh "github.com/go-git/go-git/v5/plumbing/transport/ssh"
 	"golang.org/x/crypto/ssh"
+
+	"github.com/jmmarotta/agent_skills_manager/internal/debug"
+	"github.com/jmmarotta/agent_skills_manager/internal/envflag"
 )
 
 type RemoteAccess struct {
 	URL  string
 	Auth transport.AuthMethod
+	// Source names where Auth came from, such as "GITHUB_TOKEN" or
+	// ".netrc". It is empty when no credentials apply.
+	Source string
+	// Rewritten reports whether a url.<base>.insteadOf rule changed URL.
+	Rewritten bool
+
+	// credentials are the git credential helpers asked when the remote
+	// requires auth that no other source supplied.
+	credentials *credentialHelpers
+	// http holds the proxy and TLS settings for HTTP remotes.
+	http httpOptions
 }
 
 type urlCredentials struct {
 	Username    string
 	Password    string
 	HasUserinfo bool
+	// Source names where the userinfo came from.
+	Source string
 }
 
 type remoteInfo struct {
@@ -48,94 +66,249 @@ func ResolveRemoteAccess(origin string) (RemoteAccess, error) {
 		return RemoteAccess{URL: stripped}, nil
 	}
 
-	rewritten, _, err := applyInsteadOf(stripped)
+	rewritten, insteadOf, err := applyInsteadOf(stripped)
 	if err != nil {
 		return RemoteAccess{}, err
 	}
+	// An insteadOf base may carry credentials of its own. They are sent as
+	// auth like the origin's, never kept in the URL that gets printed.
+	if insteadOf {
+		var rewriteCreds urlCredentials
+		if rewritten, rewriteCreds, err = stripCredentials(rewritten); err != nil {
+			return RemoteAccess{}, err
+		}
+		if rewriteCreds.HasUserinfo && !creds.HasUserinfo {
+			rewriteCreds.Source = "insteadOf URL userinfo"
+			creds = rewriteCreds
+		}
+	}
 
-	auth, err := resolveAuth(rewritten, creds)
+	info, err := parseRemoteInfo(rewritten)
 	if err != nil {
 		return RemoteAccess{}, err
 	}
+	// ~/.ssh/config is read once, for both the keys and the real host.
+	var sshConfig sshHostConfig
+	if info.Scheme == "ssh" {
+		if sshConfig, err = loadSSHHostConfig(info.Host); err != nil {
+			return RemoteAccess{}, fmt.Errorf("read ssh config: %w", err)
+		}
+	}
 
-	return RemoteAccess{URL: rewritten, Auth: auth}, nil
-}
-
-func resolveAuth(origin string, creds urlCredentials) (transport.AuthMethod, error) {
-	info, err := parseRemoteInfo(origin)
+	auth, source, err := resolveAuth(info, creds, sshConfig)
 	if err != nil {
-		return nil, err
+		return RemoteAccess{}, err
+	}
+	rewritten, err = applySSHHostConfig(rewritten, sshConfig)
+	if err != nil {
+		return RemoteAccess{}, err
 	}
+	access := RemoteAccess{URL: rewritten, Auth: auth, Source: source, Rewritten: insteadOf}
+	if scheme, ok := schemeForOrigin(rewritten); ok && (scheme == "http" || scheme == "https") {
+		settings, err := loadHTTPSettings()
+		if err != nil {
+			return RemoteAccess{}, err
+		}
+		if access.http, err = settings.optionsFor(rewritten); err != nil {
+			return RemoteAccess{}, err
+		}
+	}
+	if auth == nil {
+		if scheme, ok := schemeForOrigin(rewritten); ok && (scheme == "http" || scheme == "https") {
+			access.credentials, err = loadCredentialHelpers(rewritten)
+			if err != nil {
+				return RemoteAccess{}, err
+			}
+			if access.credentials != nil {
+				access.Source = fmt.Sprintf("credential helper %s, asked if the server requires auth", strings.Join(access.credentials.Helpers, ", "))
+			}
+		}
+	}
+
+	return access, nil
+}
+
+// resolveAuth returns the credentials for the origin described by info and
+// where they came from. sshConfig holds the host's ~/.ssh/config settings.
+func resolveAuth(info remoteInfo, creds urlCredentials, sshConfig sshHostConfig) (transport.AuthMethod, string, error) {
 	if !info.IsRemote {
-		return nil, nil
+		return nil, "", nil
 	}
 
 	switch info.Scheme {
 	case "http", "https":
 		return resolveHTTPAuth(info.Host, creds)
 	case "ssh":
-		return resolveSSHAuth(info.User)
+		return resolveSSHAuth(info.Host, info.User, sshConfig)
 	default:
-		return nil, nil
+		return nil, "", nil
 	}
 }
 
-func resolveHTTPAuth(host string, creds urlCredentials) (transport.AuthMethod, error) {
+func resolveHTTPAuth(host string, creds urlCredentials) (transport.AuthMethod, string, error) {
 	if creds.HasUserinfo {
-		return &githttp.BasicAuth{Username: creds.Username, Password: creds.Password}, nil
+		return &githttp.BasicAuth{Username: creds.Username, Password: creds.Password}, creds.Source, nil
 	}
-	if username, password, ok := githubTokenAuth(host); ok {
-		return &githttp.BasicAuth{Username: username, Password: password}, nil
+	if username, password, source, err := hostTokenAuth(host); err != nil {
+		return nil, "", err
+	} else if source != "" {
+		return &githttp.BasicAuth{Username: username, Password: password}, source, nil
+	}
+	if username, password, source := githubTokenAuth(host); source != "" {
+		return &githttp.BasicAuth{Username: username, Password: password}, source, nil
 	}
 	if username, password, ok, err := netrcCredentials(host); err != nil {
-		return nil, err
+		return nil, "", err
 	} else if ok {
-		return &githttp.BasicAuth{Username: username, Password: password}, nil
+		path, _ := netrcPath()
+		return &githttp.BasicAuth{Username: username, Password: password}, path, nil
 	}
-	if username, password, ok := envBasicAuth(); ok {
-		return &githttp.BasicAuth{Username: username, Password: password}, nil
+	if username, password, source := envBasicAuth(); source != "" {
+		scoped, err := hostTokensConfigured()
+		if err != nil {
+			return nil, "", err
+		}
+		if !scoped {
+			return &githttp.BasicAuth{Username: username, Password: password}, source, nil
+		}
+		debug.Logf("skip %s for host=%s: per-host tokens are configured", source, host)
 	}
-	return nil, nil
+	return nil, "", nil
 }
 
-func resolveSSHAuth(user string) (transport.AuthMethod, error) {
-	auth, err := gitssh.NewSSHAgentAuth(user)
+const (
+	sshKeyEnv           = "ASM_SSH_KEY"
+	sshKeyPassphraseEnv = "ASM_SSH_KEY_PASSPHRASE"
+)
+
+// resolveSSHAuth offers the key in $ASM_SSH_KEY, or the host's IdentityFile
+// keys from its ~/.ssh/config settings, ahead of the SSH agent's keys, and
+// checks host keys against the host's UserKnownHostsFile when it names one.
+func resolveSSHAuth(host string, username string, config sshHostConfig) (transport.AuthMethod, string, error) {
+	if username == "" {
+		username = config.User
+	}
+	if username == "" {
+		if current, err := user.Current(); err == nil {
+			username = current.Username
+		}
+	}
+
+	signers, sources, err := sshKeySigners(config.IdentityFiles)
 	if err != nil {
-		return nil, fmt.Errorf("ssh agent auth: %w", err)
+		return nil, "", err
+	}
+	agentAuth, agentErr := gitssh.NewSSHAgentAuth(username)
+	if len(signers) == 0 && agentErr != nil {
+		return nil, "", fmt.Errorf("ssh agent auth: %w", agentErr)
+	}
+	if agentErr == nil {
+		sources = append(sources, "ssh agent")
+	}
+
+	auth := &gitssh.PublicKeysCallback{
+		User: username,
+		Callback: func() ([]ssh.Signer, error) {
+			if agentAuth == nil {
+				return signers, nil
+			}
+			agentSigners, err := agentAuth.Callback()
+			if err != nil {
+				if len(signers) > 0 {
+					debug.Logf("ssh agent signers: %v", err)
+					return signers, nil
+				}
+				return nil, err
+			}
+			return append(append([]ssh.Signer{}, signers...), agentSigners...), nil
+		},
 	}
-	if isEnvTrue("ASM_SSH_INSECURE") {
+	switch {
+	case envflag.Enabled("ASM_SSH_INSECURE"):
 		auth.HostKeyCallback = ssh.InsecureIgnoreHostKey()
+	case len(config.KnownHostsFiles) > 0:
+		auth.HostKeyCallback, err = gitssh.NewKnownHostsCallback(config.KnownHostsFiles...)
+		if err != nil {
+			return nil, "", fmt.Errorf("known hosts for %s: %w", host, err)
+		}
+	}
+	return auth, strings.Join(sources, ", "), nil
+}
+
+// sshKeySigners loads $ASM_SSH_KEY, which must be usable, or else the
+// identity files that exist and can be decrypted with
+// $ASM_SSH_KEY_PASSPHRASE. It also names where each signer came from.
+func sshKeySigners(identityFiles []string) ([]ssh.Signer, []string, error) {
+	passphrase := os.Getenv(sshKeyPassphraseEnv)
+	if keyPath := os.Getenv(sshKeyEnv); keyPath != "" {
+		signer, err := loadSSHKey(keyPath, passphrase)
+		if err != nil {
+			return nil, nil, fmt.Errorf("load %s %s: %w", sshKeyEnv, keyPath, err)
+		}
+		return []ssh.Signer{signer}, []string{sshKeyEnv + " " + keyPath}, nil
 	}
-	return auth, nil
+
+	signers := []ssh.Signer{}
+	sources := []string{}
+	for _, keyPath := range identityFiles {
+		signer, err := loadSSHKey(keyPath, passphrase)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				debug.Logf("skip identity file %s: %v", keyPath, err)
+			}
+			continue
+		}
+		signers = append(signers, signer)
+		sources = append(sources, "IdentityFile "+keyPath)
+	}
+	return signers, sources, nil
+}
+
+func loadSSHKey(keyPath string, passphrase string) (ssh.Signer, error) {
+	data, err := os.ReadFile(keyPath)
+	if err != nil {
+		return nil, err
+	}
+	signer, err := ssh.ParsePrivateKey(data)
+	var missing *ssh.PassphraseMissingError
+	if errors.As(err, &missing) && passphrase != "" {
+		signer, err = ssh.ParsePrivateKeyWithPassphrase(data, []byte(passphrase))
+	}
+	return signer, err
 }
 
-func githubTokenAuth(host string) (string, string, bool) {
+// githubTokenAuth returns a GitHub token for github.com and the variable
+// it came from.
+func githubTokenAuth(host string) (string, string, string) {
 	if !strings.EqualFold(host, "github.com") {
-		return "", "", false
+		return "", "", ""
 	}
 	for _, key := range []string{"ASM_GITHUB_TOKEN", "GITHUB_TOKEN", "GH_TOKEN"} {
 		if token := os.Getenv(key); token != "" {
-			return "x-access-token", token, true
+			return "x-access-token", token, key
 		}
 	}
-	return "", "", false
+	return "", "", ""
 }
 
-func envBasicAuth() (string, string, bool) {
+// envBasicAuth returns the ASM_GIT_* credentials and the variables they came
+// from. They are sent to any host, so resolveHTTPAuth drops them once
+// per-host tokens are configured.
+func envBasicAuth() (string, string, string) {
 	if token := os.Getenv("ASM_GIT_TOKEN"); token != "" {
 		username := os.Getenv("ASM_GIT_USERNAME")
 		if username == "" {
 			username = "x-access-token"
 		}
-		return username, token, true
+		return username, token, "ASM_GIT_TOKEN"
 	}
 
 	username := os.Getenv("ASM_GIT_USERNAME")
 	password := os.Getenv("ASM_GIT_PASSWORD")
 	if username != "" || password != "" {
-		return username, password, true
+		return username, password, "ASM_GIT_USERNAME/ASM_GIT_PASSWORD"
 	}
-	return "", "", false
+	return "", "", ""
 }
 
 func parseRemoteInfo(origin string) (remoteInfo, error) {
@@ -189,6 +362,7 @@ func stripCredentials(origin string) (string, urlCredentials, error) {
 		}
 		if creds.Username != "" || creds.Password != "" {
 			creds.HasUserinfo = true
+			creds.Source = "URL userinfo"
 		}
 	}
 
@@ -227,16 +401,3 @@ func isRemoteOrigin(origin string) bool {
 	}
 	return scpLikePattern.MatchString(origin)
 }
-
-func isEnvTrue(name string) bool {
-	value := strings.TrimSpace(os.Getenv(name))
-	if value == "" {
-		return false
-	}
-	switch strings.ToLower(value) {
-	case "1", "true", "yes", "on":
-		return true
-	default:
-		return false
-	}
-}
@@ -2,11 +2,13 @@ package gitstore
 
 import (
 	"fmt"
+	"os"
 
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/plumbing"
 
 	"github.com/jmmarotta/agent_skills_manager/internal/debug"
+	"github.com/jmmarotta/agent_skills_manager/internal/filelock"
 )
 
 func OriginURL(repoRoot string) (string, bool, error) {
@@ -47,28 +49,58 @@ func HeadHash(repoRoot string) (string, error) {
 }
 
 func ResolveForRefAt(repoPath string, ref string) (Resolved, error) {
-	repo, err := openRepo(repoPath)
+	repo, release, err := readRepo(repoPath)
 	if err != nil {
 		return Resolved{}, err
 	}
+	defer release()
 
 	return ResolveForRef(repo, ref)
 }
 
+// ResolveForBranchAt resolves the tip of branch, preferring the
+// remote-tracking ref that UpdateRepo fetches over a local branch.
+func ResolveForBranchAt(repoPath string, branch string) (Resolved, error) {
+	repo, release, err := readRepo(repoPath)
+	if err != nil {
+		return Resolved{}, err
+	}
+	defer release()
+
+	for _, name := range []plumbing.ReferenceName{
+		plumbing.NewRemoteReferenceName("origin", branch),
+		plumbing.NewBranchReferenceName(branch),
+	} {
+		reference, err := repo.Reference(name, true)
+		if err != nil {
+			continue
+		}
+		commit, err := repo.CommitObject(reference.Hash())
+		if err != nil {
+			return Resolved{}, fmt.Errorf("load commit for %s: %w", name, err)
+		}
+		return resolveFromCommit(repo, commit)
+	}
+
+	return Resolved{}, fmt.Errorf("branch %q not found", branch)
+}
+
 func ResolveForVersionAt(repoPath string, version string) (string, error) {
-	repo, err := openRepo(repoPath)
+	repo, release, err := readRepo(repoPath)
 	if err != nil {
 		return "", err
 	}
+	defer release()
 
 	return ResolveForVersion(repo, version)
 }
 
 func CommitExists(repoPath string, hash string) (bool, error) {
-	repo, err := openRepo(repoPath)
+	repo, release, err := readRepo(repoPath)
 	if err != nil {
 		return false, err
 	}
+	defer release()
 
 	if _, err := repo.CommitObject(plumbing.NewHash(hash)); err != nil {
 		return false, nil
@@ -96,6 +128,35 @@ func CheckoutRevision(repoPath string, rev string) error {
 	return nil
 }
 
+// readRepo opens the store repo at repoPath holding a shared lock on the
+// lock file EnsureRepo, EnsureRevisions and UpdateRepo hold exclusively, so a
+// repo is never replaced or fetched into while another process reads it. A
+// repo asm never cloned, such as a replace directory, has no lock file and is
+// read unlocked. Call release once done with the repo.
+func readRepo(repoPath string) (*git.Repository, func(), error) {
+	release, err := readLock(repoPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	repo, err := openRepo(repoPath)
+	if err != nil {
+		release()
+		return nil, nil, err
+	}
+	return repo, release, nil
+}
+
+func readLock(repoPath string) (func(), error) {
+	lock, err := filelock.AcquireShared(repoPath + ".lock")
+	if err != nil {
+		if os.IsNotExist(err) {
+			return func() {}, nil
+		}
+		return nil, err
+	}
+	return func() { lock.Release() }, nil
+}
+
 func openRepo(repoPath string) (*git.Repository, error) {
 	repo, err := git.PlainOpen(repoPath)
 	if err != nil {
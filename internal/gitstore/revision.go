@@ -1,6 +1,7 @@
 package gitstore
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -60,20 +61,23 @@ func ResolveForRef(repo *git.Repository, ref string) (Resolved, error) {
 	return resolveFromCommit(repo, commit)
 }
 
-func ResolveForRemoteHeadAt(repoPath string, origin string) (Resolved, error) {
+func ResolveForRemoteHeadAt(ctx context.Context, repoPath string, origin string) (Resolved, error) {
 	if origin == "" {
 		return Resolved{}, fmt.Errorf("origin is required")
 	}
 
-	repo, err := openRepo(repoPath)
+	// The remote is asked first so the repo lock is not held across the
+	// network.
+	headHash, err := RemoteHeadHash(ctx, origin)
 	if err != nil {
 		return Resolved{}, err
 	}
 
-	headHash, err := RemoteHeadHash(origin)
+	repo, release, err := readRepo(repoPath)
 	if err != nil {
 		return Resolved{}, err
 	}
+	defer release()
 
 	commit, err := repo.CommitObject(plumbing.NewHash(headHash))
 	if err != nil {
@@ -83,6 +87,39 @@ func ResolveForRemoteHeadAt(repoPath string, origin string) (Resolved, error) {
 	return resolveFromCommit(repo, commit)
 }
 
+// ListSemverTags returns the semver tags in the repo at repoPath, as named.
+func ListSemverTags(repoPath string) ([]string, error) {
+	repo, release, err := readRepo(repoPath)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	iter, err := repo.Tags()
+	if err != nil {
+		return nil, fmt.Errorf("list tags: %w", err)
+	}
+
+	tags := []string{}
+	// go-git iterators return io.EOF when exhausted.
+	for {
+		ref, err := iter.Next()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("iterate tags: %w", err)
+		}
+		name := ref.Name().Short()
+		if semver.IsValid(name) && !module.IsPseudoVersion(name) {
+			tags = append(tags, name)
+		}
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		return semver.Compare(tags[i], tags[j]) < 0
+	})
+	return tags, nil
+}
+
 func ResolveForVersion(repo *git.Repository, version string) (string, error) {
 	debug.Logf("resolve version=%q", version)
 	if module.IsPseudoVersion(version) {
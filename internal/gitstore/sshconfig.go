@@ -0,0 +1,233 @@
+package gitstore
+
+import (
+	"bufio"
+	"net"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// sshHostConfig is what ~/.ssh/config says about one host alias.
+type sshHostConfig struct {
+	HostName        string
+	User            string
+	Port            string
+	IdentityFiles   []string
+	KnownHostsFiles []string
+}
+
+// loadSSHHostConfig reads the settings for alias from ~/.ssh/config. As in
+// ssh, the first value found for a keyword wins, except IdentityFile, which
+// accumulates. Match blocks are skipped.
+func loadSSHHostConfig(alias string) (sshHostConfig, error) {
+	home, err := os.UserHomeDir()
+	if err != nil || home == "" {
+		return sshHostConfig{}, nil
+	}
+	config := sshHostConfig{}
+	if err := parseSSHConfigFile(filepath.Join(home, ".ssh", "config"), alias, home, &config, map[string]struct{}{}); err != nil {
+		return sshHostConfig{}, err
+	}
+
+	tokens := strings.NewReplacer("%d", home, "%h", firstNonEmpty(config.HostName, alias), "%r", config.User, "%%", "%")
+	expand := func(value string) string {
+		value = tokens.Replace(value)
+		if value == "~" {
+			return home
+		}
+		if strings.HasPrefix(value, "~/") {
+			return filepath.Join(home, value[2:])
+		}
+		if !filepath.IsAbs(value) {
+			return filepath.Join(home, ".ssh", value)
+		}
+		return value
+	}
+	for index, file := range config.IdentityFiles {
+		config.IdentityFiles[index] = expand(file)
+	}
+	for index, file := range config.KnownHostsFiles {
+		config.KnownHostsFiles[index] = expand(file)
+	}
+	return config, nil
+}
+
+func parseSSHConfigFile(configPath string, alias string, home string, config *sshHostConfig, seen map[string]struct{}) error {
+	if _, ok := seen[configPath]; ok {
+		return nil
+	}
+	seen[configPath] = struct{}{}
+
+	file, err := os.Open(configPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer file.Close()
+
+	// Settings before the first Host line apply to every host.
+	matching := true
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		keyword, args := parseSSHConfigLine(scanner.Text())
+		if keyword == "" {
+			continue
+		}
+		switch keyword {
+		case "host":
+			matching = sshHostMatches(args, alias)
+			continue
+		case "match":
+			matching = false
+			continue
+		}
+		if !matching || len(args) == 0 {
+			continue
+		}
+
+		switch keyword {
+		case "include":
+			for _, pattern := range args {
+				if strings.HasPrefix(pattern, "~/") {
+					pattern = filepath.Join(home, pattern[2:])
+				} else if !filepath.IsAbs(pattern) {
+					pattern = filepath.Join(home, ".ssh", pattern)
+				}
+				matches, _ := filepath.Glob(pattern)
+				for _, included := range matches {
+					if err := parseSSHConfigFile(included, alias, home, config, seen); err != nil {
+						return err
+					}
+				}
+			}
+		case "hostname":
+			if config.HostName == "" {
+				config.HostName = args[0]
+			}
+		case "user":
+			if config.User == "" {
+				config.User = args[0]
+			}
+		case "port":
+			if config.Port == "" {
+				config.Port = args[0]
+			}
+		case "identityfile":
+			config.IdentityFiles = append(config.IdentityFiles, args[0])
+		case "userknownhostsfile":
+			if config.KnownHostsFiles == nil {
+				config.KnownHostsFiles = append([]string{}, args...)
+			}
+		}
+	}
+	return scanner.Err()
+}
+
+// parseSSHConfigLine splits a config line into its lower-case keyword and
+// arguments, honoring "keyword=value" and double-quoted arguments.
+func parseSSHConfigLine(line string) (string, []string) {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return "", nil
+	}
+	end := strings.IndexAny(line, " \t=")
+	if end == -1 {
+		return strings.ToLower(line), nil
+	}
+	keyword := line[:end]
+	rest := strings.TrimPrefix(strings.TrimSpace(line[end:]), "=")
+
+	var args []string
+	var current strings.Builder
+	quoted := false
+	inArg := false
+	for _, char := range strings.TrimSpace(rest) {
+		switch {
+		case char == '"':
+			quoted = !quoted
+			inArg = true
+		case (char == ' ' || char == '\t') && !quoted:
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(char)
+			inArg = true
+		}
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+	return strings.ToLower(keyword), args
+}
+
+// sshHostMatches reports whether alias matches a Host line's patterns: at
+// least one pattern matches and no negated pattern does.
+func sshHostMatches(patterns []string, alias string) bool {
+	alias = strings.ToLower(alias)
+	matched := false
+	for _, pattern := range patterns {
+		negated := strings.HasPrefix(pattern, "!")
+		pattern = strings.ToLower(strings.TrimPrefix(pattern, "!"))
+		if ok, _ := path.Match(pattern, alias); !ok {
+			continue
+		}
+		if negated {
+			return false
+		}
+		matched = true
+	}
+	return matched
+}
+
+// applySSHHostConfig rewrites an SSH origin whose host is a ~/.ssh/config
+// alias to the real host, port and user in config, as ssh would connect.
+func applySSHHostConfig(origin string, config sshHostConfig) (string, error) {
+	info, err := parseRemoteInfo(origin)
+	if err != nil || info.Scheme != "ssh" {
+		return origin, err
+	}
+	if config.HostName == "" && config.Port == "" && (config.User == "" || info.User != "") {
+		return origin, nil
+	}
+
+	var parsed *url.URL
+	if scpLikePattern.MatchString(origin) && !strings.Contains(origin, "://") {
+		userHost, repoPath, _ := strings.Cut(origin, ":")
+		user, _, _ := strings.Cut(userHost, "@")
+		parsed = &url.URL{Scheme: "ssh", User: url.User(user), Host: info.Host, Path: "/" + strings.TrimPrefix(repoPath, "/")}
+	} else {
+		parsed, err = url.Parse(origin)
+		if err != nil {
+			return origin, err
+		}
+	}
+
+	host := firstNonEmpty(config.HostName, parsed.Hostname())
+	port := firstNonEmpty(parsed.Port(), config.Port)
+	if port != "" && port != "22" {
+		parsed.Host = net.JoinHostPort(host, port)
+	} else {
+		parsed.Host = host
+	}
+	if parsed.User == nil && config.User != "" {
+		parsed.User = url.User(config.User)
+	}
+	return parsed.String(), nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,120 @@
+package gitstore
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"path/filepath"
+	"testing"
+
+	gitssh "github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	"golang.org/x/crypto/ssh"
+)
+
+const testSSHConfig = `Host github-work
+	HostName github.com
+	Port 2222
+	IdentityFile ~/.ssh/work_key
+	UserKnownHostsFile ~/.ssh/work_known_hosts
+
+Match host *
+	User matched
+
+Host *
+	User fallback
+	HostName ignored.example.com
+	IdentityFile id_default
+`
+
+func TestApplySSHHostConfigResolvesAlias(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	writeFile(t, home, ".ssh/config", testSSHConfig)
+
+	applyAlias := func(origin string, alias string) string {
+		t.Helper()
+		config, err := loadSSHHostConfig(alias)
+		if err != nil {
+			t.Fatalf("loadSSHHostConfig: %v", err)
+		}
+		got, err := applySSHHostConfig(origin, config)
+		if err != nil {
+			t.Fatalf("applySSHHostConfig: %v", err)
+		}
+		return got
+	}
+	if got := applyAlias("git@github-work:org/repo", "github-work"); got != "ssh://git@github.com:2222/org/repo" {
+		t.Fatalf("expected alias to resolve, got %q", got)
+	}
+	if got := applyAlias("ssh://example.com/org/repo", "example.com"); got != "ssh://fallback@ignored.example.com/org/repo" {
+		t.Fatalf("expected wildcard settings, got %q", got)
+	}
+
+	config, err := loadSSHHostConfig("github-work")
+	if err != nil {
+		t.Fatalf("loadSSHHostConfig: %v", err)
+	}
+	wantKeys := []string{filepath.Join(home, ".ssh", "work_key"), filepath.Join(home, ".ssh", "id_default")}
+	if len(config.IdentityFiles) != 2 || config.IdentityFiles[0] != wantKeys[0] || config.IdentityFiles[1] != wantKeys[1] {
+		t.Fatalf("expected identity files %v, got %v", wantKeys, config.IdentityFiles)
+	}
+	if config.User != "fallback" {
+		t.Fatalf("expected user from Host *, got %q", config.User)
+	}
+	if len(config.KnownHostsFiles) != 1 || config.KnownHostsFiles[0] != filepath.Join(home, ".ssh", "work_known_hosts") {
+		t.Fatalf("unexpected known hosts files %v", config.KnownHostsFiles)
+	}
+}
+
+func TestResolveSSHAuthUsesKeyFileWithoutAgent(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("SSH_AUTH_SOCK", "")
+	t.Setenv("ASM_SSH_INSECURE", "")
+	writeFile(t, home, ".ssh/config", testSSHConfig)
+	writeFile(t, home, ".ssh/work_known_hosts", "")
+
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	block, err := ssh.MarshalPrivateKeyWithPassphrase(privateKey, "deploy", []byte("hunter2"))
+	if err != nil {
+		t.Fatalf("marshal key: %v", err)
+	}
+	writeFile(t, home, "deploy_key", string(pem.EncodeToMemory(block)))
+	t.Setenv("ASM_SSH_KEY", filepath.Join(home, "deploy_key"))
+	t.Setenv("ASM_SSH_KEY_PASSPHRASE", "hunter2")
+
+	access, err := ResolveRemoteAccess("git@github-work:org/repo")
+	if err != nil {
+		t.Fatalf("ResolveRemoteAccess: %v", err)
+	}
+	if access.URL != "ssh://git@github.com:2222/org/repo" {
+		t.Fatalf("unexpected url %q", access.URL)
+	}
+	auth, ok := access.Auth.(*gitssh.PublicKeysCallback)
+	if !ok {
+		t.Fatalf("expected public key auth, got %T", access.Auth)
+	}
+	if auth.User != "git" || auth.HostKeyCallback == nil {
+		t.Fatalf("expected user git with a known hosts callback, got %q", auth.User)
+	}
+	signers, err := auth.Callback()
+	if err != nil {
+		t.Fatalf("signers: %v", err)
+	}
+	signer, err := ssh.NewSignerFromKey(privateKey)
+	if err != nil {
+		t.Fatalf("signer: %v", err)
+	}
+	if len(signers) != 1 || !bytes.Equal(signers[0].PublicKey().Marshal(), signer.PublicKey().Marshal()) {
+		t.Fatalf("expected the deploy key to be offered")
+	}
+
+	t.Setenv("ASM_SSH_KEY_PASSPHRASE", "")
+	if _, err := ResolveRemoteAccess("git@github-work:org/repo"); err == nil {
+		t.Fatalf("expected an error for an encrypted key without a passphrase")
+	}
+}
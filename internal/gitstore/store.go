@@ -16,3 +16,13 @@ func RepoKey(origin string) string {
 func RepoPath(storeDir string, origin string) string {
 	return filepath.Join(storeDir, RepoKey(origin))
 }
+
+// CheckoutsPath holds the exported revisions of origin. Checkouts live apart
+// from the store so a shared store only ever holds object databases.
+func CheckoutsPath(checkoutsDir string, origin string) string {
+	return filepath.Join(checkoutsDir, RepoKey(origin))
+}
+
+func CheckoutPath(checkoutsDir string, origin string, rev string) string {
+	return filepath.Join(CheckoutsPath(checkoutsDir, origin), rev)
+}
@@ -0,0 +1,226 @@
+package gitstore
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/go-git/go-git/v5/plumbing"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+
+	"github.com/jmmarotta/agent_skills_manager/internal/debug"
+	"github.com/jmmarotta/agent_skills_manager/internal/manifest"
+)
+
+const (
+	openPGPSignatureHeader = "-----BEGIN PGP SIGNATURE-----"
+	sshSignatureHeader     = "-----BEGIN SSH SIGNATURE-----"
+	sshSignatureMagic      = "SSHSIG"
+	// sshSignatureNamespace is the namespace git signs objects under.
+	sshSignatureNamespace = "git"
+)
+
+// VerifyRevision checks that version of the store repo at repoPath, locked to
+// rev, is signed by one of policy's keys and returns the signer's
+// fingerprint. A semver version is verified through its annotated tag when
+// the tag is signed, otherwise through the commit; a pseudo-version is
+// verified through the commit.
+func VerifyRevision(repoPath string, version string, rev string, policy manifest.VerifyPolicy) (string, error) {
+	keyring, err := os.ReadFile(policy.Keyring)
+	if err != nil {
+		return "", fmt.Errorf("read keyring: %w", err)
+	}
+	repo, release, err := readRepo(repoPath)
+	if err != nil {
+		return "", err
+	}
+	defer release()
+
+	if semver.IsValid(version) && !module.IsPseudoVersion(version) {
+		ref, err := repo.Reference(plumbing.NewTagReferenceName(version), true)
+		if err == nil {
+			if tag, err := repo.TagObject(ref.Hash()); err == nil && tag.PGPSignature != "" {
+				// The tag name is part of what was signed, so an older
+				// signed tag pushed under a newer name is refused.
+				if tag.Name != version {
+					return "", fmt.Errorf("tag %s holds the signed tag %s", version, tag.Name)
+				}
+				if tag.Target.String() != rev {
+					return "", fmt.Errorf("tag %s points at %s, not the locked rev %s", version, tag.Target, rev)
+				}
+				payload, err := unsignedPayload(tag.EncodeWithoutSignature)
+				if err != nil {
+					return "", err
+				}
+				return verifySigner(payload, tag.PGPSignature, keyring, policy, "tag "+version)
+			}
+		}
+	}
+
+	commit, err := repo.CommitObject(plumbing.NewHash(rev))
+	if err != nil {
+		return "", fmt.Errorf("load commit %s: %w", rev, err)
+	}
+	if commit.PGPSignature == "" {
+		return "", fmt.Errorf("commit %s for %s is not signed", rev, version)
+	}
+	payload, err := unsignedPayload(commit.EncodeWithoutSignature)
+	if err != nil {
+		return "", err
+	}
+	return verifySigner(payload, commit.PGPSignature, keyring, policy, "commit "+rev)
+}
+
+// unsignedPayload returns the bytes a tag or commit signature covers.
+func unsignedPayload(encode func(plumbing.EncodedObject) error) ([]byte, error) {
+	encoded := &plumbing.MemoryObject{}
+	if err := encode(encoded); err != nil {
+		return nil, err
+	}
+	reader, err := encoded.Reader()
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
+
+func verifySigner(payload []byte, signature string, keyring []byte, policy manifest.VerifyPolicy, subject string) (string, error) {
+	var fingerprint string
+	var err error
+	switch {
+	case strings.HasPrefix(signature, openPGPSignatureHeader):
+		fingerprint, err = verifyOpenPGPSignature(payload, signature, keyring)
+	case strings.HasPrefix(signature, sshSignatureHeader):
+		fingerprint, err = verifySSHSignature(payload, signature, keyring)
+	default:
+		err = fmt.Errorf("unsupported signature format")
+	}
+	if err != nil {
+		return "", fmt.Errorf("verify %s signature: %w", subject, err)
+	}
+	if !policy.Allows(fingerprint) {
+		return "", fmt.Errorf("%s is signed by %s, which is not an allowed key", subject, fingerprint)
+	}
+	debug.Logf("verified %s signer=%s", subject, fingerprint)
+	return fingerprint, nil
+}
+
+func verifyOpenPGPSignature(payload []byte, signature string, keyring []byte) (string, error) {
+	entities, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(keyring))
+	if err != nil {
+		return "", fmt.Errorf("read OpenPGP keyring: %w", err)
+	}
+	signer, err := openpgp.CheckArmoredDetachedSignature(entities, bytes.NewReader(payload), strings.NewReader(signature), nil)
+	if err != nil {
+		return "", err
+	}
+	return strings.ToUpper(hex.EncodeToString(signer.PrimaryKey.Fingerprint)), nil
+}
+
+// sshSignature is the body of an armored SSHSIG signature, after the magic
+// preamble.
+type sshSignature struct {
+	Version       uint32
+	PublicKey     []byte
+	Namespace     string
+	Reserved      string
+	HashAlgorithm string
+	Signature     []byte
+}
+
+// sshSignedData is what an SSHSIG signature signs, after the magic preamble.
+type sshSignedData struct {
+	Namespace     string
+	Reserved      string
+	HashAlgorithm string
+	Hash          []byte
+}
+
+// verifySSHSignature checks an SSHSIG signature as written by git with
+// gpg.format=ssh, and that its key is listed in keyring.
+func verifySSHSignature(payload []byte, signature string, keyring []byte) (string, error) {
+	block, _ := pem.Decode([]byte(signature))
+	if block == nil || block.Type != "SSH SIGNATURE" || !bytes.HasPrefix(block.Bytes, []byte(sshSignatureMagic)) {
+		return "", fmt.Errorf("malformed SSH signature")
+	}
+	var parsed sshSignature
+	if err := ssh.Unmarshal(block.Bytes[len(sshSignatureMagic):], &parsed); err != nil {
+		return "", fmt.Errorf("parse SSH signature: %w", err)
+	}
+	if parsed.Version != 1 {
+		return "", fmt.Errorf("unsupported SSH signature version %d", parsed.Version)
+	}
+	if parsed.Namespace != sshSignatureNamespace {
+		return "", fmt.Errorf("SSH signature namespace is %q, not %q", parsed.Namespace, sshSignatureNamespace)
+	}
+	publicKey, err := ssh.ParsePublicKey(parsed.PublicKey)
+	if err != nil {
+		return "", fmt.Errorf("parse SSH signing key: %w", err)
+	}
+
+	var digest []byte
+	switch parsed.HashAlgorithm {
+	case "sha256":
+		sum := sha256.Sum256(payload)
+		digest = sum[:]
+	case "sha512":
+		sum := sha512.Sum512(payload)
+		digest = sum[:]
+	default:
+		return "", fmt.Errorf("unsupported SSH signature hash %q", parsed.HashAlgorithm)
+	}
+	signed := append([]byte(sshSignatureMagic), ssh.Marshal(sshSignedData{
+		Namespace:     parsed.Namespace,
+		Reserved:      parsed.Reserved,
+		HashAlgorithm: parsed.HashAlgorithm,
+		Hash:          digest,
+	})...)
+	var sig ssh.Signature
+	if err := ssh.Unmarshal(parsed.Signature, &sig); err != nil {
+		return "", fmt.Errorf("parse SSH signature blob: %w", err)
+	}
+	if err := publicKey.Verify(signed, &sig); err != nil {
+		return "", err
+	}
+
+	fingerprint := ssh.FingerprintSHA256(publicKey)
+	if !sshKeyringContains(keyring, publicKey) {
+		return "", fmt.Errorf("signing key %s is not in the keyring", fingerprint)
+	}
+	return fingerprint, nil
+}
+
+// sshKeyringContains reports whether key is listed in keyring, an SSH allowed
+// signers file or a plain list of public keys.
+func sshKeyringContains(keyring []byte, key ssh.PublicKey) bool {
+	want := key.Marshal()
+	scanner := bufio.NewScanner(bytes.NewReader(keyring))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		candidate, _, _, _, err := ssh.ParseAuthorizedKey([]byte(line))
+		if err != nil {
+			// Allowed signers lines start with the signer's principals.
+			if _, rest, found := strings.Cut(line, " "); found {
+				candidate, _, _, _, err = ssh.ParseAuthorizedKey([]byte(rest))
+			}
+		}
+		if err == nil && bytes.Equal(candidate.Marshal(), want) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,194 @@
+package gitstore
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/pem"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/jmmarotta/agent_skills_manager/internal/manifest"
+)
+
+func TestVerifyRevisionSignedTag(t *testing.T) {
+	entity, err := openpgp.NewEntity("Release", "", "release@example.com", nil)
+	if err != nil {
+		t.Fatalf("new entity: %v", err)
+	}
+	repoDir, repo, _, hash := createTaggedRepo(t, "v0.9.0")
+	if _, err := repo.CreateTag("v1.0.0", hash, &git.CreateTagOptions{
+		Message: "v1.0.0",
+		Tagger:  &object.Signature{Name: "Release", Email: "release@example.com", When: time.Now()},
+		SignKey: entity,
+	}); err != nil {
+		t.Fatalf("signed tag: %v", err)
+	}
+
+	var keyring bytes.Buffer
+	writer, err := armor.Encode(&keyring, openpgp.PublicKeyType, nil)
+	if err != nil {
+		t.Fatalf("armor: %v", err)
+	}
+	if err := entity.Serialize(writer); err != nil {
+		t.Fatalf("serialize key: %v", err)
+	}
+	writer.Close()
+	keyringPath := filepath.Join(t.TempDir(), "release.asc")
+	if err := os.WriteFile(keyringPath, keyring.Bytes(), 0o644); err != nil {
+		t.Fatalf("write keyring: %v", err)
+	}
+	fingerprint := strings.ToUpper(hex.EncodeToString(entity.PrimaryKey.Fingerprint))
+
+	signer, err := VerifyRevision(repoDir, "v1.0.0", hash.String(), manifest.VerifyPolicy{
+		Keys:    []string{strings.ToLower(fingerprint)},
+		Keyring: keyringPath,
+	})
+	if err != nil {
+		t.Fatalf("VerifyRevision: %v", err)
+	}
+	if signer != fingerprint {
+		t.Fatalf("expected signer %s, got %s", fingerprint, signer)
+	}
+
+	_, err = VerifyRevision(repoDir, "v1.0.0", hash.String(), manifest.VerifyPolicy{
+		Keys:    []string{strings.Repeat("A", 40)},
+		Keyring: keyringPath,
+	})
+	if err == nil || !strings.Contains(err.Error(), "not an allowed key") {
+		t.Fatalf("expected disallowed key error, got %v", err)
+	}
+
+	// A signed v1.0.0 tag object republished as v2.0.0 is not a signed
+	// v2.0.0 release.
+	signed, err := repo.Reference(plumbing.NewTagReferenceName("v1.0.0"), true)
+	if err != nil {
+		t.Fatalf("tag ref: %v", err)
+	}
+	if err := repo.Storer.SetReference(plumbing.NewHashReference(plumbing.NewTagReferenceName("v2.0.0"), signed.Hash())); err != nil {
+		t.Fatalf("retag: %v", err)
+	}
+	_, err = VerifyRevision(repoDir, "v2.0.0", hash.String(), manifest.VerifyPolicy{
+		Keys:    []string{fingerprint},
+		Keyring: keyringPath,
+	})
+	if err == nil || !strings.Contains(err.Error(), "holds the signed tag v1.0.0") {
+		t.Fatalf("expected the renamed tag to be refused, got %v", err)
+	}
+
+	// The lightweight v0.9.0 tag falls back to the unsigned commit.
+	_, err = VerifyRevision(repoDir, "v0.9.0", hash.String(), manifest.VerifyPolicy{
+		Keys:    []string{fingerprint},
+		Keyring: keyringPath,
+	})
+	if err == nil || !strings.Contains(err.Error(), "is not signed") {
+		t.Fatalf("expected unsigned commit error, got %v", err)
+	}
+}
+
+func TestVerifyRevisionSSHSignedCommit(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	sshSigner, err := ssh.NewSignerFromKey(privateKey)
+	if err != nil {
+		t.Fatalf("ssh signer: %v", err)
+	}
+	sshPublicKey, err := ssh.NewPublicKey(publicKey)
+	if err != nil {
+		t.Fatalf("ssh public key: %v", err)
+	}
+
+	repoDir := t.TempDir()
+	repo := initRepo(t, repoDir)
+	wt, err := repo.Worktree()
+	if err != nil {
+		t.Fatalf("worktree: %v", err)
+	}
+	writeFile(t, repoDir, "README.md", "v1")
+	if _, err := wt.Add("README.md"); err != nil {
+		t.Fatalf("add: %v", err)
+	}
+	hash, err := wt.Commit("init", &git.CommitOptions{
+		Author: &object.Signature{Name: "Test", Email: "test@example.com", When: time.Now()},
+		Signer: testSSHSigner{signer: sshSigner},
+	})
+	if err != nil {
+		t.Fatalf("commit: %v", err)
+	}
+
+	allowedSigners := "test@example.com " + string(ssh.MarshalAuthorizedKey(sshPublicKey))
+	keyringPath := filepath.Join(t.TempDir(), "allowed_signers")
+	if err := os.WriteFile(keyringPath, []byte(allowedSigners), 0o644); err != nil {
+		t.Fatalf("write keyring: %v", err)
+	}
+	fingerprint := ssh.FingerprintSHA256(sshPublicKey)
+	version := "v0.0.0-20240101000000-" + hash.String()[:12]
+
+	signer, err := VerifyRevision(repoDir, version, hash.String(), manifest.VerifyPolicy{
+		Keys:    []string{fingerprint},
+		Keyring: keyringPath,
+	})
+	if err != nil {
+		t.Fatalf("VerifyRevision: %v", err)
+	}
+	if signer != fingerprint {
+		t.Fatalf("expected signer %s, got %s", fingerprint, signer)
+	}
+
+	emptyKeyring := filepath.Join(t.TempDir(), "allowed_signers")
+	if err := os.WriteFile(emptyKeyring, nil, 0o644); err != nil {
+		t.Fatalf("write keyring: %v", err)
+	}
+	_, err = VerifyRevision(repoDir, version, hash.String(), manifest.VerifyPolicy{
+		Keys:    []string{fingerprint},
+		Keyring: emptyKeyring,
+	})
+	if err == nil || !strings.Contains(err.Error(), "not in the keyring") {
+		t.Fatalf("expected keyring error, got %v", err)
+	}
+}
+
+// testSSHSigner signs objects the way git does with gpg.format=ssh.
+type testSSHSigner struct {
+	signer ssh.Signer
+}
+
+func (s testSSHSigner) Sign(message io.Reader) ([]byte, error) {
+	payload, err := io.ReadAll(message)
+	if err != nil {
+		return nil, err
+	}
+	digest := sha512.Sum512(payload)
+	signed := append([]byte(sshSignatureMagic), ssh.Marshal(sshSignedData{
+		Namespace:     sshSignatureNamespace,
+		HashAlgorithm: "sha512",
+		Hash:          digest[:],
+	})...)
+	signature, err := s.signer.Sign(rand.Reader, signed)
+	if err != nil {
+		return nil, err
+	}
+	blob := append([]byte(sshSignatureMagic), ssh.Marshal(sshSignature{
+		Version:       1,
+		PublicKey:     s.signer.PublicKey().Marshal(),
+		Namespace:     sshSignatureNamespace,
+		HashAlgorithm: "sha512",
+		Signature:     ssh.Marshal(signature),
+	})...)
+	return pem.EncodeToMemory(&pem.Block{Type: "SSH SIGNATURE", Bytes: blob}), nil
+}
@@ -10,7 +10,7 @@ func SourcesFromSkillPaths(paths []manifest.SkillPath) []Source {
 	return sources
 }
 
-func SourcesFromConfig(config manifest.Config, originPaths map[string]string) ([]Source, error) {
+func SourcesFromConfig(config manifest.Config, originPaths map[manifest.LockKey]string) ([]Source, error) {
 	skillPaths, err := config.ResolveSkillPaths(originPaths)
 	if err != nil {
 		return nil, err
@@ -32,7 +32,9 @@ func TestSourcesFromConfig(t *testing.T) {
 		},
 	}
 
-	originPaths := map[string]string{"https://example.com/repo": "/store/repo"}
+	originPaths := map[manifest.LockKey]string{
+		{Origin: "https://example.com/repo", Version: "v1.0.0"}: "/store/repo",
+	}
 
 	sources, err := SourcesFromConfig(config, originPaths)
 	if err != nil {
@@ -54,7 +56,7 @@ func TestSourcesFromConfigMissingOrigin(t *testing.T) {
 		Skills: []manifest.Skill{{Name: "remote", Origin: "https://example.com/repo", Version: "v1.0.0"}},
 	}
 
-	_, err := SourcesFromConfig(config, map[string]string{})
+	_, err := SourcesFromConfig(config, map[manifest.LockKey]string{})
 	if err == nil {
 		t.Fatalf("expected error")
 	}
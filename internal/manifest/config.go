@@ -3,6 +3,8 @@ package manifest
 import (
 	"fmt"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 
 	"golang.org/x/mod/module"
@@ -12,15 +14,37 @@ import (
 )
 
 type Config struct {
-	Skills  []Skill           `json:"skills"`
-	Replace map[string]string `json:"replace,omitempty"`
+	Schema      string            `json:"$schema,omitempty"`
+	Extends     *Extends          `json:"extends,omitempty"`
+	Skills      []Skill           `json:"skills"`
+	Replace     map[string]string `json:"replace,omitempty"`
+	Constraints map[string]string `json:"constraints,omitempty"`
+	// Verify requires the locked revisions of an origin to be signed by
+	// one of the listed keys.
+	Verify map[string]VerifyPolicy `json:"verify,omitempty"`
+
+	inheritedReplace     map[string]bool
+	inheritedConstraints map[string]bool
+	inheritedVerify      map[string]bool
+	inheritedLock        map[LockKey]string
+	inheritedHashes      map[HashKey]string
 }
 
 type Skill struct {
-	Name    string `json:"name"`
-	Origin  string `json:"origin"`
-	Subdir  string `json:"subdir,omitempty"`
-	Version string `json:"version,omitempty"`
+	Name       string   `json:"name"`
+	Origin     string   `json:"origin"`
+	Subdir     string   `json:"subdir,omitempty"`
+	Version    string   `json:"version,omitempty"`
+	Constraint string   `json:"constraint,omitempty"`
+	Track      string   `json:"track,omitempty"`
+	Groups     []string `json:"groups,omitempty"`
+	// Sha256 pins an archive origin to the hex SHA-256 of its download, in
+	// place of a version.
+	Sha256 string `json:"sha256,omitempty"`
+
+	// Inherited marks skills merged in from an extended manifest; they are
+	// never written back to the local one.
+	Inherited bool `json:"-"`
 }
 
 type LockKey struct {
@@ -28,38 +52,115 @@ type LockKey struct {
 	Version string
 }
 
+type HashKey struct {
+	Origin  string
+	Version string
+	Subdir  string
+}
+
+func (skill Skill) LockKey() LockKey {
+	return LockKey{Origin: skill.Origin, Version: skill.Version}
+}
+
+func (skill Skill) HashKey() HashKey {
+	return HashKey{Origin: skill.Origin, Version: skill.Version, Subdir: skill.Subdir}
+}
+
 func (config *Config) Validate() error {
-	versions := make(map[string]string)
+	if issues := config.issues(); len(issues) > 0 {
+		return issues[0].err
+	}
+	return nil
+}
+
+// issue is one validation problem and the manifest field it points at, as a
+// path of object keys and array indexes.
+type issue struct {
+	field []string
+	err   error
+}
+
+func newIssue(err error, field ...string) issue {
+	return issue{field: field, err: err}
+}
+
+func (config *Config) issues() []issue {
+	issues := []issue{}
+	if config.Extends != nil {
+		if err := config.Extends.validate(); err != nil {
+			issues = append(issues, newIssue(err, "extends"))
+		}
+	}
 	names := make(map[string]int)
 	identities := make(map[skillIdentity]int)
 	for index, skill := range config.Skills {
-		if err := skill.Validate(index); err != nil {
-			return err
-		}
+		position := strconv.Itoa(index)
+		issues = append(issues, skill.issues(index)...)
 		if prior, ok := names[skill.Name]; ok {
-			return fmt.Errorf("skills[%d]: duplicate name %q (already at skills[%d])", index, skill.Name, prior)
+			issues = append(issues, newIssue(fmt.Errorf("skills[%d]: duplicate name %q (already at skills[%d])", index, skill.Name, prior), "skills", position, "name"))
+		} else {
+			names[skill.Name] = index
 		}
-		names[skill.Name] = index
 
 		normalizedSubdir, err := normalizeSubdir(skill.Subdir)
 		if err != nil {
-			return fmt.Errorf("skills[%d]: invalid subdir %q: %w", index, skill.Subdir, err)
+			issues = append(issues, newIssue(fmt.Errorf("skills[%d]: invalid subdir %q: %w", index, skill.Subdir, err), "skills", position, "subdir"))
+			continue
 		}
 		identity := skillIdentity{origin: skill.Origin, subdir: normalizedSubdir}
 		if prior, ok := identities[identity]; ok {
-			return fmt.Errorf("skills[%d]: origin %q subdir %q already used by skills[%d]", index, skill.Origin, normalizedSubdir, prior)
+			issues = append(issues, newIssue(fmt.Errorf("skills[%d]: origin %q subdir %q already used by skills[%d]", index, skill.Origin, normalizedSubdir, prior), "skills", position, "subdir"))
+		} else {
+			identities[identity] = index
 		}
-		identities[identity] = index
-		if skill.Version == "" {
+	}
+
+	sums := make(map[string]int)
+	for index, skill := range config.Skills {
+		if skill.Sha256 == "" {
 			continue
 		}
-		if existing, ok := versions[skill.Origin]; ok && existing != skill.Version {
-			return fmt.Errorf("skills[%d]: origin %q uses multiple versions", index, skill.Origin)
+		if prior, ok := sums[skill.Origin]; ok && config.Skills[prior].Sha256 != skill.Sha256 {
+			issues = append(issues, newIssue(fmt.Errorf("skills[%d]: sha256 of %s differs from skills[%d]", index, skill.Origin, prior), "skills", strconv.Itoa(index), "sha256"))
+		} else if !ok {
+			sums[skill.Origin] = index
 		}
-		versions[skill.Origin] = skill.Version
 	}
 
-	return nil
+	// update follows track before any constraint, so an origin constraint,
+	// possibly inherited through extends, would be silently ignored.
+	for index, skill := range config.Skills {
+		if skill.Track != "" && config.Constraints[skill.Origin] != "" {
+			issues = append(issues, newIssue(fmt.Errorf("skills[%d]: track cannot be set on %s, which has an origin constraint", index, skill.Origin), "skills", strconv.Itoa(index), "track"))
+		}
+	}
+
+	origins := make([]string, 0, len(config.Constraints))
+	for origin := range config.Constraints {
+		origins = append(origins, origin)
+	}
+	sort.Strings(origins)
+	for _, origin := range origins {
+		if !source.IsRemoteOrigin(origin) {
+			issues = append(issues, newIssue(fmt.Errorf("constraints[%q]: constraints require a git origin", origin), "constraints", origin))
+			continue
+		}
+		if _, err := ParseConstraint(config.Constraints[origin]); err != nil {
+			issues = append(issues, newIssue(fmt.Errorf("constraints[%q]: %w", origin, err), "constraints", origin))
+		}
+	}
+	issues = append(issues, verifyIssues(config.Verify)...)
+
+	return issues
+}
+
+// ConstraintFor returns the version constraint that applies to skill: its own
+// constraint, else the one set for its origin.
+func (config Config) ConstraintFor(skill Skill) string {
+	if skill.Constraint != "" {
+		return skill.Constraint
+	}
+	return config.Constraints[skill.Origin]
 }
 
 func (config *Config) UpsertSkill(skill Skill) {
@@ -92,28 +193,90 @@ func FindSkill(skills []Skill, name string) (Skill, bool) {
 }
 
 func (skill Skill) Validate(index int) error {
+	if issues := skill.issues(index); len(issues) > 0 {
+		return issues[0].err
+	}
+	return nil
+}
+
+func (skill Skill) issues(index int) []issue {
+	position := strconv.Itoa(index)
+	fail := func(field string, format string, args ...any) issue {
+		return newIssue(fmt.Errorf("skills[%d]: "+format, append([]any{index}, args...)...), "skills", position, field)
+	}
+
+	issues := []issue{}
 	if skill.Name == "" {
-		return fmt.Errorf("skills[%d]: missing name", index)
+		issues = append(issues, fail("name", "missing name"))
 	}
 	if skill.Origin == "" {
-		return fmt.Errorf("skills[%d]: missing origin", index)
+		return append(issues, fail("origin", "missing origin"))
 	}
 	if err := source.ValidateOriginScheme(skill.Origin); err != nil {
-		return fmt.Errorf("skills[%d]: %w", index, err)
+		return append(issues, fail("origin", "%w", err))
+	}
+	// Archives are pinned by sha256 and, like local paths, have no version.
+	kind := "local"
+	isArchive := source.IsArchiveOrigin(skill.Origin)
+	switch {
+	case isArchive && skill.Sha256 == "":
+		issues = append(issues, fail("sha256", "archive origins require sha256"))
+	case isArchive && !IsSha256(skill.Sha256):
+		issues = append(issues, fail("sha256", "invalid sha256 %q", skill.Sha256))
+	case !isArchive && skill.Sha256 != "":
+		issues = append(issues, fail("sha256", "sha256 requires an archive origin"))
+	}
+	if isArchive {
+		kind = "archive"
 	}
 	isRemote := source.IsRemoteOrigin(skill.Origin)
 	if isRemote && skill.Version == "" {
-		return fmt.Errorf("skills[%d]: missing version", index)
+		issues = append(issues, fail("version", "missing version"))
 	}
 	if !isRemote && skill.Version != "" {
-		return fmt.Errorf("skills[%d]: local origins cannot set version", index)
+		issues = append(issues, fail("version", "%s origins cannot set version", kind))
 	}
 	if skill.Version != "" {
 		if !semver.IsValid(skill.Version) && !module.IsPseudoVersion(skill.Version) {
-			return fmt.Errorf("skills[%d]: invalid version %q", index, skill.Version)
+			issues = append(issues, fail("version", "invalid version %q", skill.Version))
 		}
 	}
-	return nil
+	if skill.Constraint != "" {
+		if !isRemote {
+			issues = append(issues, fail("constraint", "%s origins cannot set constraint", kind))
+		} else if _, err := ParseConstraint(skill.Constraint); err != nil {
+			issues = append(issues, fail("constraint", "%w", err))
+		}
+	}
+	if skill.Track != "" {
+		switch {
+		case !isRemote:
+			issues = append(issues, fail("track", "%s origins cannot set track", kind))
+		case skill.Constraint != "":
+			issues = append(issues, fail("track", "track and constraint cannot both be set"))
+		case strings.TrimSpace(skill.Track) != skill.Track || strings.HasPrefix(skill.Track, "refs/"):
+			issues = append(issues, fail("track", "invalid track %q", skill.Track))
+		}
+	}
+	for groupIndex, group := range skill.Groups {
+		if err := ValidateGroupName(group); err != nil {
+			issues = append(issues, newIssue(fmt.Errorf("skills[%d]: %w", index, err), "skills", position, "groups", strconv.Itoa(groupIndex)))
+		}
+	}
+	return issues
+}
+
+// IsSha256 reports whether value is a lower-case hex SHA-256 digest.
+func IsSha256(value string) bool {
+	if len(value) != 64 {
+		return false
+	}
+	for _, c := range value {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
 }
 
 type skillIdentity struct {
@@ -1,6 +1,9 @@
 package manifest
 
-import "testing"
+import (
+	"strings"
+	"testing"
+)
 
 func TestConfigValidateMissingFields(t *testing.T) {
 	config := Config{
@@ -31,15 +34,124 @@ func TestConfigUpsertAndRemove(t *testing.T) {
 	}
 }
 
-func TestConfigRejectsMultipleVersionsPerOrigin(t *testing.T) {
+func TestUpsertDiscoveredSkillsRepinDropsTrack(t *testing.T) {
+	origin := "https://example.com/repo"
+	config := Config{
+		Skills: []Skill{{Name: "foo", Origin: origin, Subdir: "foo", Version: "v0.0.0-20240101000000-abcdefabcdef", Track: "main", Groups: []string{"dev"}}},
+	}
+	discovered := []DiscoveredSkill{{Name: "foo", Subdir: "foo"}}
+
+	if err := config.UpsertDiscoveredSkills(discovered, UpsertOptions{Origin: origin, Version: "v0.0.0-20240102000000-abcdefabcdef"}); err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	if config.Skills[0].Track != "main" {
+		t.Fatalf("expected a re-add without a version to keep the track, got %+v", config.Skills[0])
+	}
+
+	config.Skills[0].Track = ""
+	config.Skills[0].Constraint = "^1.0"
+	if err := config.UpsertDiscoveredSkills(discovered, UpsertOptions{Origin: origin, Version: "v1.2.0", Pinned: true}); err != nil {
+		t.Fatalf("upsert pinned: %v", err)
+	}
+	skill := config.Skills[0]
+	if skill.Version != "v1.2.0" || skill.Track != "" || skill.Constraint != "" {
+		t.Fatalf("expected an explicit version to drop the track and constraint, got %+v", skill)
+	}
+	if len(skill.Groups) != 1 || skill.Groups[0] != "dev" {
+		t.Fatalf("expected groups to be kept, got %+v", skill.Groups)
+	}
+}
+
+func TestConfigAllowsMultipleVersionsPerOrigin(t *testing.T) {
 	config := Config{
 		Skills: []Skill{
-			{Name: "one", Origin: "https://example.com/repo", Version: "v1.0.0"},
-			{Name: "two", Origin: "https://example.com/repo", Version: "v1.1.0"},
+			{Name: "one", Origin: "https://example.com/repo", Subdir: "one", Version: "v1.0.0"},
+			{Name: "two", Origin: "https://example.com/repo", Subdir: "two", Version: "v1.1.0"},
 		},
 	}
+	if err := config.Validate(); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+}
+
+func TestConfigValidatesConstraints(t *testing.T) {
+	config := Config{
+		Skills: []Skill{{Name: "local", Origin: "/tmp/local", Constraint: "^1.0"}},
+	}
+	if err := config.Validate(); err == nil {
+		t.Fatalf("expected local origin constraint error")
+	}
+
+	config = Config{
+		Skills:      []Skill{{Name: "remote", Origin: "https://example.com/repo", Version: "v1.0.0"}},
+		Constraints: map[string]string{"https://example.com/repo": ">=banana"},
+	}
 	if err := config.Validate(); err == nil {
-		t.Fatalf("expected version conflict error")
+		t.Fatalf("expected invalid constraint error")
+	}
+
+	config.Constraints["https://example.com/repo"] = "^1.0"
+	if err := config.Validate(); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+	if got := config.ConstraintFor(config.Skills[0]); got != "^1.0" {
+		t.Fatalf("expected origin constraint, got %q", got)
+	}
+}
+
+func TestConfigValidatesTrack(t *testing.T) {
+	config := Config{
+		Skills: []Skill{{Name: "local", Origin: "/tmp/local", Track: "main"}},
+	}
+	if err := config.Validate(); err == nil {
+		t.Fatalf("expected local origin track error")
+	}
+
+	config = Config{
+		Skills: []Skill{{Name: "remote", Origin: "https://example.com/repo", Version: "v1.0.0", Track: "main", Constraint: "^1"}},
+	}
+	if err := config.Validate(); err == nil {
+		t.Fatalf("expected track and constraint conflict")
+	}
+
+	config.Skills[0].Constraint = ""
+	if err := config.Validate(); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+
+	config.Constraints = map[string]string{"https://example.com/repo": "^1"}
+	if err := config.Validate(); err == nil {
+		t.Fatalf("expected track and origin constraint conflict")
+	}
+}
+
+func TestConfigValidatesVerify(t *testing.T) {
+	origin := "https://example.com/repo"
+	config := Config{
+		Skills: []Skill{{Name: "remote", Origin: origin, Version: "v1.0.0"}},
+		Verify: map[string]VerifyPolicy{origin: {Keys: []string{"not-a-fingerprint"}, Keyring: "keys.asc"}},
+	}
+	if err := config.Validate(); err == nil {
+		t.Fatalf("expected invalid fingerprint error")
+	}
+
+	config.Verify[origin] = VerifyPolicy{Keys: []string{"SHA256:" + strings.Repeat("a", 43)}}
+	if err := config.Validate(); err == nil {
+		t.Fatalf("expected missing keyring error")
+	}
+
+	config.Verify = map[string]VerifyPolicy{"/tmp/local": {Keys: []string{strings.Repeat("a", 40)}, Keyring: "keys.asc"}}
+	if err := config.Validate(); err == nil {
+		t.Fatalf("expected local origin verify error")
+	}
+
+	config.Verify = map[string]VerifyPolicy{origin: {Keys: []string{strings.Repeat("ab ", 20)}, Keyring: "keys.asc"}}
+	if err := config.Validate(); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+	policy, ok := config.VerifyFor(origin)
+	if !ok || !policy.Allows(strings.Repeat("AB", 20)) {
+		t.Fatalf("expected normalized fingerprint to be allowed, got %+v", policy)
 	}
 }
 
@@ -69,3 +181,36 @@ func TestConfigRejectsUnknownScheme(t *testing.T) {
 		t.Fatalf("expected unsupported scheme error")
 	}
 }
+
+func TestConfigValidatesArchiveSkills(t *testing.T) {
+	archive := "https://example.com/skills-1.2.0.tar.gz"
+	sum := strings.Repeat("ab", 32)
+	valid := Config{Skills: []Skill{
+		{Name: "foo", Origin: archive, Subdir: "skills/foo", Sha256: sum},
+		{Name: "bar", Origin: archive, Subdir: "skills/bar", Sha256: sum},
+	}}
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("expected archive skills to be valid, got %v", err)
+	}
+
+	tests := []struct {
+		skills []Skill
+		want   string
+	}{
+		{skills: []Skill{{Name: "foo", Origin: archive}}, want: "archive origins require sha256"},
+		{skills: []Skill{{Name: "foo", Origin: archive, Sha256: "abc"}}, want: "invalid sha256"},
+		{skills: []Skill{{Name: "foo", Origin: archive, Sha256: sum, Version: "v1.0.0"}}, want: "archive origins cannot set version"},
+		{skills: []Skill{{Name: "foo", Origin: "https://example.com/repo", Version: "v1.0.0", Sha256: sum}}, want: "sha256 requires an archive origin"},
+		{skills: []Skill{
+			{Name: "foo", Origin: archive, Subdir: "skills/foo", Sha256: sum},
+			{Name: "bar", Origin: archive, Subdir: "skills/bar", Sha256: strings.Repeat("cd", 32)},
+		}, want: "differs from skills[0]"},
+	}
+	for _, test := range tests {
+		config := Config{Skills: test.skills}
+		err := config.Validate()
+		if err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Fatalf("expected %q, got %v", test.want, err)
+		}
+	}
+}
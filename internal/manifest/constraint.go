@@ -0,0 +1,219 @@
+package manifest
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"golang.org/x/mod/semver"
+)
+
+// Constraint is a semver range such as "^1.2", "~1.4.0" or ">=1.0 <2".
+// Space- or comma-separated comparisons must all hold; "||" separates
+// alternatives.
+type Constraint struct {
+	raw  string
+	sets [][]comparison
+}
+
+type comparison struct {
+	op      string
+	version string
+}
+
+func ParseConstraint(value string) (Constraint, error) {
+	raw := strings.TrimSpace(value)
+	if raw == "" {
+		return Constraint{}, fmt.Errorf("empty constraint")
+	}
+
+	constraint := Constraint{raw: raw}
+	for _, alternative := range strings.Split(raw, "||") {
+		fields := joinOperators(strings.FieldsFunc(alternative, func(r rune) bool {
+			return r == ' ' || r == ','
+		}))
+		if len(fields) == 0 {
+			return Constraint{}, fmt.Errorf("invalid constraint %q", raw)
+		}
+		set := []comparison{}
+		for _, field := range fields {
+			comparisons, err := parseComparison(field)
+			if err != nil {
+				return Constraint{}, fmt.Errorf("invalid constraint %q: %w", raw, err)
+			}
+			set = append(set, comparisons...)
+		}
+		constraint.sets = append(constraint.sets, set)
+	}
+	return constraint, nil
+}
+
+func (constraint Constraint) String() string {
+	return constraint.raw
+}
+
+// Allows reports whether version satisfies the constraint. Prerelease
+// versions are only allowed when prerelease is set.
+func (constraint Constraint) Allows(version string, prerelease bool) bool {
+	if !semver.IsValid(version) {
+		return false
+	}
+	if semver.Prerelease(version) != "" && !prerelease {
+		return false
+	}
+	for _, set := range constraint.sets {
+		if allowsAll(set, version) {
+			return true
+		}
+	}
+	return false
+}
+
+// Latest returns the highest version in versions that satisfies the
+// constraint.
+func (constraint Constraint) Latest(versions []string, prerelease bool) (string, bool) {
+	best := ""
+	for _, version := range versions {
+		if !constraint.Allows(version, prerelease) {
+			continue
+		}
+		if best == "" || semver.Compare(version, best) > 0 {
+			best = version
+		}
+	}
+	return best, best != ""
+}
+
+func allowsAll(set []comparison, version string) bool {
+	for _, item := range set {
+		result := semver.Compare(version, item.version)
+		switch item.op {
+		case "=":
+			if result != 0 {
+				return false
+			}
+		case ">":
+			if result <= 0 {
+				return false
+			}
+		case ">=":
+			if result < 0 {
+				return false
+			}
+		case "<":
+			if result >= 0 {
+				return false
+			}
+		case "<=":
+			if result > 0 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+var constraintOperators = []string{">=", "<=", ">", "<", "=", "^", "~"}
+
+// joinOperators glues an operator written on its own, as in ">= 1.0", to the
+// version that follows it.
+func joinOperators(fields []string) []string {
+	joined := make([]string, 0, len(fields))
+	for index := 0; index < len(fields); index++ {
+		field := fields[index]
+		if slices.Contains(constraintOperators, field) && index+1 < len(fields) {
+			index++
+			field += fields[index]
+		}
+		joined = append(joined, field)
+	}
+	return joined
+}
+
+func parseComparison(field string) ([]comparison, error) {
+	for _, op := range constraintOperators {
+		if !strings.HasPrefix(field, op) {
+			continue
+		}
+		rest := strings.TrimPrefix(field, op)
+		switch op {
+		case "^", "~":
+			return parseRange(op, rest)
+		default:
+			version, _, err := parsePartialVersion(rest)
+			if err != nil {
+				return nil, err
+			}
+			return []comparison{{op: op, version: version}}, nil
+		}
+	}
+	// A bare version pins every part it spells out: "1.2" means ~1.2.
+	version, parts, err := parsePartialVersion(field)
+	if err != nil {
+		return nil, err
+	}
+	if parts == 3 {
+		return []comparison{{op: "=", version: version}}, nil
+	}
+	return parseRange("~", field)
+}
+
+func parseRange(op string, value string) ([]comparison, error) {
+	lower, parts, err := parsePartialVersion(value)
+	if err != nil {
+		return nil, err
+	}
+	major, minor, patch := versionParts(lower)
+
+	var upper string
+	switch {
+	case op == "~" && parts == 1:
+		upper = fmt.Sprintf("v%d.0.0", major+1)
+	case op == "~":
+		upper = fmt.Sprintf("v%d.%d.0", major, minor+1)
+	case major > 0 || parts == 1:
+		upper = fmt.Sprintf("v%d.0.0", major+1)
+	case minor > 0 || parts == 2:
+		upper = fmt.Sprintf("v0.%d.0", minor+1)
+	default:
+		upper = fmt.Sprintf("v0.0.%d", patch+1)
+	}
+
+	return []comparison{
+		{op: ">=", version: lower},
+		{op: "<", version: upper + "-0"},
+	}, nil
+}
+
+// parsePartialVersion accepts "1", "v1.2" or "1.2.3-rc.1" and returns the
+// canonical version along with how many numeric parts were written.
+func parsePartialVersion(value string) (string, int, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "", 0, fmt.Errorf("missing version")
+	}
+	if !strings.HasPrefix(value, "v") {
+		value = "v" + value
+	}
+	if !semver.IsValid(value) {
+		return "", 0, fmt.Errorf("invalid version %q", value)
+	}
+	core := strings.TrimPrefix(value, "v")
+	if index := strings.IndexAny(core, "-+"); index >= 0 {
+		core = core[:index]
+	}
+	return semver.Canonical(value), strings.Count(core, ".") + 1, nil
+}
+
+func versionParts(version string) (int, int, int) {
+	core := strings.TrimPrefix(semver.Canonical(version), "v")
+	if index := strings.IndexAny(core, "-+"); index >= 0 {
+		core = core[:index]
+	}
+	numbers := [3]int{}
+	for index, part := range strings.SplitN(core, ".", 3) {
+		numbers[index], _ = strconv.Atoi(part)
+	}
+	return numbers[0], numbers[1], numbers[2]
+}
@@ -0,0 +1,66 @@
+package manifest
+
+import "testing"
+
+func TestConstraintAllows(t *testing.T) {
+	cases := []struct {
+		constraint string
+		version    string
+		allowed    bool
+	}{
+		{"^1.2", "v1.2.0", true},
+		{"^1.2", "v1.9.3", true},
+		{"^1.2", "v2.0.0", false},
+		{"^1.2", "v1.1.9", false},
+		{"^0.3.1", "v0.3.5", true},
+		{"^0.3.1", "v0.4.0", false},
+		{"~1.4.0", "v1.4.7", true},
+		{"~1.4.0", "v1.5.0", false},
+		{"~1", "v1.9.0", true},
+		{">=1.0 <2", "v1.5.0", true},
+		{">=1.0 <2", "v2.0.0", false},
+		{">=1.0, <2", "v0.9.0", false},
+		{">= 1.0 < 2", "v1.5.0", true},
+		{">= 1.0, < 2", "v2.0.0", false},
+		{"^ 1.2", "v1.3.0", true},
+		{"1.2.3", "v1.2.3", true},
+		{"1.2.3", "v1.2.4", false},
+		{"1.2", "v1.2.9", true},
+		{"^1 || ^3", "v3.1.0", true},
+		{"^1 || ^3", "v2.1.0", false},
+		{"^1.2", "v1.3.0-rc.1", false},
+	}
+
+	for _, tc := range cases {
+		constraint, err := ParseConstraint(tc.constraint)
+		if err != nil {
+			t.Fatalf("parse %q: %v", tc.constraint, err)
+		}
+		if got := constraint.Allows(tc.version, false); got != tc.allowed {
+			t.Fatalf("expected %q allows %s = %t, got %t", tc.constraint, tc.version, tc.allowed, got)
+		}
+	}
+}
+
+func TestConstraintLatestSkipsPrereleaseUnlessAllowed(t *testing.T) {
+	constraint, err := ParseConstraint("^1.2")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	tags := []string{"v1.1.0", "v1.2.0", "v1.4.0", "v1.5.0-rc.1", "v2.0.0"}
+
+	if got, ok := constraint.Latest(tags, false); !ok || got != "v1.4.0" {
+		t.Fatalf("expected v1.4.0, got %q", got)
+	}
+	if got, ok := constraint.Latest(tags, true); !ok || got != "v1.5.0-rc.1" {
+		t.Fatalf("expected v1.5.0-rc.1 with prereleases, got %q", got)
+	}
+}
+
+func TestParseConstraintRejectsInvalid(t *testing.T) {
+	for _, value := range []string{"", "^", ">=banana", "1.2 ||", ">= 1.0 <"} {
+		if _, err := ParseConstraint(value); err == nil {
+			t.Fatalf("expected %q to be rejected", value)
+		}
+	}
+}
@@ -0,0 +1,363 @@
+package manifest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/tidwall/jsonc"
+)
+
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Diagnostic is one manifest or lockfile problem with its source position.
+type Diagnostic struct {
+	File     string
+	Line     int
+	Column   int
+	Field    string
+	Severity string
+	Message  string
+}
+
+func (diagnostic Diagnostic) String() string {
+	return fmt.Sprintf("%s:%d:%d: %s: %s", diagnostic.File, diagnostic.Line, diagnostic.Column, diagnostic.Severity, diagnostic.Message)
+}
+
+func HasErrors(diagnostics []Diagnostic) bool {
+	for _, diagnostic := range diagnostics {
+		if diagnostic.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// Check reports every problem in the manifest at path and the lockfile next
+// to it. Bases named by a git extends are not fetched, so their skills are
+// not checked. The error is only set when the manifest cannot be read.
+func Check(path string) ([]Diagnostic, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	source := newSourceFile(path, data)
+
+	var parsed Config
+	if err := json.Unmarshal(jsonc.ToJSON(data), &parsed); err != nil {
+		return []Diagnostic{source.syntaxError(err)}, nil
+	}
+
+	root := filepath.Dir(path)
+	expanded, issues := expandConfig(parsed, root)
+	issues = append(issues, expanded.issues()...)
+
+	merged := expanded
+	if expanded.Extends != nil && !expanded.Extends.IsRemote() && expanded.Extends.validate() == nil {
+		base, err := loadBase(expanded.Extends.Path, map[string]bool{filepath.Clean(path): true})
+		if err != nil {
+			issues = append(issues, newIssue(fmt.Errorf("extends %s: %w", expanded.Extends.Path, err), "extends"))
+		} else {
+			merged.Skills = append([]Skill{}, expanded.Skills...)
+			merged.Replace = map[string]string{}
+			for origin, value := range expanded.Replace {
+				merged.Replace[origin] = value
+			}
+			merged.Inherit(base)
+			for _, item := range merged.issues() {
+				issues = append(issues, newIssue(fmt.Errorf("with extends: %w", item.err), "extends"))
+			}
+		}
+	}
+
+	diagnostics := []Diagnostic{}
+	seen := map[string]bool{}
+	for _, item := range issues {
+		message := item.err.Error()
+		if strings.HasPrefix(message, "with extends: ") && seen[strings.TrimPrefix(message, "with extends: ")] {
+			continue
+		}
+		if seen[message] {
+			continue
+		}
+		seen[message] = true
+		diagnostics = append(diagnostics, source.diagnostic(item.field, SeverityError, message))
+	}
+
+	diagnostics = append(diagnostics, source.unknownFields()...)
+
+	origins := make([]string, 0, len(parsed.Replace))
+	for origin := range parsed.Replace {
+		origins = append(origins, origin)
+	}
+	sort.Strings(origins)
+	for _, origin := range origins {
+		replacePath := parsed.Replace[origin]
+		if _, err := os.Stat(expandRelativePath(replacePath, root)); errors.Is(err, os.ErrNotExist) {
+			message := fmt.Sprintf("replace[%q]: %s does not exist; installs fall back to the remote", origin, replacePath)
+			diagnostics = append(diagnostics, source.diagnostic([]string{"replace", origin}, SeverityWarning, message))
+		}
+	}
+
+	lockDiagnostics, err := checkLock(LockPath(root), merged, source)
+	if err != nil {
+		return nil, err
+	}
+	diagnostics = append(diagnostics, lockDiagnostics...)
+	sort.SliceStable(diagnostics, func(i, j int) bool {
+		left, right := diagnostics[i], diagnostics[j]
+		if (left.File == path) != (right.File == path) {
+			return left.File == path
+		}
+		if left.Line != right.Line {
+			return left.Line < right.Line
+		}
+		return left.Column < right.Column
+	})
+	return diagnostics, nil
+}
+
+// checkLock compares the lockfile at path with the merged manifest config.
+func checkLock(path string, config Config, manifest sourceFile) ([]Diagnostic, error) {
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	source := newSourceFile(path, data)
+	if hasConflictMarkers(data) {
+		offset := bytes.Index(data, []byte("<<<<<<<"))
+		line, column := source.position(offset)
+		return []Diagnostic{{
+			File:     path,
+			Line:     line,
+			Column:   column,
+			Severity: SeverityError,
+			Message:  "merge conflict markers; run asm lock fix",
+		}}, nil
+	}
+
+	var parsed lockFile
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &parsed); err != nil {
+			return []Diagnostic{source.syntaxError(err)}, nil
+		}
+	}
+	diagnostics := []Diagnostic{}
+	if parsed.Schema < 0 || parsed.Schema > lockSchemaVersion {
+		diagnostics = append(diagnostics, source.diagnostic([]string{"schema"}, SeverityError, fmt.Sprintf("unsupported lock schema %d", parsed.Schema)))
+	}
+
+	used := map[LockKey]bool{}
+	byName := map[string]Skill{}
+	for _, skill := range config.Skills {
+		if skill.Version == "" {
+			continue
+		}
+		used[skill.LockKey()] = true
+		byName[skill.Name] = skill
+	}
+	remoteBase := config.Extends != nil && config.Extends.IsRemote()
+	if remoteBase {
+		used[config.Extends.LockKey()] = true
+	}
+
+	sums := config.ArchiveSums()
+	revs := map[LockKey]string{}
+	for index, entry := range parsed.Entries {
+		field := []string{"entries", strconv.Itoa(index)}
+		fail := func(severity string, format string, args ...any) {
+			message := fmt.Sprintf("entries[%d]: "+format, append([]any{index}, args...)...)
+			diagnostics = append(diagnostics, source.diagnostic(field, severity, message))
+		}
+		if entry.Sha256 != "" {
+			if sum, ok := sums[entry.Origin]; !ok {
+				fail(SeverityWarning, "no skill uses %s", entry.Origin)
+			} else if sum != entry.Sha256 {
+				fail(SeverityError, "sha256 of %s differs from the manifest; run asm install", entry.Origin)
+			}
+			continue
+		}
+		if entry.Origin == "" || entry.Version == "" || entry.Rev == "" {
+			fail(SeverityError, "origin, version and rev are required")
+			continue
+		}
+		key := LockKey{Origin: entry.Origin, Version: entry.Version}
+		if existing, ok := revs[key]; ok && existing != entry.Rev {
+			fail(SeverityError, "conflicting rev for %s %s", entry.Origin, entry.Version)
+		}
+		revs[key] = entry.Rev
+		if entry.Hash != "" && !strings.HasPrefix(entry.Hash, hashPrefix) {
+			fail(SeverityError, "invalid hash %q", entry.Hash)
+		}
+		if !used[key] && !remoteBase {
+			fail(SeverityWarning, "no skill uses %s %s", entry.Origin, entry.Version)
+			continue
+		}
+		if skill, ok := byName[entry.Name]; ok && entry.Name != "" {
+			if skill.Origin != entry.Origin || skill.Version != entry.Version || skill.Subdir != entry.Subdir {
+				fail(SeverityError, "skill %q is %s %s subdir %q in the manifest", entry.Name, skill.Origin, skill.Version, skill.Subdir)
+			}
+		}
+	}
+
+	for index, skill := range config.Skills {
+		if skill.Version == "" || skill.Inherited {
+			continue
+		}
+		if _, ok := revs[skill.LockKey()]; !ok {
+			message := fmt.Sprintf("skills[%d]: %s %s is not locked; run asm install", index, skill.Origin, skill.Version)
+			diagnostics = append(diagnostics, manifest.diagnostic([]string{"skills", strconv.Itoa(index), "version"}, SeverityWarning, message))
+		}
+	}
+	return diagnostics, nil
+}
+
+// sourceFile maps manifest fields and byte offsets to line and column.
+type sourceFile struct {
+	path string
+	data []byte
+	tree *jsonNode
+}
+
+func newSourceFile(path string, data []byte) sourceFile {
+	tree, err := parseJSONC(data)
+	if err != nil {
+		tree = nil
+	}
+	return sourceFile{path: path, data: data, tree: tree}
+}
+
+func (file sourceFile) diagnostic(field []string, severity string, message string) Diagnostic {
+	line, column := file.position(file.locate(field))
+	return Diagnostic{
+		File:     file.path,
+		Line:     line,
+		Column:   column,
+		Field:    formatField(field),
+		Severity: severity,
+		Message:  message,
+	}
+}
+
+func (file sourceFile) syntaxError(err error) Diagnostic {
+	offset := 0
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntaxErr):
+		offset = int(syntaxErr.Offset)
+	case errors.As(err, &typeErr):
+		offset = int(typeErr.Offset)
+	}
+	line, column := file.position(offset)
+	return Diagnostic{File: file.path, Line: line, Column: column, Severity: SeverityError, Message: err.Error()}
+}
+
+// unknownFields warns about keys the schema does not define; they are
+// otherwise silently ignored.
+func (file sourceFile) unknownFields() []Diagnostic {
+	if file.tree == nil || file.tree.kind != '{' {
+		return nil
+	}
+	diagnostics := []Diagnostic{}
+	check := func(node *jsonNode, field []string, allowed []string) {
+		if node == nil || node.kind != '{' {
+			return
+		}
+		for _, member := range node.members {
+			if slices.Contains(allowed, member.key) {
+				continue
+			}
+			path := append(append([]string{}, field...), member.key)
+			message := fmt.Sprintf("unknown field %q", formatField(path))
+			diagnostics = append(diagnostics, file.diagnostic(path, SeverityWarning, message))
+		}
+	}
+
+	check(file.tree, nil, schemaKeys(Schema()))
+	if index, ok := file.tree.member("extends"); ok {
+		check(file.tree.members[index].value, []string{"extends"}, schemaKeys(extendsSchema()))
+	}
+	if index, ok := file.tree.member("verify"); ok {
+		verify := file.tree.members[index].value
+		if verify.kind == '{' {
+			for _, member := range verify.members {
+				check(member.value, []string{"verify", member.key}, schemaKeys(verifySchema()))
+			}
+		}
+	}
+	if index, ok := file.tree.member("skills"); ok {
+		skills := file.tree.members[index].value
+		if skills.kind == '[' {
+			for position, member := range skills.members {
+				check(member.value, []string{"skills", strconv.Itoa(position)}, schemaKeys(skillSchema()))
+			}
+		}
+	}
+	return diagnostics
+}
+
+// locate returns the offset of the deepest node along field that exists.
+func (file sourceFile) locate(field []string) int {
+	if file.tree == nil {
+		return 0
+	}
+	node := file.tree
+	offset := node.start
+	for _, part := range field {
+		switch node.kind {
+		case '{':
+			index, ok := node.member(part)
+			if !ok {
+				return offset
+			}
+			offset = node.members[index].start
+			node = node.members[index].value
+		case '[':
+			index, err := strconv.Atoi(part)
+			if err != nil || index < 0 || index >= len(node.members) {
+				return offset
+			}
+			node = node.members[index].value
+			offset = node.start
+		default:
+			return offset
+		}
+	}
+	return offset
+}
+
+func (file sourceFile) position(offset int) (int, int) {
+	if offset > len(file.data) {
+		offset = len(file.data)
+	}
+	before := file.data[:offset]
+	line := bytes.Count(before, []byte("\n")) + 1
+	column := offset - bytes.LastIndexByte(before, '\n')
+	return line, column
+}
+
+func formatField(field []string) string {
+	var builder strings.Builder
+	for _, part := range field {
+		if _, err := strconv.Atoi(part); err == nil {
+			builder.WriteString("[" + part + "]")
+			continue
+		}
+		if builder.Len() > 0 {
+			builder.WriteString(".")
+		}
+		builder.WriteString(part)
+	}
+	return builder.String()
+}
@@ -0,0 +1,93 @@
+package manifest
+
+import (
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestCheckReportsEveryProblemWithPositions(t *testing.T) {
+	root := t.TempDir()
+	path := filepath.Join(root, "skills.jsonc")
+	writeManifest(t, path, `{
+  // shared skills
+  "skills": [
+    {"name": "one", "origin": "https://github.com/acme/skills", "subdir": "one", "version": "v1.0.0"},
+    {"name": "one", "origin": "https://github.com/acme/skills", "subdir": "../two", "version": "v1.0.0"},
+    {"name": "three", "origin": "s3://bucket/repo", "version": "v1.0.0", "verison": "x"}
+  ]
+}
+`)
+	writeLockFile(t, root, `{"schema": 2, "entries": [
+  {"origin": "https://github.com/acme/skills", "version": "v1.0.0", "rev": "abc", "subdir": "other", "name": "one"},
+  {"origin": "https://github.com/acme/gone", "version": "v1.0.0", "rev": "def"}
+]}
+`)
+
+	diagnostics, err := Check(path)
+	if err != nil {
+		t.Fatalf("check: %v", err)
+	}
+	if !HasErrors(diagnostics) {
+		t.Fatalf("expected errors")
+	}
+
+	expected := []string{
+		"skills.jsonc:5:6: error: skills[1]: duplicate name \"one\"",
+		"skills.jsonc:5:65: error: skills[1]: invalid subdir \"../two\"",
+		"skills.jsonc:6:23: error: skills[2]: unsupported origin scheme",
+		"skills.jsonc:6:74: warning: unknown field \"skills[2].verison\"",
+		"skills-lock.json:2:3: error: entries[0]: skill \"one\" is",
+		"skills-lock.json:3:3: warning: entries[1]: no skill uses https://github.com/acme/gone v1.0.0",
+	}
+	var lines []string
+	for _, diagnostic := range diagnostics {
+		lines = append(lines, strings.TrimPrefix(diagnostic.String(), root+string(filepath.Separator)))
+	}
+	output := strings.Join(lines, "\n")
+	for _, want := range expected {
+		if !strings.Contains(output, want) {
+			t.Fatalf("expected %q in:\n%s", want, output)
+		}
+	}
+}
+
+func TestCheckReportsSyntaxErrorPosition(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "skills.jsonc")
+	writeManifest(t, path, "{\n  \"skills\": [\n    {\"name\": \"one\" \"origin\": \"x\"}\n  ]\n}\n")
+
+	diagnostics, err := Check(path)
+	if err != nil {
+		t.Fatalf("check: %v", err)
+	}
+	if len(diagnostics) != 1 || diagnostics[0].Line != 3 {
+		t.Fatalf("expected one syntax error on line 3, got %+v", diagnostics)
+	}
+}
+
+func TestSchemaCoversManifestFields(t *testing.T) {
+	cases := []struct {
+		value  any
+		schema schemaObject
+	}{
+		{Config{}, Schema()},
+		{Skill{}, skillSchema()},
+		{Extends{}, extendsSchema()},
+	}
+	for _, item := range cases {
+		keys := schemaKeys(item.schema)
+		valueType := reflect.TypeOf(item.value)
+		for index := 0; index < valueType.NumField(); index++ {
+			field := valueType.Field(index)
+			name := strings.Split(field.Tag.Get("json"), ",")[0]
+			if !field.IsExported() || name == "" || name == "-" {
+				continue
+			}
+			if !slices.Contains(keys, name) {
+				t.Fatalf("schema for %s is missing %q", valueType.Name(), name)
+			}
+		}
+	}
+}
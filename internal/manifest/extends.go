@@ -0,0 +1,328 @@
+package manifest
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/tidwall/jsonc"
+
+	"github.com/jmmarotta/agent_skills_manager/internal/source"
+)
+
+// Extends points at a base manifest whose skills and replace rules are merged
+// in before the local ones. Path alone names a local manifest; with Origin and
+// Version it names a manifest inside that git repo.
+type Extends struct {
+	Origin  string `json:"origin,omitempty"`
+	Version string `json:"version,omitempty"`
+	Path    string `json:"path,omitempty"`
+}
+
+func (extends Extends) IsRemote() bool {
+	return extends.Origin != ""
+}
+
+func (extends Extends) LockKey() LockKey {
+	return LockKey{Origin: extends.Origin, Version: extends.Version}
+}
+
+// ManifestPath returns the base manifest path, relative to the repo root for
+// git bases.
+func (extends Extends) ManifestPath() string {
+	if extends.Path == "" {
+		return jsoncFilename
+	}
+	return extends.Path
+}
+
+func (extends *Extends) UnmarshalJSON(data []byte) error {
+	var path string
+	if err := json.Unmarshal(data, &path); err == nil {
+		*extends = Extends{Path: path}
+		return nil
+	}
+	type plain Extends
+	var value plain
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	*extends = Extends(value)
+	return nil
+}
+
+func (extends Extends) MarshalJSON() ([]byte, error) {
+	if !extends.IsRemote() {
+		return json.Marshal(extends.Path)
+	}
+	type plain Extends
+	return json.Marshal(plain(extends))
+}
+
+func (extends Extends) validate() error {
+	if extends.IsRemote() {
+		if err := source.ValidateOriginScheme(extends.Origin); err != nil {
+			return fmt.Errorf("extends: %w", err)
+		}
+		if !source.IsRemoteOrigin(extends.Origin) {
+			return fmt.Errorf("extends: origin %q must be a git origin", extends.Origin)
+		}
+		if extends.Version == "" {
+			return fmt.Errorf("extends: version is required for origin %q", extends.Origin)
+		}
+		if filepath.IsAbs(extends.Path) {
+			return fmt.Errorf("extends: path %q must be relative to the repo", extends.Path)
+		}
+		return nil
+	}
+	if extends.Version != "" {
+		return fmt.Errorf("extends: version requires an origin")
+	}
+	if extends.Path == "" {
+		return fmt.Errorf("extends: path is required")
+	}
+	return nil
+}
+
+// LoadBase loads a manifest to be extended, along with the lock entries
+// recorded next to it.
+func LoadBase(path string) (Config, error) {
+	return loadBase(path, map[string]bool{})
+}
+
+func loadBase(path string, seen map[string]bool) (Config, error) {
+	config, err := loadConfig(path, seen)
+	if err != nil {
+		return Config{}, err
+	}
+	entries, hashes, err := LoadLockWithHashes(LockPath(filepath.Dir(path)))
+	if err != nil {
+		return Config{}, err
+	}
+	config.inheritLock(entries, hashes)
+	return config, nil
+}
+
+// loadConfig reads path and merges in any local base manifest. Git bases are
+// left for the caller to resolve.
+func loadConfig(path string, seen map[string]bool) (Config, error) {
+	absolute, err := filepath.Abs(path)
+	if err != nil {
+		return Config{}, err
+	}
+	if seen[absolute] {
+		return Config{}, fmt.Errorf("extends cycle at %s", path)
+	}
+	seen[absolute] = true
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, err
+	}
+
+	cleaned := jsonc.ToJSON(data)
+	var parsed Config
+	if err := json.Unmarshal(cleaned, &parsed); err != nil {
+		return Config{}, err
+	}
+
+	config, err := expandConfigPaths(parsed, filepath.Dir(path))
+	if err != nil {
+		return Config{}, err
+	}
+
+	if config.Extends == nil {
+		return config, nil
+	}
+	if err := config.Extends.validate(); err != nil {
+		return Config{}, err
+	}
+	if !config.Extends.IsRemote() {
+		base, err := loadBase(config.Extends.Path, seen)
+		if err != nil {
+			return Config{}, fmt.Errorf("extends %s: %w", config.Extends.Path, err)
+		}
+		config.Inherit(base)
+	}
+	return config, nil
+}
+
+// Inherit merges base in beneath config: base skills are added unless a local
+// skill has the same name, and base replace rules, constraints and verify
+// policies apply to any origin the local manifest does not set them for.
+func (config *Config) Inherit(base Config) {
+	local := map[string]bool{}
+	for _, skill := range config.Skills {
+		local[skill.Name] = true
+	}
+	for _, skill := range base.Skills {
+		if local[skill.Name] {
+			continue
+		}
+		skill.Inherited = true
+		config.Skills = append(config.Skills, skill)
+	}
+
+	if config.Replace == nil {
+		config.Replace = map[string]string{}
+	}
+	for origin, path := range base.Replace {
+		if _, ok := config.Replace[origin]; ok {
+			continue
+		}
+		config.Replace[origin] = path
+		if config.inheritedReplace == nil {
+			config.inheritedReplace = map[string]bool{}
+		}
+		config.inheritedReplace[origin] = true
+	}
+
+	for origin, constraint := range base.Constraints {
+		if _, ok := config.Constraints[origin]; ok {
+			continue
+		}
+		if config.Constraints == nil {
+			config.Constraints = map[string]string{}
+		}
+		config.Constraints[origin] = constraint
+		if config.inheritedConstraints == nil {
+			config.inheritedConstraints = map[string]bool{}
+		}
+		config.inheritedConstraints[origin] = true
+	}
+
+	for origin, policy := range base.Verify {
+		if _, ok := config.Verify[origin]; ok {
+			continue
+		}
+		if config.Verify == nil {
+			config.Verify = map[string]VerifyPolicy{}
+		}
+		config.Verify[origin] = policy
+		if config.inheritedVerify == nil {
+			config.inheritedVerify = map[string]bool{}
+		}
+		config.inheritedVerify[origin] = true
+	}
+
+	config.inheritLock(base.inheritedLock, base.inheritedHashes)
+}
+
+func (config *Config) inheritLock(entries map[LockKey]string, hashes map[HashKey]string) {
+	if len(entries) > 0 && config.inheritedLock == nil {
+		config.inheritedLock = map[LockKey]string{}
+	}
+	for key, rev := range entries {
+		if _, ok := config.inheritedLock[key]; !ok {
+			config.inheritedLock[key] = rev
+		}
+	}
+	if len(hashes) > 0 && config.inheritedHashes == nil {
+		config.inheritedHashes = map[HashKey]string{}
+	}
+	for key, hash := range hashes {
+		if _, ok := config.inheritedHashes[key]; !ok {
+			config.inheritedHashes[key] = hash
+		}
+	}
+}
+
+// Local returns config without anything merged in from its base manifest.
+func (config Config) Local() Config {
+	local := Config{
+		Schema:  config.Schema,
+		Extends: config.Extends,
+		Skills:  make([]Skill, 0, len(config.Skills)),
+		Replace: make(map[string]string, len(config.Replace)),
+	}
+	for _, skill := range config.Skills {
+		if !skill.Inherited {
+			local.Skills = append(local.Skills, skill)
+		}
+	}
+	for origin, path := range config.Replace {
+		if !config.inheritedReplace[origin] {
+			local.Replace[origin] = path
+		}
+	}
+	for origin, constraint := range config.Constraints {
+		if config.inheritedConstraints[origin] {
+			continue
+		}
+		if local.Constraints == nil {
+			local.Constraints = map[string]string{}
+		}
+		local.Constraints[origin] = constraint
+	}
+	for origin, policy := range config.Verify {
+		if config.inheritedVerify[origin] {
+			continue
+		}
+		if local.Verify == nil {
+			local.Verify = map[string]VerifyPolicy{}
+		}
+		local.Verify[origin] = policy
+	}
+	return local
+}
+
+// AdoptInheritedLock copies lock entries recorded by base manifests for
+// inherited skills that the local lock does not pin yet.
+func (state *State) AdoptInheritedLock() {
+	used := map[LockKey]bool{}
+	for _, skill := range state.Config.Skills {
+		if skill.Inherited && skill.Version != "" {
+			used[skill.LockKey()] = true
+		}
+	}
+	for key, rev := range state.Config.inheritedLock {
+		if !used[key] {
+			continue
+		}
+		if _, ok := state.Lock[key]; ok {
+			continue
+		}
+		if state.Lock == nil {
+			state.Lock = map[LockKey]string{}
+		}
+		state.Lock[key] = rev
+		for hashKey, hash := range state.Config.inheritedHashes {
+			if hashKey.Origin != key.Origin || hashKey.Version != key.Version {
+				continue
+			}
+			if state.Hashes == nil {
+				state.Hashes = map[HashKey]string{}
+			}
+			state.Hashes[hashKey] = hash
+		}
+	}
+}
+
+func expandExtends(extends *Extends, root string) *Extends {
+	if extends == nil {
+		return nil
+	}
+	expanded := *extends
+	if expanded.IsRemote() {
+		expanded.Origin = source.NormalizeOrigin(expanded.Origin)
+		return &expanded
+	}
+	expanded.Path = expandRelativePath(expanded.Path, root)
+	return &expanded
+}
+
+func collapseExtends(extends *Extends, root string) *Extends {
+	if extends == nil {
+		return nil
+	}
+	collapsed := *extends
+	if collapsed.IsRemote() || !filepath.IsAbs(collapsed.Path) {
+		return &collapsed
+	}
+	// Shared baselines usually sit beside the repo, so keep "../" paths too.
+	if relative, err := filepath.Rel(filepath.Clean(root), collapsed.Path); err == nil {
+		collapsed.Path = filepath.ToSlash(relative)
+	}
+	return &collapsed
+}
@@ -0,0 +1,223 @@
+package manifest
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadMergesLocalExtends(t *testing.T) {
+	root := t.TempDir()
+	baseDir := filepath.Join(root, "baseline")
+	teamDir := filepath.Join(root, "team")
+	for _, dir := range []string{baseDir, teamDir} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+	}
+
+	writeManifest(t, filepath.Join(baseDir, "skills.jsonc"), `{
+  "skills": [
+    {"name": "review", "origin": "https://github.com/acme/skills", "subdir": "review", "version": "v1.0.0"},
+    {"name": "notes", "origin": "./notes"}
+  ],
+  "replace": {"https://github.com/acme/skills": "./acme"}
+}
+`)
+	writeLockFile(t, baseDir, `{"schema": 2, "entries": [
+  {"origin": "https://github.com/acme/skills", "version": "v1.0.0", "rev": "abc123", "subdir": "review", "name": "review"}
+]}
+`)
+	manifestPath := filepath.Join(teamDir, "skills.jsonc")
+	writeManifest(t, manifestPath, `{
+  // Team skills on top of the shared baseline.
+  "extends": "../baseline/skills.jsonc",
+  "skills": [
+    {"name": "notes", "origin": "./my-notes"}
+  ]
+}
+`)
+
+	state, err := LoadStateAt(manifestPath)
+	if err != nil {
+		t.Fatalf("load state: %v", err)
+	}
+	if len(state.Config.Skills) != 2 {
+		t.Fatalf("expected 2 skills, got %d", len(state.Config.Skills))
+	}
+	notes, _ := FindSkill(state.Config.Skills, "notes")
+	if notes.Inherited || notes.Origin != filepath.Join(teamDir, "my-notes") {
+		t.Fatalf("expected local notes override, got %+v", notes)
+	}
+	review, _ := FindSkill(state.Config.Skills, "review")
+	if !review.Inherited {
+		t.Fatalf("expected review to be inherited")
+	}
+	if got := state.Config.Replace["https://github.com/acme/skills"]; got != filepath.Join(baseDir, "acme") {
+		t.Fatalf("expected inherited replace, got %q", got)
+	}
+	if got := state.Lock[review.LockKey()]; got != "abc123" {
+		t.Fatalf("expected inherited lock entry, got %q", got)
+	}
+
+	if err := SaveState(state); err != nil {
+		t.Fatalf("save state: %v", err)
+	}
+	data, err := os.ReadFile(manifestPath)
+	if err != nil {
+		t.Fatalf("read manifest: %v", err)
+	}
+	saved := string(data)
+	if strings.Contains(saved, "review") || strings.Contains(saved, "replace\": {\"") {
+		t.Fatalf("expected inherited entries to stay out of the local manifest:\n%s", saved)
+	}
+	if !strings.Contains(saved, `"extends": "../baseline/skills.jsonc"`) || !strings.Contains(saved, "// Team skills") {
+		t.Fatalf("expected extends and comments to survive:\n%s", saved)
+	}
+
+	lock, err := LoadLock(state.LockPath)
+	if err != nil {
+		t.Fatalf("load lock: %v", err)
+	}
+	if lock[review.LockKey()] != "abc123" {
+		t.Fatalf("expected inherited lock entry in local lock, got %v", lock)
+	}
+}
+
+func TestLoadInheritsVerifyAndConstraints(t *testing.T) {
+	root := t.TempDir()
+	baseDir := filepath.Join(root, "baseline")
+	teamDir := filepath.Join(root, "team")
+	for _, dir := range []string{baseDir, teamDir} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+	}
+
+	acme := "https://github.com/acme/skills"
+	tools := "https://github.com/acme/tools"
+	writeManifest(t, filepath.Join(baseDir, "skills.jsonc"), `{
+  "skills": [],
+  "constraints": {"`+acme+`": "^1.0", "`+tools+`": "^2.0"},
+  "verify": {
+    "`+acme+`": {"keys": ["0123456789ABCDEF0123456789ABCDEF01234567"], "keyring": "./team.asc"},
+    "`+tools+`": {"keys": ["0123456789ABCDEF0123456789ABCDEF01234567"], "keyring": "./team.asc"}
+  }
+}
+`)
+	manifestPath := filepath.Join(teamDir, "skills.jsonc")
+	writeManifest(t, manifestPath, `{
+  "extends": "../baseline/skills.jsonc",
+  "skills": [],
+  "constraints": {"`+tools+`": "^3.0"},
+  "verify": {
+    "`+tools+`": {"keys": ["89ABCDEF0123456789ABCDEF0123456789ABCDEF"], "keyring": "./mine.asc"}
+  }
+}
+`)
+
+	state, err := LoadStateAt(manifestPath)
+	if err != nil {
+		t.Fatalf("load state: %v", err)
+	}
+	if got := state.Config.Constraints[acme]; got != "^1.0" {
+		t.Fatalf("expected inherited constraint, got %q", got)
+	}
+	if got := state.Config.Constraints[tools]; got != "^3.0" {
+		t.Fatalf("expected local constraint to win, got %q", got)
+	}
+	if got := state.Config.Verify[acme].Keyring; got != filepath.Join(baseDir, "team.asc") {
+		t.Fatalf("expected inherited verify policy relative to the base, got %q", got)
+	}
+	if got := state.Config.Verify[tools].Keyring; got != filepath.Join(teamDir, "mine.asc") {
+		t.Fatalf("expected local verify policy to win, got %q", got)
+	}
+
+	if err := SaveState(state); err != nil {
+		t.Fatalf("save state: %v", err)
+	}
+	saved, err := os.ReadFile(manifestPath)
+	if err != nil {
+		t.Fatalf("read manifest: %v", err)
+	}
+	if strings.Contains(string(saved), acme+`"`) {
+		t.Fatalf("expected inherited policies to stay out of the local manifest:\n%s", saved)
+	}
+}
+
+func TestLoadRejectsTrackOnInheritedConstraint(t *testing.T) {
+	root := t.TempDir()
+	acme := "https://github.com/acme/skills"
+	writeManifest(t, filepath.Join(root, "base.jsonc"), `{
+  "skills": [],
+  "constraints": {"`+acme+`": "^1.0"}
+}
+`)
+	manifestPath := filepath.Join(root, "skills.jsonc")
+	writeManifest(t, manifestPath, `{
+  "extends": "./base.jsonc",
+  "skills": [{"name": "alpha", "origin": "`+acme+`", "version": "v1.0.0", "track": "main"}]
+}
+`)
+
+	_, err := LoadStateAt(manifestPath)
+	if err == nil || !strings.Contains(err.Error(), "origin constraint") {
+		t.Fatalf("expected track and inherited constraint conflict, got %v", err)
+	}
+}
+
+func TestLoadRejectsExtendsCycle(t *testing.T) {
+	root := t.TempDir()
+	writeManifest(t, filepath.Join(root, "a.jsonc"), `{"extends": "./b.jsonc", "skills": []}`)
+	writeManifest(t, filepath.Join(root, "b.jsonc"), `{"extends": "./a.jsonc", "skills": []}`)
+
+	if _, err := Load(filepath.Join(root, "a.jsonc")); err == nil || !strings.Contains(err.Error(), "cycle") {
+		t.Fatalf("expected extends cycle error, got %v", err)
+	}
+}
+
+func TestLoadValidatesMergedSkills(t *testing.T) {
+	root := t.TempDir()
+	writeManifest(t, filepath.Join(root, "base.jsonc"), `{"skills": [
+  {"name": "review", "origin": "https://github.com/acme/skills", "subdir": "review", "version": "v1.0.0"}
+]}`)
+	writeManifest(t, filepath.Join(root, "skills.jsonc"), `{"extends": "./base.jsonc", "skills": [
+  {"name": "other", "origin": "https://github.com/acme/skills", "subdir": "review", "version": "v1.0.0"}
+]}`)
+
+	if _, err := Load(filepath.Join(root, "skills.jsonc")); err == nil {
+		t.Fatalf("expected duplicate origin subdir error")
+	}
+}
+
+func TestConfigValidatesExtends(t *testing.T) {
+	config := Config{Extends: &Extends{Origin: "https://github.com/acme/baseline"}}
+	if err := config.Validate(); err == nil {
+		t.Fatalf("expected missing version error")
+	}
+
+	config.Extends.Version = "v1.0.0"
+	if err := config.Validate(); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+
+	config.Extends = &Extends{Version: "v1.0.0", Path: "base.jsonc"}
+	if err := config.Validate(); err == nil {
+		t.Fatalf("expected version without origin error")
+	}
+}
+
+func writeManifest(t *testing.T, path string, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("write manifest: %v", err)
+	}
+}
+
+func writeLockFile(t *testing.T, dir string, content string) {
+	t.Helper()
+	if err := os.WriteFile(LockPath(dir), []byte(content), 0o644); err != nil {
+		t.Fatalf("write lock: %v", err)
+	}
+}
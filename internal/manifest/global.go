@@ -0,0 +1,115 @@
+package manifest
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+
+	"github.com/jmmarotta/agent_skills_manager/internal/debug"
+)
+
+const globalSkillsDirEnv = "ASM_GLOBAL_SKILLS_DIR"
+
+// GlobalRoot is the directory holding the user-scope manifest and lock:
+// $XDG_CONFIG_HOME/asm, falling back to ~/.config/asm.
+func GlobalRoot() (string, error) {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "asm"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".config", "asm"), nil
+}
+
+// GlobalPaths lays out user-scope state under root. Skills link into
+// $ASM_GLOBAL_SKILLS_DIR when set.
+func GlobalPaths(root string) (Paths, error) {
+	skillsDir := filepath.Join(root, "skills")
+	if dir := os.Getenv(globalSkillsDirEnv); dir != "" {
+		abs, err := filepath.Abs(dir)
+		if err != nil {
+			return Paths{}, err
+		}
+		skillsDir = abs
+	}
+	return withStore(Paths{
+		Root:      root,
+		StateDir:  root,
+		CacheDir:  filepath.Join(root, "cache"),
+		SkillsDir: skillsDir,
+	}, filepath.Join(root, "store")), nil
+}
+
+// FindGlobalManifestPath returns the user-scope manifest, or
+// ErrManifestNotFound when there is none yet.
+func FindGlobalManifestPath() (string, error) {
+	root, err := GlobalRoot()
+	if err != nil {
+		return "", err
+	}
+	path, exists, err := resolveManifestPath(root)
+	if err != nil {
+		return "", err
+	}
+	if !exists {
+		return "", ErrManifestNotFound
+	}
+	return path, nil
+}
+
+func LoadGlobalState() (State, error) {
+	path, err := FindGlobalManifestPath()
+	if err != nil {
+		return State{}, err
+	}
+	debug.Logf("global manifest path=%s", path)
+	root := filepath.Dir(path)
+
+	paths, err := GlobalPaths(root)
+	if err != nil {
+		return State{}, err
+	}
+	state, err := loadStateWithPaths(path, paths)
+	if err != nil {
+		return State{}, err
+	}
+	state.Global = true
+	return state, nil
+}
+
+func LoadOrInitGlobalState() (State, bool, error) {
+	state, err := LoadGlobalState()
+	if err == nil {
+		return state, false, nil
+	}
+	if !errors.Is(err, ErrManifestNotFound) {
+		return State{}, false, err
+	}
+
+	root, err := GlobalRoot()
+	if err != nil {
+		return State{}, false, err
+	}
+	paths, err := GlobalPaths(root)
+	if err != nil {
+		return State{}, false, err
+	}
+	manifestPath := DefaultManifestPath(root)
+	debug.Logf("global manifest init path=%s", manifestPath)
+	return State{
+		Root:         root,
+		ManifestPath: manifestPath,
+		LockPath:     LockPath(root),
+		Paths:        paths,
+		Global:       true,
+		Config: Config{
+			Replace: map[string]string{},
+		},
+		Lock:     map[LockKey]string{},
+		Hashes:   map[HashKey]string{},
+		Signers:  map[LockKey]string{},
+		Archives: map[string]string{},
+	}, true, nil
+}
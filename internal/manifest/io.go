@@ -7,10 +7,11 @@ import (
 	"os"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
 
-	"github.com/tidwall/jsonc"
-
+	"github.com/jmmarotta/agent_skills_manager/internal/debug"
+	"github.com/jmmarotta/agent_skills_manager/internal/envflag"
 	"github.com/jmmarotta/agent_skills_manager/internal/source"
 )
 
@@ -19,7 +20,8 @@ const (
 	jsonFilename  = "skills.json"
 	lockFilename  = "skills-lock.json"
 
-	lockSchemaVersion = 1
+	lockSchemaVersion = 2
+	hashPrefix        = "h1:"
 )
 
 var ErrManifestNotFound = errors.New("skills.jsonc not found")
@@ -68,19 +70,7 @@ func Load(path string) (Config, error) {
 		return Config{}, fmt.Errorf("manifest path is required")
 	}
 
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return Config{}, err
-	}
-
-	cleaned := jsonc.ToJSON(data)
-	var parsed Config
-	if err := json.Unmarshal(cleaned, &parsed); err != nil {
-		return Config{}, err
-	}
-
-	root := filepath.Dir(path)
-	expanded, err := expandConfigPaths(parsed, root)
+	expanded, err := loadConfig(path, map[string]bool{})
 	if err != nil {
 		return Config{}, err
 	}
@@ -92,13 +82,15 @@ func Load(path string) (Config, error) {
 	return expanded, nil
 }
 
+const rewriteManifestEnv = "ASM_REWRITE_MANIFEST"
+
 func Save(path string, config Config) error {
 	if path == "" {
 		return fmt.Errorf("manifest path is required")
 	}
 
 	root := filepath.Dir(path)
-	normalized, err := normalizeConfigPaths(config, root)
+	normalized, err := normalizeConfigPaths(config.Local(), root)
 	if err != nil {
 		return err
 	}
@@ -113,6 +105,23 @@ func Save(path string, config Config) error {
 		return err
 	}
 
+	// Edit an existing manifest in place so comments and layout survive.
+	// When that cannot be done safely the file is left alone, unless
+	// $ASM_REWRITE_MANIFEST allows rewriting it without them.
+	if existing, err := os.ReadFile(path); err == nil {
+		edited, err := editJSONC(existing, data)
+		switch {
+		case err == nil:
+			data = edited
+		case envflag.Enabled(rewriteManifestEnv):
+			debug.Logf("rewrite manifest path=%s: %v", path, err)
+		default:
+			return fmt.Errorf("cannot edit %s in place without losing its comments and layout: %w; set %s=1 to rewrite it", path, err, rewriteManifestEnv)
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
 	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
 		return err
 	}
@@ -125,48 +134,97 @@ type lockFile struct {
 	Entries []LockEntry `json:"entries"`
 }
 
+// LockEntry is one locked skill. Git origins record the version and its
+// rev; archive origins record their sha256 instead.
 type LockEntry struct {
 	Origin  string `json:"origin"`
-	Version string `json:"version"`
-	Rev     string `json:"rev"`
+	Version string `json:"version,omitempty"`
+	Rev     string `json:"rev,omitempty"`
+	Sha256  string `json:"sha256,omitempty"`
 	Subdir  string `json:"subdir,omitempty"`
 	Name    string `json:"name,omitempty"`
+	Hash    string `json:"hash,omitempty"`
+	// Signer is the fingerprint of the key whose signature on the tag or
+	// commit was verified, for origins with a verify policy.
+	Signer string `json:"signer,omitempty"`
 }
 
 func LoadLock(path string) (map[LockKey]string, error) {
+	entries, _, err := LoadLockWithHashes(path)
+	return entries, err
+}
+
+func LoadLockWithHashes(path string) (map[LockKey]string, map[HashKey]string, error) {
+	entries, hashes, _, _, err := loadLock(path)
+	return entries, hashes, err
+}
+
+// loadLock reads the lockfile at path, returning the locked revisions, the
+// content hashes, the verified signer of each locked revision and the sha256
+// of each archive origin.
+func loadLock(path string) (map[LockKey]string, map[HashKey]string, map[LockKey]string, map[string]string, error) {
 	entries := make(map[LockKey]string)
+	hashes := make(map[HashKey]string)
+	signers := make(map[LockKey]string)
+	archives := make(map[string]string)
 	if path == "" {
-		return entries, fmt.Errorf("lock path is required")
+		return entries, hashes, signers, archives, fmt.Errorf("lock path is required")
 	}
 
 	data, err := os.ReadFile(path)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return entries, nil
+			return entries, hashes, signers, archives, nil
 		}
-		return nil, err
+		return nil, nil, nil, nil, err
+	}
+
+	if hasConflictMarkers(data) {
+		return nil, nil, nil, nil, fmt.Errorf("%s: %w", path, ErrLockConflict)
 	}
 
 	var parsed lockFile
 	if err := json.Unmarshal(data, &parsed); err != nil {
-		return nil, err
+		return nil, nil, nil, nil, err
 	}
-	if parsed.Schema != 0 && parsed.Schema != lockSchemaVersion {
-		return nil, fmt.Errorf("unsupported lock schema %d", parsed.Schema)
+	if parsed.Schema < 0 || parsed.Schema > lockSchemaVersion {
+		return nil, nil, nil, nil, fmt.Errorf("unsupported lock schema %d", parsed.Schema)
 	}
 
 	for _, entry := range parsed.Entries {
+		if entry.Sha256 != "" {
+			if entry.Origin == "" || !IsSha256(entry.Sha256) {
+				return nil, nil, nil, nil, fmt.Errorf("invalid skills-lock.json entry: origin=%q sha256=%q", entry.Origin, entry.Sha256)
+			}
+			archives[entry.Origin] = entry.Sha256
+			continue
+		}
 		if entry.Origin == "" || entry.Version == "" || entry.Rev == "" {
-			return nil, fmt.Errorf("invalid skills-lock.json entry: origin=%q version=%q rev=%q", entry.Origin, entry.Version, entry.Rev)
+			return nil, nil, nil, nil, fmt.Errorf("invalid skills-lock.json entry: origin=%q version=%q rev=%q", entry.Origin, entry.Version, entry.Rev)
 		}
 		key := LockKey{Origin: entry.Origin, Version: entry.Version}
 		if existing, ok := entries[key]; ok && existing != entry.Rev {
-			return nil, fmt.Errorf("skills-lock.json has conflicting entries for %s %s", key.Origin, key.Version)
+			return nil, nil, nil, nil, fmt.Errorf("skills-lock.json has conflicting entries for %s %s: %w", key.Origin, key.Version, ErrLockConflict)
 		}
 		entries[key] = entry.Rev
+		if entry.Signer != "" {
+			signers[key] = entry.Signer
+		}
+
+		if entry.Hash == "" {
+			continue
+		}
+		if !strings.HasPrefix(entry.Hash, hashPrefix) {
+			return nil, nil, nil, nil, fmt.Errorf("invalid skills-lock.json hash for %s %s: %q", key.Origin, key.Version, entry.Hash)
+		}
+		hashKey := HashKey{Origin: entry.Origin, Version: entry.Version, Subdir: entry.Subdir}
+		if existing, ok := hashes[hashKey]; ok && existing != entry.Hash {
+			return nil, nil, nil, nil, fmt.Errorf("skills-lock.json has conflicting hashes for %s %s subdir %q", key.Origin, key.Version, entry.Subdir)
+		}
+		hashes[hashKey] = entry.Hash
 	}
 
-	return entries, nil
+	return entries, hashes, signers, archives, nil
 }
 
 func SaveLock(path string, entries map[LockKey]string) error {
@@ -174,11 +232,19 @@ func SaveLock(path string, entries map[LockKey]string) error {
 }
 
 func SaveLockWithSkills(path string, entries map[LockKey]string, skills []Skill) error {
+	return SaveLockWithHashes(path, entries, nil, skills)
+}
+
+func SaveLockWithHashes(path string, entries map[LockKey]string, hashes map[HashKey]string, skills []Skill) error {
+	return saveLock(path, entries, hashes, nil, skills)
+}
+
+func saveLock(path string, entries map[LockKey]string, hashes map[HashKey]string, signers map[LockKey]string, skills []Skill) error {
 	if path == "" {
 		return fmt.Errorf("lock path is required")
 	}
 
-	lockEntries := buildLockEntries(entries, skills)
+	lockEntries := buildLockEntries(entries, hashes, signers, skills)
 
 	payload, err := json.MarshalIndent(lockFile{Schema: lockSchemaVersion, Entries: lockEntries}, "", "  ")
 	if err != nil {
@@ -200,10 +266,19 @@ type lockEntryMetadata struct {
 	Name   string
 }
 
-func buildLockEntries(entries map[LockKey]string, skills []Skill) []LockEntry {
+func buildLockEntries(entries map[LockKey]string, hashes map[HashKey]string, signers map[LockKey]string, skills []Skill) []LockEntry {
 	lockEntries := make([]LockEntry, 0, len(entries))
-	if len(entries) == 0 {
-		return lockEntries
+	seenArchives := map[LockEntry]struct{}{}
+	for _, skill := range skills {
+		if skill.Sha256 == "" {
+			continue
+		}
+		entry := LockEntry{Origin: skill.Origin, Sha256: skill.Sha256, Subdir: skill.Subdir, Name: skill.Name}
+		if _, exists := seenArchives[entry]; exists {
+			continue
+		}
+		seenArchives[entry] = struct{}{}
+		lockEntries = append(lockEntries, entry)
 	}
 
 	metadataByKey := map[LockKey][]lockEntryMetadata{}
@@ -230,7 +305,7 @@ func buildLockEntries(entries map[LockKey]string, skills []Skill) []LockEntry {
 	for key, rev := range entries {
 		metadata := metadataByKey[key]
 		if len(metadata) == 0 {
-			lockEntries = append(lockEntries, LockEntry{Origin: key.Origin, Version: key.Version, Rev: rev})
+			lockEntries = append(lockEntries, LockEntry{Origin: key.Origin, Version: key.Version, Rev: rev, Signer: signers[key]})
 			continue
 		}
 		for _, item := range metadata {
@@ -240,6 +315,8 @@ func buildLockEntries(entries map[LockKey]string, skills []Skill) []LockEntry {
 				Rev:     rev,
 				Subdir:  item.Subdir,
 				Name:    item.Name,
+				Hash:    hashes[HashKey{Origin: key.Origin, Version: key.Version, Subdir: item.Subdir}],
+				Signer:  signers[key],
 			})
 		}
 	}
@@ -259,7 +336,10 @@ func buildLockEntries(entries map[LockKey]string, skills []Skill) []LockEntry {
 		if left.Name != right.Name {
 			return left.Name < right.Name
 		}
-		return left.Rev < right.Rev
+		if left.Rev != right.Rev {
+			return left.Rev < right.Rev
+		}
+		return left.Sha256 < right.Sha256
 	})
 
 	return lockEntries
@@ -295,58 +375,96 @@ func fileExists(path string) (bool, error) {
 }
 
 func expandConfigPaths(config Config, root string) (Config, error) {
+	expanded, issues := expandConfig(config, root)
+	if len(issues) > 0 {
+		return Config{}, issues[0].err
+	}
+	return expanded, nil
+}
+
+// expandConfig resolves origins and replace paths against root, collecting
+// every problem instead of stopping at the first. Entries that fail keep
+// their raw values.
+func expandConfig(config Config, root string) (Config, []issue) {
+	issues := []issue{}
 	expanded := Config{
-		Skills:  make([]Skill, len(config.Skills)),
-		Replace: make(map[string]string, len(config.Replace)),
+		Schema:      config.Schema,
+		Extends:     expandExtends(config.Extends, root),
+		Skills:      make([]Skill, len(config.Skills)),
+		Replace:     make(map[string]string, len(config.Replace)),
+		Constraints: normalizeConstraintOrigins(config.Constraints),
+		Verify: mapVerify(config.Verify, func(path string) string {
+			return expandRelativePath(path, root)
+		}),
 	}
 
 	for index, skill := range config.Skills {
+		expanded.Skills[index] = skill
+		field := []string{"skills", strconv.Itoa(index), "origin"}
 		originValue, _, err := source.NormalizeFileOrigin(skill.Origin)
 		if err != nil {
-			return Config{}, fmt.Errorf("skills[%d]: %w", index, err)
+			issues = append(issues, newIssue(fmt.Errorf("skills[%d]: %w", index, err), field...))
+			continue
 		}
 		skill.Origin = originValue
 		if err := source.ValidateOriginScheme(skill.Origin); err != nil {
-			return Config{}, fmt.Errorf("skills[%d]: %w", index, err)
+			issues = append(issues, newIssue(fmt.Errorf("skills[%d]: %w", index, err), field...))
+			continue
 		}
 		if source.IsRemoteOrigin(skill.Origin) {
 			skill.Origin = source.NormalizeOrigin(skill.Origin)
 		}
-		if !source.IsRemoteOrigin(skill.Origin) {
+		if !source.IsRemoteOrigin(skill.Origin) && !source.IsArchiveOrigin(skill.Origin) {
 			skill.Origin = expandRelativePath(skill.Origin, root)
 		}
 		expanded.Skills[index] = skill
 	}
 
-	for origin, replace := range config.Replace {
+	origins := make([]string, 0, len(config.Replace))
+	for origin := range config.Replace {
+		origins = append(origins, origin)
+	}
+	sort.Strings(origins)
+	for _, origin := range origins {
+		replace := config.Replace[origin]
 		normalizedOrigin := origin
 		if source.IsRemoteOrigin(normalizedOrigin) {
 			normalizedOrigin = source.NormalizeOrigin(normalizedOrigin)
 		}
 		replaceValue, _, err := source.NormalizeFileOrigin(replace)
 		if err != nil {
-			return Config{}, fmt.Errorf("replace[%q]: %w", origin, err)
+			issues = append(issues, newIssue(fmt.Errorf("replace[%q]: %w", origin, err), "replace", origin))
+			continue
 		}
 		if err := source.ValidateOriginScheme(replaceValue); err != nil {
-			return Config{}, fmt.Errorf("replace[%q]: %w", origin, err)
+			issues = append(issues, newIssue(fmt.Errorf("replace[%q]: %w", origin, err), "replace", origin))
+			continue
 		}
-		if source.IsRemoteOrigin(replaceValue) {
-			return Config{}, fmt.Errorf("replace[%q]: replace path must be a local path", origin)
+		if source.IsRemoteOrigin(replaceValue) || source.IsArchiveOrigin(replaceValue) {
+			issues = append(issues, newIssue(fmt.Errorf("replace[%q]: replace path must be a local path", origin), "replace", origin))
+			continue
 		}
 		resolved := expandRelativePath(replaceValue, root)
 		if existing, ok := expanded.Replace[normalizedOrigin]; ok && existing != resolved {
-			return Config{}, fmt.Errorf("replace[%q]: conflicts with replace[%q]", origin, normalizedOrigin)
+			issues = append(issues, newIssue(fmt.Errorf("replace[%q]: conflicts with replace[%q]", origin, normalizedOrigin), "replace", origin))
+			continue
 		}
 		expanded.Replace[normalizedOrigin] = resolved
 	}
 
-	return expanded, nil
+	return expanded, issues
 }
 
 func normalizeConfigPaths(config Config, root string) (Config, error) {
 	normalized := Config{
-		Skills:  make([]Skill, len(config.Skills)),
-		Replace: make(map[string]string, len(config.Replace)),
+		Schema:      config.Schema,
+		Extends:     collapseExtends(config.Extends, root),
+		Skills:      make([]Skill, len(config.Skills)),
+		Replace:     make(map[string]string, len(config.Replace)),
+		Constraints: normalizeConstraintOrigins(config.Constraints),
+		Verify: mapVerify(config.Verify, func(path string) string {
+			return collapseRelativePath(path, root)
+		}),
 	}
 
 	for index, skill := range config.Skills {
@@ -361,7 +479,7 @@ func normalizeConfigPaths(config Config, root string) (Config, error) {
 		if source.IsRemoteOrigin(skill.Origin) {
 			skill.Origin = source.NormalizeOrigin(skill.Origin)
 		}
-		if !source.IsRemoteOrigin(skill.Origin) {
+		if !source.IsRemoteOrigin(skill.Origin) && !source.IsArchiveOrigin(skill.Origin) {
 			skill.Origin = collapseRelativePath(skill.Origin, root)
 		}
 		normalized.Skills[index] = skill
@@ -379,7 +497,7 @@ func normalizeConfigPaths(config Config, root string) (Config, error) {
 		if err := source.ValidateOriginScheme(replaceValue); err != nil {
 			return Config{}, fmt.Errorf("replace[%q]: %w", origin, err)
 		}
-		if source.IsRemoteOrigin(replaceValue) {
+		if source.IsRemoteOrigin(replaceValue) || source.IsArchiveOrigin(replaceValue) {
 			return Config{}, fmt.Errorf("replace[%q]: replace path must be a local path", origin)
 		}
 		resolved := collapseRelativePath(replaceValue, root)
@@ -392,6 +510,17 @@ func normalizeConfigPaths(config Config, root string) (Config, error) {
 	return normalized, nil
 }
 
+func normalizeConstraintOrigins(constraints map[string]string) map[string]string {
+	normalized := make(map[string]string, len(constraints))
+	for origin, value := range constraints {
+		if source.IsRemoteOrigin(origin) {
+			origin = source.NormalizeOrigin(origin)
+		}
+		normalized[origin] = value
+	}
+	return normalized
+}
+
 func expandRelativePath(value string, root string) string {
 	if value == "" {
 		return ""
@@ -245,3 +245,129 @@ func TestSaveLockWithSkillsOrdersByOriginVersionSubdirAndName(t *testing.T) {
 		t.Fatalf("unexpected rev for repo-b v1.0.0")
 	}
 }
+
+func TestSaveLockWithHashesRoundTrip(t *testing.T) {
+	root := t.TempDir()
+	lockPath := filepath.Join(root, "skills-lock.json")
+
+	origin := "https://example.com/repo"
+	entries := map[LockKey]string{{Origin: origin, Version: "v1.0.0"}: "aaaa1111"}
+	hashes := map[HashKey]string{
+		{Origin: origin, Version: "v1.0.0", Subdir: "plugins/foo"}: "h1:foo=",
+		{Origin: origin, Version: "v0.9.0", Subdir: "plugins/foo"}: "h1:stale=",
+	}
+	skills := []Skill{
+		{Name: "foo", Origin: origin, Version: "v1.0.0", Subdir: "plugins/foo"},
+		{Name: "bar", Origin: origin, Version: "v1.0.0", Subdir: "plugins/bar"},
+	}
+
+	if err := SaveLockWithHashes(lockPath, entries, hashes, skills); err != nil {
+		t.Fatalf("SaveLockWithHashes: %v", err)
+	}
+
+	data, err := os.ReadFile(lockPath)
+	if err != nil {
+		t.Fatalf("read lock: %v", err)
+	}
+	if !strings.Contains(string(data), `"schema": 2`) {
+		t.Fatalf("expected schema 2, got %s", string(data))
+	}
+	if strings.Contains(string(data), "h1:stale=") {
+		t.Fatalf("expected stale hash to be dropped, got %s", string(data))
+	}
+
+	loadedEntries, loadedHashes, err := LoadLockWithHashes(lockPath)
+	if err != nil {
+		t.Fatalf("LoadLockWithHashes: %v", err)
+	}
+	if loadedEntries[LockKey{Origin: origin, Version: "v1.0.0"}] != "aaaa1111" {
+		t.Fatalf("unexpected rev")
+	}
+	if len(loadedHashes) != 1 {
+		t.Fatalf("expected 1 hash, got %d", len(loadedHashes))
+	}
+	if loadedHashes[HashKey{Origin: origin, Version: "v1.0.0", Subdir: "plugins/foo"}] != "h1:foo=" {
+		t.Fatalf("unexpected hash %v", loadedHashes)
+	}
+}
+
+func TestSaveLockRecordsSigners(t *testing.T) {
+	lockPath := filepath.Join(t.TempDir(), "skills-lock.json")
+
+	origin := "https://example.com/repo"
+	key := LockKey{Origin: origin, Version: "v1.0.0"}
+	signer := strings.Repeat("AB", 20)
+	entries := map[LockKey]string{key: "aaaa1111"}
+	skills := []Skill{{Name: "foo", Origin: origin, Version: "v1.0.0", Subdir: "plugins/foo"}}
+
+	if err := saveLock(lockPath, entries, nil, map[LockKey]string{key: signer}, skills); err != nil {
+		t.Fatalf("saveLock: %v", err)
+	}
+	data, err := os.ReadFile(lockPath)
+	if err != nil {
+		t.Fatalf("read lock: %v", err)
+	}
+	if !strings.Contains(string(data), `"signer": "`+signer+`"`) {
+		t.Fatalf("expected signer in lock, got %s", string(data))
+	}
+
+	_, _, signers, _, err := loadLock(lockPath)
+	if err != nil {
+		t.Fatalf("loadLock: %v", err)
+	}
+	if signers[key] != signer {
+		t.Fatalf("expected signer %s, got %v", signer, signers)
+	}
+}
+
+func TestLoadLockAcceptsSchemaOne(t *testing.T) {
+	root := t.TempDir()
+	lockPath := filepath.Join(root, "skills-lock.json")
+	payload := `{"schema": 1, "entries": [{"origin": "https://example.com/repo", "version": "v1.0.0", "rev": "aaaa1111"}]}`
+	if err := os.WriteFile(lockPath, []byte(payload), 0o644); err != nil {
+		t.Fatalf("write lock: %v", err)
+	}
+
+	entries, hashes, err := LoadLockWithHashes(lockPath)
+	if err != nil {
+		t.Fatalf("LoadLockWithHashes: %v", err)
+	}
+	if len(entries) != 1 || len(hashes) != 0 {
+		t.Fatalf("expected 1 entry and no hashes, got %d and %d", len(entries), len(hashes))
+	}
+}
+
+func TestSaveLockRecordsArchiveChecksums(t *testing.T) {
+	lockPath := filepath.Join(t.TempDir(), "skills-lock.json")
+
+	archive := "https://example.com/skills-1.2.0.tar.gz"
+	sum := strings.Repeat("ab", 32)
+	key := LockKey{Origin: "https://example.com/repo", Version: "v1.0.0"}
+	skills := []Skill{
+		{Name: "foo", Origin: archive, Subdir: "skills/foo", Sha256: sum},
+		{Name: "bar", Origin: archive, Subdir: "skills/bar", Sha256: sum},
+		{Name: "baz", Origin: key.Origin, Version: key.Version},
+	}
+
+	if err := saveLock(lockPath, map[LockKey]string{key: "aaaa1111"}, nil, nil, skills); err != nil {
+		t.Fatalf("saveLock: %v", err)
+	}
+	data, err := os.ReadFile(lockPath)
+	if err != nil {
+		t.Fatalf("read lock: %v", err)
+	}
+	if strings.Count(string(data), `"sha256": "`+sum+`"`) != 2 {
+		t.Fatalf("expected one checksum entry per archive skill, got %s", string(data))
+	}
+
+	entries, _, _, archives, err := loadLock(lockPath)
+	if err != nil {
+		t.Fatalf("loadLock: %v", err)
+	}
+	if len(entries) != 1 || entries[key] != "aaaa1111" {
+		t.Fatalf("expected only the git entry to be a locked rev, got %v", entries)
+	}
+	if archives[archive] != sum {
+		t.Fatalf("expected archive checksum %s, got %v", sum, archives)
+	}
+}
@@ -0,0 +1,500 @@
+package manifest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/tidwall/jsonc"
+)
+
+// editJSONC rewrites original so it holds the same data as updated while
+// keeping comments, trailing commas and key order. Values are diffed
+// structurally; arrays of objects with a "name" key (skills) are matched by
+// name so reordering never shows up as an edit. It fails when original
+// cannot be edited safely.
+func editJSONC(original []byte, updated []byte) ([]byte, error) {
+	oldRoot, err := parseJSONC(original)
+	if err != nil {
+		return nil, fmt.Errorf("parse existing manifest: %w", err)
+	}
+	if oldRoot.kind != '{' {
+		return nil, fmt.Errorf("existing manifest is not a JSON object")
+	}
+	newRoot, err := parseJSONC(updated)
+	if err != nil {
+		return nil, fmt.Errorf("parse updated manifest: %w", err)
+	}
+	if newRoot.kind != '{' {
+		return nil, fmt.Errorf("updated manifest is not a JSON object")
+	}
+
+	editor := &jsoncEditor{old: original, new: updated}
+	editor.patch(oldRoot, newRoot)
+	edited := editor.apply()
+
+	if !sameDocument(edited, updated) {
+		return nil, fmt.Errorf("in-place edit does not match the update")
+	}
+	return edited, nil
+}
+
+type jsonNode struct {
+	kind    byte // '{', '[', or 'v' for scalars
+	start   int
+	end     int
+	members []jsonMember
+}
+
+type jsonMember struct {
+	key   string
+	start int
+	value *jsonNode
+	// comma is the offset of the separator after the member, or -1.
+	comma int
+}
+
+func (node *jsonNode) member(key string) (int, bool) {
+	for index, member := range node.members {
+		if member.key == key {
+			return index, true
+		}
+	}
+	return -1, false
+}
+
+type jsoncParser struct {
+	data []byte
+	pos  int
+}
+
+func parseJSONC(data []byte) (*jsonNode, error) {
+	parser := &jsoncParser{data: data}
+	parser.skip()
+	node, err := parser.value()
+	if err != nil {
+		return nil, err
+	}
+	parser.skip()
+	if parser.pos != len(data) {
+		return nil, fmt.Errorf("unexpected data at offset %d", parser.pos)
+	}
+	return node, nil
+}
+
+// skip advances past whitespace and comments.
+func (parser *jsoncParser) skip() {
+	for parser.pos < len(parser.data) {
+		switch {
+		case isJSONSpace(parser.data[parser.pos]):
+			parser.pos++
+		case bytes.HasPrefix(parser.data[parser.pos:], []byte("//")):
+			end := bytes.IndexByte(parser.data[parser.pos:], '\n')
+			if end < 0 {
+				parser.pos = len(parser.data)
+				return
+			}
+			parser.pos += end
+		case bytes.HasPrefix(parser.data[parser.pos:], []byte("/*")):
+			end := bytes.Index(parser.data[parser.pos+2:], []byte("*/"))
+			if end < 0 {
+				parser.pos = len(parser.data)
+				return
+			}
+			parser.pos += end + 4
+		default:
+			return
+		}
+	}
+}
+
+func (parser *jsoncParser) value() (*jsonNode, error) {
+	if parser.pos >= len(parser.data) {
+		return nil, fmt.Errorf("unexpected end of document")
+	}
+	switch parser.data[parser.pos] {
+	case '{':
+		return parser.container('{', '}')
+	case '[':
+		return parser.container('[', ']')
+	case '"':
+		start := parser.pos
+		if err := parser.str(); err != nil {
+			return nil, err
+		}
+		return &jsonNode{kind: 'v', start: start, end: parser.pos}, nil
+	default:
+		start := parser.pos
+		for parser.pos < len(parser.data) {
+			c := parser.data[parser.pos]
+			if isJSONSpace(c) || c == ',' || c == '}' || c == ']' || c == '/' {
+				break
+			}
+			parser.pos++
+		}
+		if parser.pos == start {
+			return nil, fmt.Errorf("unexpected %q at offset %d", parser.data[start], start)
+		}
+		return &jsonNode{kind: 'v', start: start, end: parser.pos}, nil
+	}
+}
+
+func (parser *jsoncParser) container(open byte, close byte) (*jsonNode, error) {
+	node := &jsonNode{kind: open, start: parser.pos}
+	parser.pos++
+	for {
+		parser.skip()
+		if parser.pos >= len(parser.data) {
+			return nil, fmt.Errorf("unterminated %q at offset %d", open, node.start)
+		}
+		if parser.data[parser.pos] == close {
+			parser.pos++
+			node.end = parser.pos
+			return node, nil
+		}
+
+		member := jsonMember{start: parser.pos, comma: -1}
+		if open == '{' {
+			if parser.data[parser.pos] != '"' {
+				return nil, fmt.Errorf("expected key at offset %d", parser.pos)
+			}
+			keyStart := parser.pos
+			if err := parser.str(); err != nil {
+				return nil, err
+			}
+			if err := json.Unmarshal(parser.data[keyStart:parser.pos], &member.key); err != nil {
+				return nil, err
+			}
+			parser.skip()
+			if parser.pos >= len(parser.data) || parser.data[parser.pos] != ':' {
+				return nil, fmt.Errorf("expected ':' at offset %d", parser.pos)
+			}
+			parser.pos++
+			parser.skip()
+		}
+		value, err := parser.value()
+		if err != nil {
+			return nil, err
+		}
+		member.value = value
+
+		parser.skip()
+		if parser.pos < len(parser.data) && parser.data[parser.pos] == ',' {
+			member.comma = parser.pos
+			parser.pos++
+		} else if parser.pos < len(parser.data) && parser.data[parser.pos] != close {
+			return nil, fmt.Errorf("expected ',' at offset %d", parser.pos)
+		}
+		node.members = append(node.members, member)
+	}
+}
+
+func (parser *jsoncParser) str() error {
+	parser.pos++
+	for parser.pos < len(parser.data) {
+		switch parser.data[parser.pos] {
+		case '\\':
+			parser.pos += 2
+		case '"':
+			parser.pos++
+			return nil
+		default:
+			parser.pos++
+		}
+	}
+	return fmt.Errorf("unterminated string")
+}
+
+func isJSONSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+type jsonEdit struct {
+	start int
+	end   int
+	text  string
+}
+
+type jsoncEditor struct {
+	old   []byte
+	new   []byte
+	edits []jsonEdit
+}
+
+func (editor *jsoncEditor) patch(oldNode *jsonNode, newNode *jsonNode) {
+	switch {
+	case oldNode.kind == '{' && newNode.kind == '{':
+		editor.patchObject(oldNode, newNode)
+	case oldNode.kind == '[' && newNode.kind == '[' && namedElements(editor.old, oldNode) && namedElements(editor.new, newNode):
+		editor.patchNamedArray(oldNode, newNode)
+	default:
+		if !sameValue(editor.old[oldNode.start:oldNode.end], editor.new[newNode.start:newNode.end]) {
+			editor.replace(oldNode, newNode)
+		}
+	}
+}
+
+func (editor *jsoncEditor) patchObject(oldNode *jsonNode, newNode *jsonNode) {
+	removed := []int{}
+	for index, member := range oldNode.members {
+		if _, ok := newNode.member(member.key); !ok {
+			removed = append(removed, index)
+		}
+	}
+
+	added := []jsonMember{}
+	for _, member := range newNode.members {
+		index, ok := oldNode.member(member.key)
+		if !ok {
+			added = append(added, member)
+			continue
+		}
+		editor.patch(oldNode.members[index].value, member.value)
+	}
+
+	editor.rewriteMembers(oldNode, removed, added)
+}
+
+func (editor *jsoncEditor) patchNamedArray(oldNode *jsonNode, newNode *jsonNode) {
+	newByName := map[string]jsonMember{}
+	for _, member := range newNode.members {
+		newByName[elementName(editor.new, member.value)] = member
+	}
+	oldNames := map[string]bool{}
+	removed := []int{}
+	for index, member := range oldNode.members {
+		name := elementName(editor.old, member.value)
+		oldNames[name] = true
+		replacement, ok := newByName[name]
+		if !ok {
+			removed = append(removed, index)
+			continue
+		}
+		editor.patch(member.value, replacement.value)
+	}
+
+	added := []jsonMember{}
+	for _, member := range newNode.members {
+		if !oldNames[elementName(editor.new, member.value)] {
+			added = append(added, member)
+		}
+	}
+
+	editor.rewriteMembers(oldNode, removed, added)
+}
+
+// rewriteMembers deletes the removed members, along with comments on the
+// lines above them, and appends added after the last member that survives,
+// matching its indentation and trailing-comma style. Comments trailing a
+// kept member on its own line stay put.
+func (editor *jsoncEditor) rewriteMembers(container *jsonNode, removed []int, added []jsonMember) {
+	if len(removed) == 0 && len(added) == 0 {
+		return
+	}
+	gone := map[int]bool{}
+	for _, index := range removed {
+		gone[index] = true
+	}
+	lastKept := -1
+	for index := range container.members {
+		if !gone[index] {
+			lastKept = index
+		}
+	}
+
+	for _, index := range removed {
+		member := container.members[index]
+		start := container.start + 1
+		if index > 0 {
+			previous := container.members[index-1]
+			start = previous.value.end
+			if previous.comma >= 0 {
+				start = previous.comma + 1
+			}
+		}
+		start = editor.endOfLine(start)
+		end := member.value.end
+		if member.comma >= 0 {
+			end = member.comma + 1
+		}
+		end = editor.endOfLine(end)
+		editor.edits = append(editor.edits, jsonEdit{start: start, end: end})
+	}
+
+	last := container.members[len(container.members)-1:]
+	trailingComma := len(last) > 0 && last[0].comma >= 0
+
+	if lastKept < 0 {
+		if len(added) == 0 {
+			return
+		}
+		containerIndent := editor.lineIndent(editor.old, container.start)
+		if len(container.members) == 0 {
+			text := editor.renderMembers(added, containerIndent+"  ", false)
+			position := container.end - 1
+			editor.edits = append(editor.edits, jsonEdit{start: position, end: position, text: text + "\n" + containerIndent})
+			return
+		}
+		// Everything was removed; the last removal ends where the closing
+		// line begins.
+		position := editor.edits[len(editor.edits)-1].end
+		indent := editor.lineIndent(editor.old, container.members[0].start)
+		editor.edits = append(editor.edits, jsonEdit{start: position, end: position, text: editor.renderMembers(added, indent, trailingComma)})
+		return
+	}
+
+	kept := container.members[lastKept]
+	if len(added) == 0 {
+		// Dropping trailing members can leave the last kept member with a
+		// separator it did not have before.
+		if lastKept < len(container.members)-1 && kept.comma >= 0 && !trailingComma {
+			editor.edits = append(editor.edits, jsonEdit{start: kept.comma, end: kept.comma + 1})
+		}
+		return
+	}
+
+	after := kept.value.end
+	if kept.comma >= 0 {
+		after = kept.comma + 1
+	}
+	position := editor.endOfLine(after)
+	text := editor.renderMembers(added, editor.lineIndent(editor.old, kept.start), trailingComma)
+	if kept.comma < 0 {
+		// The separator goes right after the kept member, ahead of any
+		// comment on its line; with none, it leads the appended text, since
+		// two insertions at one offset would land in reverse.
+		if position == after {
+			text = "," + text
+		} else {
+			editor.edits = append(editor.edits, jsonEdit{start: after, end: after, text: ","})
+		}
+	}
+	editor.edits = append(editor.edits, jsonEdit{start: position, end: position, text: text})
+}
+
+func (editor *jsoncEditor) renderMembers(members []jsonMember, indent string, trailingComma bool) string {
+	var builder strings.Builder
+	for index, member := range members {
+		builder.WriteString("\n")
+		builder.WriteString(indent)
+		builder.WriteString(editor.reindent(member.start, member.value.end, indent))
+		if trailingComma || index < len(members)-1 {
+			builder.WriteString(",")
+		}
+	}
+	return builder.String()
+}
+
+func (editor *jsoncEditor) replace(oldNode *jsonNode, newNode *jsonNode) {
+	text := editor.reindent(newNode.start, newNode.end, editor.lineIndent(editor.old, oldNode.start))
+	editor.edits = append(editor.edits, jsonEdit{start: oldNode.start, end: oldNode.end, text: text})
+}
+
+// reindent copies new[start:end] and shifts its continuation lines from the
+// indentation they had in the new document to indent.
+func (editor *jsoncEditor) reindent(start int, end int, indent string) string {
+	text := string(editor.new[start:end])
+	from := editor.lineIndent(editor.new, start)
+	lines := strings.Split(text, "\n")
+	for index := 1; index < len(lines); index++ {
+		lines[index] = indent + strings.TrimPrefix(lines[index], from)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// endOfLine skips spaces and a same-line comment after offset, stopping
+// before the newline.
+func (editor *jsoncEditor) endOfLine(offset int) int {
+	for offset < len(editor.old) && (editor.old[offset] == ' ' || editor.old[offset] == '\t') {
+		offset++
+	}
+	if bytes.HasPrefix(editor.old[offset:], []byte("//")) {
+		if end := bytes.IndexByte(editor.old[offset:], '\n'); end >= 0 {
+			return offset + end
+		}
+		return len(editor.old)
+	}
+	return offset
+}
+
+func (editor *jsoncEditor) lineIndent(data []byte, offset int) string {
+	lineStart := bytes.LastIndexByte(data[:offset], '\n') + 1
+	end := lineStart
+	for end < len(data) && (data[end] == ' ' || data[end] == '\t') {
+		end++
+	}
+	return string(data[lineStart:end])
+}
+
+// apply splices edits from the end of the document backwards. Edits that
+// start at the same offset apply in the order they were recorded.
+func (editor *jsoncEditor) apply() []byte {
+	edits := append([]jsonEdit{}, editor.edits...)
+	sort.SliceStable(edits, func(i, j int) bool {
+		return edits[i].start > edits[j].start
+	})
+	result := append([]byte{}, editor.old...)
+	for _, edit := range edits {
+		result = append(result[:edit.start], append([]byte(edit.text), result[edit.end:]...)...)
+	}
+	return result
+}
+
+func namedElements(data []byte, node *jsonNode) bool {
+	for _, member := range node.members {
+		if elementName(data, member.value) == "" {
+			return false
+		}
+	}
+	return true
+}
+
+func elementName(data []byte, node *jsonNode) string {
+	if node.kind != '{' {
+		return ""
+	}
+	index, ok := node.member("name")
+	if !ok {
+		return ""
+	}
+	value := node.members[index].value
+	var name string
+	if err := json.Unmarshal(data[value.start:value.end], &name); err != nil {
+		return ""
+	}
+	return name
+}
+
+func sameValue(left []byte, right []byte) bool {
+	var leftValue any
+	var rightValue any
+	if err := json.Unmarshal(jsonc.ToJSON(left), &leftValue); err != nil {
+		return false
+	}
+	if err := json.Unmarshal(jsonc.ToJSON(right), &rightValue); err != nil {
+		return false
+	}
+	leftData, _ := json.Marshal(leftValue)
+	rightData, _ := json.Marshal(rightValue)
+	return bytes.Equal(leftData, rightData)
+}
+
+// sameDocument reports whether edited decodes to the same config as
+// updated, ignoring skill order.
+func sameDocument(edited []byte, updated []byte) bool {
+	var editedConfig Config
+	var updatedConfig Config
+	if err := json.Unmarshal(jsonc.ToJSON(edited), &editedConfig); err != nil {
+		return false
+	}
+	if err := json.Unmarshal(updated, &updatedConfig); err != nil {
+		return false
+	}
+	SortSkills(editedConfig.Skills)
+	SortSkills(updatedConfig.Skills)
+	editedData, _ := json.Marshal(editedConfig)
+	updatedData, _ := json.Marshal(updatedConfig)
+	return bytes.Equal(editedData, updatedData)
+}
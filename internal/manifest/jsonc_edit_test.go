@@ -0,0 +1,154 @@
+package manifest
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const commentedManifest = `// team skills
+{
+  "skills": [
+    // keep: used by onboarding
+    {
+      "origin": "https://github.com/acme/skills", // upstream
+      "name": "zeta",
+      "subdir": "zeta",
+      "version": "v1.0.0",
+    },
+    /* reviewer helper */
+    {
+      "name": "alpha",
+      "origin": "https://github.com/acme/skills",
+      "subdir": "alpha",
+      "version": "v1.0.0",
+    },
+  ],
+}
+`
+
+func TestSavePreservesCommentsAndLayout(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "skills.jsonc")
+	if err := os.WriteFile(path, []byte(commentedManifest), 0o644); err != nil {
+		t.Fatalf("write manifest: %v", err)
+	}
+
+	config, err := Load(path)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	config.Skills[0].Version = "v1.1.0"
+	if err := Save(path, config); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read manifest: %v", err)
+	}
+	expected := strings.Replace(commentedManifest, `"zeta",
+      "version": "v1.0.0"`, `"zeta",
+      "version": "v1.1.0"`, 1)
+	if string(data) != expected {
+		t.Fatalf("expected only the version to change, got:\n%s", data)
+	}
+}
+
+func TestSaveEditsSkillsInPlace(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "skills.jsonc")
+	if err := os.WriteFile(path, []byte(commentedManifest), 0o644); err != nil {
+		t.Fatalf("write manifest: %v", err)
+	}
+
+	config, err := Load(path)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	config.RemoveSkill("alpha")
+	config.UpsertSkill(Skill{Name: "beta", Origin: "https://github.com/acme/other", Version: "v2.0.0"})
+	config.Replace = map[string]string{"https://github.com/acme/other": filepath.Dir(path)}
+	if err := Save(path, config); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read manifest: %v", err)
+	}
+	content := string(data)
+	for _, want := range []string{"// team skills", "// keep: used by onboarding", "// upstream", `"name": "beta"`, `"replace"`} {
+		if !strings.Contains(content, want) {
+			t.Fatalf("expected %q in manifest:\n%s", want, content)
+		}
+	}
+	for _, unwanted := range []string{"reviewer helper", `"alpha"`} {
+		if strings.Contains(content, unwanted) {
+			t.Fatalf("expected %q to be removed:\n%s", unwanted, content)
+		}
+	}
+	if strings.Index(content, `"zeta"`) > strings.Index(content, `"beta"`) {
+		t.Fatalf("expected existing skill order to be kept:\n%s", content)
+	}
+
+	reloaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if len(reloaded.Skills) != 2 || reloaded.Replace["https://github.com/acme/other"] == "" {
+		t.Fatalf("unexpected reloaded config: %+v", reloaded)
+	}
+}
+
+func TestEditJSONCRemovesAllAndAppends(t *testing.T) {
+	original := []byte("{\n  \"skills\": [\n    {\"name\": \"a\", \"origin\": \"/a\"}\n  ]\n}\n")
+	updated := []byte("{\n  \"skills\": [\n    {\n      \"name\": \"b\",\n      \"origin\": \"/b\"\n    }\n  ]\n}")
+
+	edited, err := editJSONC(original, updated)
+	if err != nil {
+		t.Fatalf("expected edit to succeed: %v", err)
+	}
+	expected := "{\n  \"skills\": [\n    {\n      \"name\": \"b\",\n      \"origin\": \"/b\"\n    }\n  ]\n}\n"
+	if string(edited) != expected {
+		t.Fatalf("unexpected document:\n%s", edited)
+	}
+}
+
+func TestSaveRefusesToDropCommentsUnlessAllowed(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "skills.jsonc")
+	original := "// not an object\n[]\n"
+	if err := os.WriteFile(path, []byte(original), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	config := Config{Skills: []Skill{{Name: "a", Origin: "/a"}}}
+
+	err := Save(path, config)
+	if err == nil || !strings.Contains(err.Error(), "ASM_REWRITE_MANIFEST") {
+		t.Fatalf("expected the edit to be refused, got %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != original {
+		t.Fatalf("expected the manifest untouched, got %q", data)
+	}
+
+	t.Setenv("ASM_REWRITE_MANIFEST", "1")
+	if err := Save(path, config); err != nil {
+		t.Fatalf("save with rewrite allowed: %v", err)
+	}
+	if data, _ := os.ReadFile(path); strings.Contains(string(data), "not an object") {
+		t.Fatalf("expected the manifest rewritten, got %q", data)
+	}
+}
+
+func TestEditJSONCAppendsAfterMemberWithoutComma(t *testing.T) {
+	original := []byte("{\n  \"skills\": [\n    {\"name\": \"a\", \"origin\": \"/a\"}\n  ]\n}")
+	updated := []byte("{\n  \"skills\": [\n    {\"name\": \"a\", \"origin\": \"/a\"},\n    {\"name\": \"b\", \"origin\": \"/b\"}\n  ]\n}")
+
+	edited, err := editJSONC(original, updated)
+	if err != nil {
+		t.Fatalf("expected edit to succeed: %v", err)
+	}
+	expected := "{\n  \"skills\": [\n    {\"name\": \"a\", \"origin\": \"/a\"},\n    {\"name\": \"b\", \"origin\": \"/b\"}\n  ]\n}"
+	if string(edited) != expected {
+		t.Fatalf("unexpected document:\n%s", edited)
+	}
+}
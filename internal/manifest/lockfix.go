@@ -0,0 +1,138 @@
+package manifest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/tidwall/jsonc"
+)
+
+// ErrLockConflict marks a lockfile left conflicted by a merge, either with
+// conflict markers or with two revisions for one origin and version.
+var ErrLockConflict = errors.New("lockfile has merge conflicts")
+
+// LockRecovery holds what both sides of a conflicted lockfile agree on, plus
+// the candidate revisions for keys they disagree on.
+type LockRecovery struct {
+	Entries    map[LockKey]string
+	Hashes     map[HashKey]string
+	Signers    map[LockKey]string
+	Candidates map[LockKey][]string
+
+	candidateHashes  map[LockKey]map[string]map[HashKey]string
+	candidateSigners map[LockKey]map[string]string
+}
+
+// RecoverLock parses every side of a conflicted lockfile.
+func RecoverLock(data []byte) (LockRecovery, error) {
+	recovery := LockRecovery{
+		Entries:          map[LockKey]string{},
+		Hashes:           map[HashKey]string{},
+		Signers:          map[LockKey]string{},
+		Candidates:       map[LockKey][]string{},
+		candidateHashes:  map[LockKey]map[string]map[HashKey]string{},
+		candidateSigners: map[LockKey]map[string]string{},
+	}
+
+	for index, side := range conflictSides(data) {
+		var parsed lockFile
+		if err := json.Unmarshal(jsonc.ToJSON(side), &parsed); err != nil {
+			return LockRecovery{}, fmt.Errorf("parse side %d of conflicted lock: %w", index+1, err)
+		}
+		for _, entry := range parsed.Entries {
+			if entry.Origin == "" || entry.Version == "" || entry.Rev == "" {
+				continue
+			}
+			key := LockKey{Origin: entry.Origin, Version: entry.Version}
+			if !slices.Contains(recovery.Candidates[key], entry.Rev) {
+				recovery.Candidates[key] = append(recovery.Candidates[key], entry.Rev)
+			}
+			if entry.Signer != "" {
+				if recovery.candidateSigners[key] == nil {
+					recovery.candidateSigners[key] = map[string]string{}
+				}
+				recovery.candidateSigners[key][entry.Rev] = entry.Signer
+			}
+			if entry.Hash == "" {
+				continue
+			}
+			if recovery.candidateHashes[key] == nil {
+				recovery.candidateHashes[key] = map[string]map[HashKey]string{}
+			}
+			if recovery.candidateHashes[key][entry.Rev] == nil {
+				recovery.candidateHashes[key][entry.Rev] = map[HashKey]string{}
+			}
+			hashKey := HashKey{Origin: entry.Origin, Version: entry.Version, Subdir: entry.Subdir}
+			recovery.candidateHashes[key][entry.Rev][hashKey] = entry.Hash
+		}
+	}
+
+	for key, revs := range recovery.Candidates {
+		if len(revs) != 1 {
+			sort.Strings(revs)
+			continue
+		}
+		recovery.Choose(key, revs[0])
+	}
+	return recovery, nil
+}
+
+// Choose settles key on rev, keeping the hashes and signer recorded
+// alongside it.
+func (recovery LockRecovery) Choose(key LockKey, rev string) {
+	recovery.Entries[key] = rev
+	delete(recovery.Candidates, key)
+	if signer, ok := recovery.candidateSigners[key][rev]; ok {
+		recovery.Signers[key] = signer
+	}
+	for hashKey, hash := range recovery.candidateHashes[key][rev] {
+		recovery.Hashes[hashKey] = hash
+	}
+}
+
+// conflictSides rebuilds each side of a file with git conflict markers. A
+// file without markers is returned as its only side.
+func conflictSides(data []byte) [][]byte {
+	if !hasConflictMarkers(data) {
+		return [][]byte{data}
+	}
+
+	var ours, theirs bytes.Buffer
+	// section is 0 outside a conflict, then 1 (ours), 2 (base) or 3 (theirs).
+	section := 0
+	for _, line := range strings.SplitAfter(string(data), "\n") {
+		switch {
+		case strings.HasPrefix(line, "<<<<<<<"):
+			section = 1
+			continue
+		case strings.HasPrefix(line, "|||||||") && section == 1:
+			section = 2
+			continue
+		case strings.HasPrefix(line, "=======") && section > 0:
+			section = 3
+			continue
+		case strings.HasPrefix(line, ">>>>>>>") && section == 3:
+			section = 0
+			continue
+		}
+		switch section {
+		case 0:
+			ours.WriteString(line)
+			theirs.WriteString(line)
+		case 1:
+			ours.WriteString(line)
+		case 3:
+			theirs.WriteString(line)
+		}
+	}
+	return [][]byte{ours.Bytes(), theirs.Bytes()}
+}
+
+func hasConflictMarkers(data []byte) bool {
+	return bytes.HasPrefix(data, []byte("<<<<<<<")) || bytes.Contains(data, []byte("\n<<<<<<<"))
+}
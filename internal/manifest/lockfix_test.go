@@ -0,0 +1,67 @@
+package manifest
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+const conflictedLock = `{
+  "schema": 2,
+  "entries": [
+    {"origin": "https://github.com/acme/a", "version": "v1.0.0", "rev": "aaa", "subdir": "one", "name": "one", "hash": "h1:one"},
+<<<<<<< HEAD
+    {"origin": "https://github.com/acme/b", "version": "v1.0.0", "rev": "bbb", "hash": "h1:ours"},
+    {"origin": "https://github.com/acme/c", "version": "v2.0.0", "rev": "ccc"}
+||||||| base
+    {"origin": "https://github.com/acme/b", "version": "v1.0.0", "rev": "old"}
+=======
+    {"origin": "https://github.com/acme/b", "version": "v1.0.0", "rev": "bbx", "hash": "h1:theirs"},
+    {"origin": "https://github.com/acme/d", "version": "v3.0.0", "rev": "ddd"}
+>>>>>>> feature
+  ]
+}
+`
+
+func TestRecoverLockSplitsConflictSides(t *testing.T) {
+	recovery, err := RecoverLock([]byte(conflictedLock))
+	if err != nil {
+		t.Fatalf("recover: %v", err)
+	}
+
+	for key, rev := range map[LockKey]string{
+		{Origin: "https://github.com/acme/a", Version: "v1.0.0"}: "aaa",
+		{Origin: "https://github.com/acme/c", Version: "v2.0.0"}: "ccc",
+		{Origin: "https://github.com/acme/d", Version: "v3.0.0"}: "ddd",
+	} {
+		if recovery.Entries[key] != rev {
+			t.Fatalf("expected %s to keep %s, got %q", key.Origin, rev, recovery.Entries[key])
+		}
+	}
+
+	ambiguous := LockKey{Origin: "https://github.com/acme/b", Version: "v1.0.0"}
+	if _, ok := recovery.Entries[ambiguous]; ok {
+		t.Fatalf("expected ambiguous entry to be left out")
+	}
+	candidates := recovery.Candidates[ambiguous]
+	if len(candidates) != 2 || candidates[0] != "bbb" || candidates[1] != "bbx" {
+		t.Fatalf("expected both sides as candidates, got %v", candidates)
+	}
+
+	recovery.Choose(ambiguous, "bbx")
+	if recovery.Entries[ambiguous] != "bbx" {
+		t.Fatalf("expected chosen rev")
+	}
+	if hash := recovery.Hashes[HashKey{Origin: ambiguous.Origin, Version: ambiguous.Version}]; hash != "h1:theirs" {
+		t.Fatalf("expected hash from the chosen side, got %q", hash)
+	}
+}
+
+func TestLoadLockReportsConflict(t *testing.T) {
+	root := t.TempDir()
+	writeLockFile(t, root, conflictedLock)
+
+	if _, err := LoadLock(filepath.Join(root, "skills-lock.json")); !errors.Is(err, ErrLockConflict) {
+		t.Fatalf("expected lock conflict error, got %v", err)
+	}
+}
@@ -1,12 +1,57 @@
 package manifest
 
-func (config Config) GitOriginVersions() map[string]string {
-	origins := make(map[string]string)
+import (
+	"slices"
+	"sort"
+)
+
+// GitLockKeys returns each distinct origin/version pair used by git skills.
+func (config Config) GitLockKeys() []LockKey {
+	seen := make(map[LockKey]struct{})
+	keys := []LockKey{}
 	for _, skill := range config.Skills {
 		if skill.Version == "" {
 			continue
 		}
-		origins[skill.Origin] = skill.Version
+		key := skill.LockKey()
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].Origin != keys[j].Origin {
+			return keys[i].Origin < keys[j].Origin
+		}
+		return keys[i].Version < keys[j].Version
+	})
+	return keys
+}
+
+// GitSubdirs returns the subdirs git skills use, keyed by origin/version. An
+// empty subdir stands for the whole repo.
+func (config Config) GitSubdirs() map[LockKey][]string {
+	subdirs := map[LockKey][]string{}
+	for _, skill := range config.Skills {
+		if skill.Version == "" {
+			continue
+		}
+		key := skill.LockKey()
+		if !slices.Contains(subdirs[key], skill.Subdir) {
+			subdirs[key] = append(subdirs[key], skill.Subdir)
+		}
+	}
+	return subdirs
+}
+
+// ArchiveSums returns the pinned sha256 of each archive origin.
+func (config Config) ArchiveSums() map[string]string {
+	sums := map[string]string{}
+	for _, skill := range config.Skills {
+		if skill.Sha256 != "" {
+			sums[skill.Origin] = skill.Sha256
+		}
 	}
-	return origins
+	return sums
 }
@@ -1,20 +1,83 @@
 package manifest
 
-import "path/filepath"
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/jmmarotta/agent_skills_manager/internal/debug"
+	"github.com/jmmarotta/agent_skills_manager/internal/envflag"
+)
+
+const stateLockFilename = "asm.lock"
+
+const (
+	sharedStoreEnv = "ASM_SHARED_STORE"
+	storeDirEnv    = "ASM_STORE_DIR"
+)
 
 type Paths struct {
-	Root      string
-	StoreDir  string
-	CacheDir  string
-	SkillsDir string
+	Root         string
+	StateDir     string
+	StoreDir     string
+	CheckoutsDir string
+	CacheDir     string
+	SkillsDir    string
+	// SharedStore is set when StoreDir is the user-level store that other
+	// repos fetch into too.
+	SharedStore bool
 }
 
 func RepoPaths(repoRoot string) Paths {
 	base := filepath.Join(repoRoot, ".asm")
-	return Paths{
+	return withStore(Paths{
 		Root:      repoRoot,
-		StoreDir:  filepath.Join(base, "store"),
+		StateDir:  base,
 		CacheDir:  filepath.Join(base, "cache"),
 		SkillsDir: filepath.Join(repoRoot, "skills"),
+	}, filepath.Join(base, "store"))
+}
+
+// StateLockPath is the file mutating commands lock while they change the
+// state under paths.
+func StateLockPath(paths Paths) string {
+	return filepath.Join(paths.StateDir, stateLockFilename)
+}
+
+// withStore places the store under localStore unless a shared store is
+// configured. Checkouts always stay in localStore, so each repo links into
+// its own exported revisions.
+func withStore(paths Paths, localStore string) Paths {
+	paths.StoreDir = localStore
+	paths.CheckoutsDir = filepath.Join(localStore, "checkouts")
+	if dir, ok := sharedStoreDir(); ok {
+		paths.StoreDir = dir
+		paths.SharedStore = true
+	}
+	return paths
+}
+
+// sharedStoreDir returns the user-level store shared by every repo: the
+// directory named by $ASM_STORE_DIR, or with $ASM_SHARED_STORE set,
+// $XDG_CACHE_HOME/asm/store falling back to ~/.cache/asm/store.
+func sharedStoreDir() (string, bool) {
+	if dir := os.Getenv(storeDirEnv); dir != "" {
+		abs, err := filepath.Abs(dir)
+		if err != nil {
+			debug.Logf("shared store dir=%q err=%v", dir, err)
+			return "", false
+		}
+		return abs, true
+	}
+	if !envflag.Enabled(sharedStoreEnv) {
+		return "", false
+	}
+	if dir := os.Getenv("XDG_CACHE_HOME"); dir != "" {
+		return filepath.Join(dir, "asm", "store"), true
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		debug.Logf("shared store home err=%v", err)
+		return "", false
 	}
+	return filepath.Join(home, ".cache", "asm", "store"), true
 }
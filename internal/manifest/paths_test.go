@@ -0,0 +1,37 @@
+package manifest
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestRepoPathsUseSharedStore(t *testing.T) {
+	root := t.TempDir()
+	cacheDir := t.TempDir()
+	t.Setenv("ASM_STORE_DIR", "")
+	t.Setenv("ASM_SHARED_STORE", "")
+	t.Setenv("XDG_CACHE_HOME", cacheDir)
+
+	local := RepoPaths(root)
+	if local.SharedStore || local.StoreDir != filepath.Join(root, ".asm", "store") {
+		t.Fatalf("expected a per-repo store, got %+v", local)
+	}
+	if local.CheckoutsDir != filepath.Join(root, ".asm", "store", "checkouts") {
+		t.Fatalf("unexpected checkouts dir %s", local.CheckoutsDir)
+	}
+
+	t.Setenv("ASM_SHARED_STORE", "1")
+	shared := RepoPaths(root)
+	if !shared.SharedStore || shared.StoreDir != filepath.Join(cacheDir, "asm", "store") {
+		t.Fatalf("expected the shared store under XDG_CACHE_HOME, got %+v", shared)
+	}
+	if shared.CheckoutsDir != local.CheckoutsDir {
+		t.Fatalf("expected checkouts to stay in the repo, got %s", shared.CheckoutsDir)
+	}
+
+	storeDir := t.TempDir()
+	t.Setenv("ASM_STORE_DIR", storeDir)
+	if explicit := RepoPaths(root); explicit.StoreDir != storeDir {
+		t.Fatalf("expected ASM_STORE_DIR to win, got %s", explicit.StoreDir)
+	}
+}
@@ -0,0 +1,120 @@
+package manifest
+
+import "sort"
+
+const versionPattern = `^v(0|[1-9][0-9]*)\.(0|[1-9][0-9]*)\.(0|[1-9][0-9]*)(-[0-9A-Za-z.-]+)?(\+[0-9A-Za-z.-]+)?$`
+
+type schemaObject = map[string]any
+
+// Schema returns a JSON Schema for skills.jsonc. Point an editor at it with a
+// "$schema" key in the manifest.
+func Schema() map[string]any {
+	stringMap := func(description string) schemaObject {
+		return schemaObject{
+			"type":                 "object",
+			"description":          description,
+			"additionalProperties": schemaObject{"type": "string"},
+		}
+	}
+
+	return schemaObject{
+		"$schema":              "https://json-schema.org/draft/2020-12/schema",
+		"title":                "skills.jsonc",
+		"description":          "Agent skills manifest managed by asm.",
+		"type":                 "object",
+		"additionalProperties": false,
+		"properties": schemaObject{
+			"$schema": schemaObject{"type": "string"},
+			"extends": schemaObject{
+				"description": "Base manifest whose skills and replace rules are merged in first.",
+				"oneOf": []any{
+					schemaObject{"type": "string", "description": "Path to a local manifest."},
+					extendsSchema(),
+				},
+			},
+			"skills": schemaObject{
+				"type":  "array",
+				"items": skillSchema(),
+			},
+			"replace":     stringMap("Local working copies used instead of a git origin."),
+			"constraints": stringMap("Semver ranges applied to every skill from an origin."),
+			"verify": schemaObject{
+				"type":                 "object",
+				"description":          "Keys allowed to sign each origin's release tags or commits.",
+				"additionalProperties": verifySchema(),
+			},
+		},
+		"required": []string{"skills"},
+	}
+}
+
+func skillSchema() schemaObject {
+	return schemaObject{
+		"type":                 "object",
+		"additionalProperties": false,
+		"required":             []string{"name", "origin"},
+		"properties": schemaObject{
+			"name":   schemaObject{"type": "string", "minLength": 1, "description": "Link name under skills/."},
+			"origin": schemaObject{"type": "string", "minLength": 1, "description": "Git URL, archive URL or local directory."},
+			"subdir": schemaObject{"type": "string", "description": "Skill directory inside the origin."},
+			"version": schemaObject{
+				"type":        "string",
+				"pattern":     versionPattern,
+				"description": "Semver tag or pseudo-version; required for git origins.",
+			},
+			"constraint": schemaObject{"type": "string", "description": "Semver range followed by asm update, such as ^1.2."},
+			"track":      schemaObject{"type": "string", "description": "Branch followed by asm update."},
+			"groups": schemaObject{
+				"type":        "array",
+				"description": "Groups selected with asm install --profile and --without.",
+				"items":       schemaObject{"type": "string", "pattern": `^[^\s,]+$`},
+				"uniqueItems": true,
+			},
+			"sha256": schemaObject{
+				"type":        "string",
+				"pattern":     "^[0-9a-f]{64}$",
+				"description": "SHA-256 of the archive download; required for archive origins.",
+			},
+		},
+	}
+}
+
+func verifySchema() schemaObject {
+	return schemaObject{
+		"type":                 "object",
+		"additionalProperties": false,
+		"required":             []string{"keys", "keyring"},
+		"properties": schemaObject{
+			"keys": schemaObject{
+				"type":        "array",
+				"description": "Allowed signer fingerprints: 40-digit OpenPGP or SSH SHA256:... fingerprints.",
+				"items":       schemaObject{"type": "string"},
+				"minItems":    1,
+			},
+			"keyring": schemaObject{"type": "string", "description": "Armored OpenPGP keyring or SSH allowed signers file, relative to the manifest."},
+		},
+	}
+}
+
+func extendsSchema() schemaObject {
+	return schemaObject{
+		"type":                 "object",
+		"additionalProperties": false,
+		"properties": schemaObject{
+			"origin":  schemaObject{"type": "string", "description": "Git URL holding the base manifest."},
+			"version": schemaObject{"type": "string", "pattern": versionPattern},
+			"path":    schemaObject{"type": "string", "description": "Manifest path, relative to the repo for git bases."},
+		},
+	}
+}
+
+// schemaKeys lists the properties an object schema allows.
+func schemaKeys(schema schemaObject) []string {
+	properties, _ := schema["properties"].(schemaObject)
+	keys := make([]string, 0, len(properties))
+	for key := range properties {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
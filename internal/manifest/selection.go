@@ -0,0 +1,117 @@
+package manifest
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+const selectionFilename = "selection.json"
+
+// Selection picks which skills an install links. Ungrouped skills are always
+// selected; grouped skills need one of their groups in Profiles when any are
+// set. A group in Without excludes a skill regardless.
+type Selection struct {
+	Profiles []string `json:"profiles,omitempty"`
+	Without  []string `json:"without,omitempty"`
+}
+
+func ValidateGroupName(name string) error {
+	if name == "" || strings.ContainsAny(name, " \t\n,") {
+		return fmt.Errorf("invalid group %q", name)
+	}
+	return nil
+}
+
+func (selection Selection) IsEmpty() bool {
+	return len(selection.Profiles) == 0 && len(selection.Without) == 0
+}
+
+func (selection Selection) Selects(skill Skill) bool {
+	for _, group := range skill.Groups {
+		if slices.Contains(selection.Without, group) {
+			return false
+		}
+	}
+	if len(skill.Groups) == 0 || len(selection.Profiles) == 0 {
+		return true
+	}
+	for _, group := range skill.Groups {
+		if slices.Contains(selection.Profiles, group) {
+			return true
+		}
+	}
+	return false
+}
+
+// Filter splits skills into the selected ones and the names of the rest.
+func (selection Selection) Filter(skills []Skill) ([]Skill, []string) {
+	selected := make([]Skill, 0, len(skills))
+	skipped := []string{}
+	for _, skill := range skills {
+		if selection.Selects(skill) {
+			selected = append(selected, skill)
+			continue
+		}
+		skipped = append(skipped, skill.Name)
+	}
+	return selected, skipped
+}
+
+// UnknownGroups returns selected groups that no skill belongs to.
+func (selection Selection) UnknownGroups(skills []Skill) []string {
+	known := map[string]bool{}
+	for _, skill := range skills {
+		for _, group := range skill.Groups {
+			known[group] = true
+		}
+	}
+	unknown := []string{}
+	for _, group := range append(append([]string{}, selection.Profiles...), selection.Without...) {
+		if !known[group] && !slices.Contains(unknown, group) {
+			unknown = append(unknown, group)
+		}
+	}
+	return unknown
+}
+
+func SelectionPath(paths Paths) string {
+	return filepath.Join(paths.StateDir, selectionFilename)
+}
+
+// LoadSelection reads the remembered selection; a missing file selects
+// everything.
+func LoadSelection(path string) (Selection, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return Selection{}, nil
+		}
+		return Selection{}, err
+	}
+	var selection Selection
+	if err := json.Unmarshal(data, &selection); err != nil {
+		return Selection{}, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return selection, nil
+}
+
+func SaveSelection(path string, selection Selection) error {
+	if selection.IsEmpty() {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	payload, err := json.MarshalIndent(selection, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(payload, '\n'), 0o644)
+}
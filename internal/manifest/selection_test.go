@@ -0,0 +1,80 @@
+package manifest
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestSelectionFiltersByGroups(t *testing.T) {
+	skills := []Skill{
+		{Name: "common"},
+		{Name: "lint", Groups: []string{"ci"}},
+		{Name: "docs", Groups: []string{"docs"}},
+		{Name: "review", Groups: []string{"ci", "docs"}},
+	}
+
+	selected, skipped := Selection{}.Filter(skills)
+	if len(selected) != 4 || len(skipped) != 0 {
+		t.Fatalf("expected every skill selected, got %d skipped", len(skipped))
+	}
+
+	selected, _ = Selection{Profiles: []string{"ci"}}.Filter(skills)
+	if err := assertSkillNames(selected, "common", "lint", "review"); err != "" {
+		t.Fatal(err)
+	}
+
+	selected, _ = Selection{Profiles: []string{"ci"}, Without: []string{"docs"}}.Filter(skills)
+	if err := assertSkillNames(selected, "common", "lint"); err != "" {
+		t.Fatal(err)
+	}
+
+	if unknown := (Selection{Profiles: []string{"ci", "nightly"}}).UnknownGroups(skills); len(unknown) != 1 || unknown[0] != "nightly" {
+		t.Fatalf("expected nightly to be unknown, got %v", unknown)
+	}
+}
+
+func TestSelectionRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "selection.json")
+	if err := SaveSelection(path, Selection{Profiles: []string{"ci"}}); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	selection, err := LoadSelection(path)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if len(selection.Profiles) != 1 || selection.Profiles[0] != "ci" {
+		t.Fatalf("unexpected selection %+v", selection)
+	}
+
+	if err := SaveSelection(path, Selection{}); err != nil {
+		t.Fatalf("clear: %v", err)
+	}
+	selection, err = LoadSelection(path)
+	if err != nil {
+		t.Fatalf("load cleared: %v", err)
+	}
+	if !selection.IsEmpty() {
+		t.Fatalf("expected empty selection, got %+v", selection)
+	}
+}
+
+func TestConfigValidatesGroups(t *testing.T) {
+	config := Config{
+		Skills: []Skill{{Name: "local", Origin: "/tmp/local", Groups: []string{"ci docs"}}},
+	}
+	if err := config.Validate(); err == nil {
+		t.Fatalf("expected invalid group error")
+	}
+}
+
+func assertSkillNames(skills []Skill, names ...string) string {
+	if len(skills) != len(names) {
+		return "unexpected skill count"
+	}
+	for index, name := range names {
+		if skills[index].Name != name {
+			return "expected " + name + ", got " + skills[index].Name
+		}
+	}
+	return ""
+}
@@ -22,12 +22,15 @@ func (err MissingOriginPathError) Error() string {
 	return fmt.Sprintf("missing origin path for %s (skill %s)", err.Origin, err.Skill)
 }
 
-func (config Config) ResolveSkillPaths(originPaths map[string]string) ([]SkillPath, error) {
+// ResolveSkillPaths returns where each skill's files are. originPaths holds
+// the checkout of each git origin and version, and the extracted directory of
+// each archive origin under its LockKey, which has no version.
+func (config Config) ResolveSkillPaths(originPaths map[LockKey]string) ([]SkillPath, error) {
 	paths := make([]SkillPath, 0, len(config.Skills))
 	for _, skill := range config.Skills {
 		base := skill.Origin
-		if skill.Version != "" {
-			resolved, ok := originPaths[skill.Origin]
+		if skill.Version != "" || skill.Sha256 != "" {
+			resolved, ok := originPaths[skill.LockKey()]
 			if !ok || resolved == "" {
 				return nil, MissingOriginPathError{Origin: skill.Origin, Skill: skill.Name}
 			}
@@ -6,27 +6,30 @@ import (
 	"testing"
 )
 
-func TestGitOriginVersions(t *testing.T) {
+func TestGitLockKeys(t *testing.T) {
 	config := Config{
 		Skills: []Skill{
 			{Name: "one", Origin: "https://example.com/repo-a", Version: "v1.0.0"},
 			{Name: "two", Origin: "/tmp/local"},
 			{Name: "three", Origin: "https://example.com/repo-b", Version: "v2.0.0"},
+			{Name: "four", Origin: "https://example.com/repo-a", Version: "v1.2.0"},
+			{Name: "five", Origin: "https://example.com/repo-a", Version: "v1.0.0", Subdir: "five"},
 		},
 	}
 
-	got := config.GitOriginVersions()
-	if len(got) != 2 {
-		t.Fatalf("expected 2 origins, got %d", len(got))
+	got := config.GitLockKeys()
+	expected := []LockKey{
+		{Origin: "https://example.com/repo-a", Version: "v1.0.0"},
+		{Origin: "https://example.com/repo-a", Version: "v1.2.0"},
+		{Origin: "https://example.com/repo-b", Version: "v2.0.0"},
 	}
-	if got["https://example.com/repo-a"] != "v1.0.0" {
-		t.Fatalf("expected repo-a version v1.0.0, got %q", got["https://example.com/repo-a"])
+	if len(got) != len(expected) {
+		t.Fatalf("expected %d keys, got %d (%v)", len(expected), len(got), got)
 	}
-	if got["https://example.com/repo-b"] != "v2.0.0" {
-		t.Fatalf("expected repo-b version v2.0.0, got %q", got["https://example.com/repo-b"])
-	}
-	if _, ok := got["/tmp/local"]; ok {
-		t.Fatalf("expected path origin to be excluded")
+	for index, key := range expected {
+		if got[index] != key {
+			t.Fatalf("expected key %d to be %v, got %v", index, key, got[index])
+		}
 	}
 }
 
@@ -38,8 +41,8 @@ func TestResolveSkillPaths(t *testing.T) {
 		},
 	}
 
-	originPaths := map[string]string{
-		"https://example.com/repo": "/store/repo",
+	originPaths := map[LockKey]string{
+		{Origin: "https://example.com/repo", Version: "v1.0.0"}: "/store/repo",
 	}
 
 	paths, err := config.ResolveSkillPaths(originPaths)
@@ -60,6 +63,29 @@ func TestResolveSkillPaths(t *testing.T) {
 	}
 }
 
+func TestResolveSkillPathsPerVersion(t *testing.T) {
+	config := Config{
+		Skills: []Skill{
+			{Name: "foo", Origin: "https://example.com/repo", Subdir: "plugins/foo", Version: "v1.2.0"},
+			{Name: "bar", Origin: "https://example.com/repo", Subdir: "plugins/bar", Version: "v1.4.0"},
+		},
+	}
+
+	paths, err := config.ResolveSkillPaths(map[LockKey]string{
+		{Origin: "https://example.com/repo", Version: "v1.2.0"}: "/store/rev-a",
+		{Origin: "https://example.com/repo", Version: "v1.4.0"}: "/store/rev-b",
+	})
+	if err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+	if paths[0].Path != filepath.Join("/store/rev-a", "plugins", "foo") {
+		t.Fatalf("expected foo from rev-a, got %s", paths[0].Path)
+	}
+	if paths[1].Path != filepath.Join("/store/rev-b", "plugins", "bar") {
+		t.Fatalf("expected bar from rev-b, got %s", paths[1].Path)
+	}
+}
+
 func TestResolveSkillPathsMissingOrigin(t *testing.T) {
 	config := Config{
 		Skills: []Skill{
@@ -67,7 +93,7 @@ func TestResolveSkillPathsMissingOrigin(t *testing.T) {
 		},
 	}
 
-	_, err := config.ResolveSkillPaths(map[string]string{})
+	_, err := config.ResolveSkillPaths(map[LockKey]string{})
 	if err == nil {
 		t.Fatalf("expected error")
 	}
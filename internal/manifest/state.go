@@ -2,6 +2,7 @@ package manifest
 
 import (
 	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 
@@ -13,8 +14,19 @@ type State struct {
 	ManifestPath string
 	LockPath     string
 	Paths        Paths
+	Global       bool
 	Config       Config
 	Lock         map[LockKey]string
+	Hashes       map[HashKey]string
+	// Signers holds the verified signer fingerprint of each locked revision
+	// whose origin has a verify policy.
+	Signers map[LockKey]string
+	// Archives holds the sha256 the lockfile records for each archive
+	// origin, to tell when it lags behind the manifest.
+	Archives map[string]string
+	// LockConflict is set when the lockfile was left conflicted by a merge;
+	// Lock then holds only the entries both sides agree on.
+	LockConflict *LockRecovery
 }
 
 func LoadState() (State, error) {
@@ -53,11 +65,58 @@ func LoadOrInitState() (State, bool, error) {
 		Config: Config{
 			Replace: map[string]string{},
 		},
-		Lock: map[LockKey]string{},
+		Lock:     map[LockKey]string{},
+		Hashes:   map[HashKey]string{},
+		Signers:  map[LockKey]string{},
+		Archives: map[string]string{},
 	}, true, nil
 }
 
+// FindStatePaths returns the paths LoadState or LoadGlobalState would use,
+// without reading the manifest. It returns ErrManifestNotFound when there is
+// no manifest yet.
+func FindStatePaths(global bool) (Paths, error) {
+	if global {
+		path, err := FindGlobalManifestPath()
+		if err != nil {
+			return Paths{}, err
+		}
+		return GlobalPaths(filepath.Dir(path))
+	}
+	path, err := FindManifestPath("")
+	if err != nil {
+		return Paths{}, err
+	}
+	return RepoPaths(filepath.Dir(path)), nil
+}
+
+// InitStatePaths returns the paths LoadOrInitState or LoadOrInitGlobalState
+// would use: those of the existing manifest, or those of the manifest they
+// would create.
+func InitStatePaths(global bool) (Paths, error) {
+	paths, err := FindStatePaths(global)
+	if !errors.Is(err, ErrManifestNotFound) {
+		return paths, err
+	}
+	if global {
+		root, err := GlobalRoot()
+		if err != nil {
+			return Paths{}, err
+		}
+		return GlobalPaths(root)
+	}
+	root, err := os.Getwd()
+	if err != nil {
+		return Paths{}, err
+	}
+	return RepoPaths(root), nil
+}
+
 func LoadStateAt(path string) (State, error) {
+	return loadStateWithPaths(path, RepoPaths(filepath.Dir(path)))
+}
+
+func loadStateWithPaths(path string, paths Paths) (State, error) {
 	configValue, err := Load(path)
 	if err != nil {
 		return State{}, err
@@ -68,19 +127,39 @@ func LoadStateAt(path string) (State, error) {
 
 	root := filepath.Dir(path)
 	lockPath := LockPath(root)
-	entries, err := LoadLock(lockPath)
+	entries, hashes, signers, archives, err := loadLock(lockPath)
+	var conflict *LockRecovery
+	if errors.Is(err, ErrLockConflict) {
+		debug.Logf("lock conflict path=%s", lockPath)
+		data, readErr := os.ReadFile(lockPath)
+		if readErr != nil {
+			return State{}, readErr
+		}
+		recovery, recoverErr := RecoverLock(data)
+		if recoverErr != nil {
+			return State{}, fmt.Errorf("%w: %w", err, recoverErr)
+		}
+		entries, hashes, signers, archives, err = recovery.Entries, recovery.Hashes, recovery.Signers, map[string]string{}, nil
+		conflict = &recovery
+	}
 	if err != nil {
 		return State{}, err
 	}
 
-	return State{
+	state := State{
 		Root:         root,
 		ManifestPath: path,
 		LockPath:     lockPath,
-		Paths:        RepoPaths(root),
+		Paths:        paths,
 		Config:       configValue,
 		Lock:         entries,
-	}, nil
+		Hashes:       hashes,
+		Signers:      signers,
+		Archives:     archives,
+		LockConflict: conflict,
+	}
+	state.AdoptInheritedLock()
+	return state, nil
 }
 
 func SaveState(state State) error {
@@ -90,12 +169,18 @@ func SaveState(state State) error {
 	if err := Save(state.ManifestPath, state.Config); err != nil {
 		return err
 	}
-	if len(state.Lock) == 0 {
+	if len(state.Lock) == 0 && len(state.Config.ArchiveSums()) == 0 {
 		if err := os.Remove(state.LockPath); err != nil && !os.IsNotExist(err) {
 			return err
 		}
 		return nil
 	}
 
-	return SaveLockWithSkills(state.LockPath, state.Lock, state.Config.Skills)
+	return SaveStateLock(state)
+}
+
+// SaveStateLock writes the lockfile of state: its revisions, content hashes,
+// verified signers and the sha256 of each archive skill.
+func SaveStateLock(state State) error {
+	return saveLock(state.LockPath, state.Lock, state.Hashes, state.Signers, state.Config.Skills)
 }
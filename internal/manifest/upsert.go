@@ -11,6 +11,13 @@ type UpsertOptions struct {
 	Origin  string
 	Version string
 	Author  string
+	Track   string
+	// Pinned is set when Version was asked for explicitly, as in
+	// origin@v1.2.0; it drops any track or constraint the skill had.
+	Pinned bool
+	// Sha256 pins archive origins; every skill of the origin takes it, since
+	// they share one download.
+	Sha256 string
 }
 
 func (config *Config) UpsertDiscoveredSkills(skills []DiscoveredSkill, opts UpsertOptions) error {
@@ -36,15 +43,6 @@ func (config *Config) UpsertDiscoveredSkills(skills []DiscoveredSkill, opts Upse
 		existingByName[skill.Name] = identity
 	}
 
-	if opts.Version != "" {
-		for index, skill := range config.Skills {
-			if skill.Origin == opts.Origin && skill.Version != "" {
-				skill.Version = opts.Version
-				config.Skills[index] = skill
-			}
-		}
-	}
-
 	for _, skill := range skills {
 		normalizedSubdir, err := normalizeSubdir(skill.Subdir)
 		if err != nil {
@@ -67,10 +65,25 @@ func (config *Config) UpsertDiscoveredSkills(skills []DiscoveredSkill, opts Upse
 			Subdir: normalizedSubdir,
 		}
 		entry.Version = opts.Version
+		entry.Track = opts.Track
+		if existing, ok := FindSkill(config.Skills, name); ok && existing.Origin == opts.Origin {
+			entry.Groups = existing.Groups
+			if entry.Track == "" && !opts.Pinned {
+				entry.Constraint = existing.Constraint
+				entry.Track = existing.Track
+			}
+		}
 		config.UpsertSkill(entry)
 		existingByIdentity[identity] = name
 		existingByName[name] = identity
 	}
 
+	if opts.Sha256 != "" {
+		for index := range config.Skills {
+			if config.Skills[index].Origin == opts.Origin && !config.Skills[index].Inherited {
+				config.Skills[index].Sha256 = opts.Sha256
+			}
+		}
+	}
 	return nil
 }
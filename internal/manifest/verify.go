@@ -0,0 +1,100 @@
+package manifest
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jmmarotta/agent_skills_manager/internal/source"
+)
+
+// VerifyPolicy lists the keys allowed to sign an origin's release tags or
+// commits. Keyring is an armored OpenPGP keyring or an SSH allowed signers
+// file holding those public keys; Keys are their fingerprints, a 40-digit
+// OpenPGP fingerprint or an SSH "SHA256:..." fingerprint.
+type VerifyPolicy struct {
+	Keys    []string `json:"keys"`
+	Keyring string   `json:"keyring"`
+}
+
+var (
+	openPGPFingerprintPattern = regexp.MustCompile(`^[0-9A-F]{40}$`)
+	sshFingerprintPattern     = regexp.MustCompile(`^SHA256:[A-Za-z0-9+/]{43}$`)
+)
+
+// NormalizeFingerprint returns fingerprint in the form recorded in the lock:
+// OpenPGP fingerprints upper-case without spaces, SSH fingerprints as given.
+func NormalizeFingerprint(fingerprint string) string {
+	fingerprint = strings.TrimSpace(fingerprint)
+	if strings.HasPrefix(fingerprint, "SHA256:") {
+		return fingerprint
+	}
+	return strings.ToUpper(strings.ReplaceAll(fingerprint, " ", ""))
+}
+
+// Allows reports whether fingerprint is one of the policy's keys.
+func (policy VerifyPolicy) Allows(fingerprint string) bool {
+	for _, key := range policy.Keys {
+		if NormalizeFingerprint(key) == fingerprint {
+			return true
+		}
+	}
+	return false
+}
+
+// VerifyFor returns the signing policy for origin, if any.
+func (config Config) VerifyFor(origin string) (VerifyPolicy, bool) {
+	policy, ok := config.Verify[origin]
+	return policy, ok
+}
+
+func verifyIssues(verify map[string]VerifyPolicy) []issue {
+	issues := []issue{}
+	origins := make([]string, 0, len(verify))
+	for origin := range verify {
+		origins = append(origins, origin)
+	}
+	sort.Strings(origins)
+	for _, origin := range origins {
+		policy := verify[origin]
+		if !source.IsRemoteOrigin(origin) {
+			issues = append(issues, newIssue(fmt.Errorf("verify[%q]: verify requires a git origin", origin), "verify", origin))
+			continue
+		}
+		if len(policy.Keys) == 0 {
+			issues = append(issues, newIssue(fmt.Errorf("verify[%q]: keys must list at least one fingerprint", origin), "verify", origin, "keys"))
+		}
+		for index, key := range policy.Keys {
+			normalized := NormalizeFingerprint(key)
+			if !openPGPFingerprintPattern.MatchString(normalized) && !sshFingerprintPattern.MatchString(normalized) {
+				issues = append(issues, newIssue(fmt.Errorf("verify[%q].keys[%d]: %q is not an OpenPGP or SSH SHA256 fingerprint", origin, index, key), "verify", origin, "keys", strconv.Itoa(index)))
+			}
+		}
+		if strings.TrimSpace(policy.Keyring) == "" {
+			issues = append(issues, newIssue(fmt.Errorf("verify[%q]: keyring is required", origin), "verify", origin, "keyring"))
+		}
+	}
+	return issues
+}
+
+// mapVerify normalizes the origins of verify and rewrites each keyring path
+// with resolvePath.
+func mapVerify(verify map[string]VerifyPolicy, resolvePath func(string) string) map[string]VerifyPolicy {
+	if len(verify) == 0 {
+		return nil
+	}
+	mapped := make(map[string]VerifyPolicy, len(verify))
+	for origin, policy := range verify {
+		if source.IsRemoteOrigin(origin) {
+			origin = source.NormalizeOrigin(origin)
+		}
+		policy.Keys = append([]string{}, policy.Keys...)
+		if policy.Keyring != "" {
+			policy.Keyring = resolvePath(policy.Keyring)
+		}
+		mapped[origin] = policy
+	}
+	return mapped
+}
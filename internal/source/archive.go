@@ -0,0 +1,36 @@
+package source
+
+import (
+	"net/url"
+	"strings"
+)
+
+// archiveSuffixes are the release archive formats asm can extract.
+var archiveSuffixes = []string{".tar.gz", ".tgz", ".tar", ".zip"}
+
+// ArchiveSuffix returns the archive format of an http(s) origin whose path
+// ends in .tar.gz, .tgz, .tar or .zip.
+func ArchiveSuffix(origin string) (string, bool) {
+	scheme, ok := schemeForOrigin(origin)
+	if !ok || (scheme != "http" && scheme != "https") {
+		return "", false
+	}
+	parsed, err := url.Parse(origin)
+	if err != nil {
+		return "", false
+	}
+	path := strings.ToLower(parsed.Path)
+	for _, suffix := range archiveSuffixes {
+		if strings.HasSuffix(path, suffix) {
+			return suffix, true
+		}
+	}
+	return "", false
+}
+
+// IsArchiveOrigin reports whether origin is a release archive rather than a
+// git remote. Archives are pinned by checksum instead of a version.
+func IsArchiveOrigin(origin string) bool {
+	_, ok := ArchiveSuffix(origin)
+	return ok
+}
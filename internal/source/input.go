@@ -15,6 +15,8 @@ type Input struct {
 	LocalPath string
 	RepoRoot  string
 	IsLocal   bool
+	// IsArchive marks a release archive URL; Origin is the URL as given.
+	IsArchive bool
 }
 
 func ParseInput(input string, pathFlag string) (Input, error) {
@@ -26,6 +28,9 @@ func ParseInput(input string, pathFlag string) (Input, error) {
 	if err := ValidateOriginScheme(input); err != nil {
 		return Input{}, err
 	}
+	if IsArchiveOrigin(input) {
+		return parseArchiveInput(input, pathFlag)
+	}
 	if IsRemoteOrigin(input) {
 		return parseRemoteInput(input, pathFlag)
 	}
@@ -104,6 +109,20 @@ func parseRemoteInput(input string, pathFlag string) (Input, error) {
 	}, nil
 }
 
+func parseArchiveInput(input string, pathFlag string) (Input, error) {
+	subdir, err := cleanSubdir(pathFlag)
+	if err != nil {
+		return Input{}, err
+	}
+
+	return Input{
+		Origin:    input,
+		RawOrigin: input,
+		Subdir:    subdir,
+		IsArchive: true,
+	}, nil
+}
+
 func cleanSubdir(subdir string) (string, error) {
 	if subdir == "" {
 		return "", nil
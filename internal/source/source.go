@@ -63,7 +63,12 @@ func ValidateOriginScheme(origin string) error {
 	return fmt.Errorf("unsupported origin scheme %q", scheme)
 }
 
+// IsRemoteOrigin reports whether origin is a git remote. Archive URLs are
+// not; see IsArchiveOrigin.
 func IsRemoteOrigin(origin string) bool {
+	if IsArchiveOrigin(origin) {
+		return false
+	}
 	if scheme, ok := schemeForOrigin(origin); ok {
 		return allowedRemoteSchemes[scheme]
 	}
@@ -33,3 +33,35 @@ func TestValidateOriginScheme(t *testing.T) {
 		t.Fatalf("expected unsupported scheme error")
 	}
 }
+
+func TestArchiveOriginsAreNotGitRemotes(t *testing.T) {
+	tests := []struct {
+		origin string
+		suffix string
+	}{
+		{origin: "https://example.com/skills-1.2.0.tar.gz", suffix: ".tar.gz"},
+		{origin: "https://example.com/skills-1.2.0.TGZ?download=1", suffix: ".tgz"},
+		{origin: "http://example.com/releases/skills.zip", suffix: ".zip"},
+		{origin: "https://example.com/skills.tar", suffix: ".tar"},
+		{origin: "https://github.com/org/repo", suffix: ""},
+		{origin: "/tmp/skills.tar.gz", suffix: ""},
+	}
+
+	for _, test := range tests {
+		suffix, ok := ArchiveSuffix(test.origin)
+		if suffix != test.suffix || ok != (test.suffix != "") {
+			t.Fatalf("ArchiveSuffix(%q)=%q, %t, want %q", test.origin, suffix, ok, test.suffix)
+		}
+		if ok && IsRemoteOrigin(test.origin) {
+			t.Fatalf("expected archive %q not to be a git remote", test.origin)
+		}
+	}
+
+	input, err := ParseInput("https://example.com/skills-1.2.0.tar.gz", "skills/foo")
+	if err != nil {
+		t.Fatalf("ParseInput: %v", err)
+	}
+	if !input.IsArchive || input.IsLocal || input.Origin != "https://example.com/skills-1.2.0.tar.gz" || input.Subdir != "skills/foo" {
+		t.Fatalf("unexpected archive input %+v", input)
+	}
+}
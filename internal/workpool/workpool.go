@@ -0,0 +1,37 @@
+// Package workpool runs independent jobs on a bounded number of goroutines.
+package workpool
+
+import "sync"
+
+// DefaultJobs is how many jobs run at once when the caller does not say.
+// Most jobs wait on the network, so this is not tied to the CPU count.
+const DefaultJobs = 8
+
+// Run calls fn for every index below count, with at most jobs calls in
+// flight, and returns once all of them have finished. A jobs value below one
+// means DefaultJobs.
+func Run(jobs int, count int, fn func(index int)) {
+	if jobs < 1 {
+		jobs = DefaultJobs
+	}
+	if jobs > count {
+		jobs = count
+	}
+
+	indexes := make(chan int)
+	var wait sync.WaitGroup
+	for worker := 0; worker < jobs; worker++ {
+		wait.Add(1)
+		go func() {
+			defer wait.Done()
+			for index := range indexes {
+				fn(index)
+			}
+		}()
+	}
+	for index := 0; index < count; index++ {
+		indexes <- index
+	}
+	close(indexes)
+	wait.Wait()
+}
@@ -0,0 +1,43 @@
+package workpool
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRunBoundsConcurrency(t *testing.T) {
+	var running, peak int32
+	var mu sync.Mutex
+	seen := map[int]bool{}
+
+	Run(3, 20, func(index int) {
+		current := atomic.AddInt32(&running, 1)
+		for {
+			previous := atomic.LoadInt32(&peak)
+			if current <= previous || atomic.CompareAndSwapInt32(&peak, previous, current) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		atomic.AddInt32(&running, -1)
+
+		mu.Lock()
+		seen[index] = true
+		mu.Unlock()
+	})
+
+	if len(seen) != 20 {
+		t.Fatalf("expected 20 jobs to run, got %d", len(seen))
+	}
+	if peak > 3 {
+		t.Fatalf("expected at most 3 jobs at once, got %d", peak)
+	}
+}
+
+func TestRunWithNoJobs(t *testing.T) {
+	Run(0, 0, func(int) {
+		t.Fatalf("expected no calls")
+	})
+}